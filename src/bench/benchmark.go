@@ -2,32 +2,102 @@
 // different database index implementations.
 package bench
 
-import (
-	"fmt"
-	"os"
-
-	"github.com/btree-query-bench/bmark/dbms/index"
-	"github.com/btree-query-bench/bmark/dbms/index/bptree"
-	"github.com/btree-query-bench/bmark/dbms/index/btree"
-	"github.com/btree-query-bench/bmark/dbms/index/lsm"
-)
+import "github.com/btree-query-bench/bmark/dbms/index"
 
 // Config defines the configuration parameters for the benchmark suite.
+// The JSON names, used by suite files, are the command-line flag names with
+// underscores instead of dashes.
 type Config struct {
-	Seed            int64
-	OutDir          string
-	DataDir         string
-	CachePages      int
-	DatasetSize     int
-	PointQueryCount int
-	WriteOpsTotal   int
-	WriteOpsWindow  int
-	MixedOpsTotal   int
-	LogInterval     int
-	ValueSize       int
-	T2StartSize     int
-	T2MaxSize       int
-	CleanupData     bool
+	Seed            int64  `json:"seed"`
+	OutDir          string `json:"out_dir"`
+	DataDir         string `json:"data_dir"`
+	CachePages      int    `json:"cache_pages"`
+	DatasetSize     int    `json:"dataset_size"`
+	PointQueryCount int    `json:"point_queries"`
+	WriteOpsTotal   int    `json:"write_ops_total"`
+	WriteOpsWindow  int    `json:"write_ops_window"`
+	MixedOpsTotal   int    `json:"mixed_ops_total"`
+	LogInterval     int    `json:"log_interval"`
+	ValueSize       int    `json:"value_size"`
+	T2StartSize     int    `json:"t2_start_size"`
+	T2MaxSize       int    `json:"t2_max_size"`
+	CleanupData     bool   `json:"cleanup_data"`
+	Repetitions     int    `json:"repetitions"` // runs of each test; above 1 the CSVs hold means and confidence intervals
+	Baselines       bool   `json:"baselines"`   // also benchmark the in-memory sorted array and B-tree
+	YCSB            string `json:"ycsb"`        // YCSB core workloads to run, e.g. "ABCDEF"
+	T4Mix           OpMix  `json:"t4_mix"`      // operation mix of T4 (zero means DefaultT4Mix)
+	T5Mix           OpMix  `json:"t5_mix"`      // operation mix of T5 (zero means DefaultT5Mix)
+
+	// Cache state of the measurements. Warmup runs before every warm
+	// measurement of T1, T2 and the mixed workloads; CacheMode (see
+	// CacheModes) selects whether T1 and T2 measure warm, cold or both.
+	Warmup      Warmup `json:"warmup"`
+	CacheMode   string `json:"cache_mode"`
+	DropOSCache bool   `json:"drop_os_cache"` // cold: also evict the index files from the OS page cache (Linux)
+
+	// Open-loop load of the mixed workloads: TargetRate operations per
+	// second, arriving as set by Arrivals (see ArrivalProcesses), with each
+	// latency measured from the operation's intended start. A TargetRate of 0
+	// runs them closed-loop, each operation starting when the last finished.
+	TargetRate float64 `json:"target_rate"`
+	Arrivals   string  `json:"arrivals"`
+
+	// SampleInterval is the length in milliseconds of the intervals of the
+	// time series every test records (see timeseriesHeader); 0 records none.
+	SampleInterval int `json:"sample_interval_ms"`
+
+	// Workload traces (see TraceOp): RecordTrace is a directory to record
+	// the operations of every test into, in TraceFormat (see TraceFormats),
+	// and Trace the trace file the replay test executes.
+	RecordTrace string `json:"record_trace"`
+	TraceFormat string `json:"trace_format"`
+	Trace       string `json:"trace"`
+
+	// Key access distributions (see KeyDists) per test and their parameters.
+	T1Dist         string  `json:"t1_dist"`     // point query keys
+	MixedDist      string  `json:"mixed_dist"`  // reads of the T4/T5 mixed workloads
+	YCSBDist       string  `json:"ycsb_dist"`   // overrides the YCSB workloads' own distributions if set
+	ZipfTheta      float64 `json:"zipf_theta"`  // skew of the Zipfian distributions
+	HotSetFraction float64 `json:"hotspot_set"` // hotspot: fraction of keys that are hot
+	HotOpFraction  float64 `json:"hotspot_ops"` // hotspot: fraction of operations on hot keys
+
+	// Dataset generation (see KeyPatterns and ValueDists). ValueSize is the
+	// fixed size and the mean of the normal and Pareto sizes.
+	KeyPattern    string  `json:"key_pattern"`
+	ValueDist     string  `json:"value_dist"`
+	ValueMin      int     `json:"value_min"`
+	ValueMax      int     `json:"value_max"`
+	ValueStdDev   float64 `json:"value_stddev"`
+	CompressRatio float64 `json:"compress_ratio"`
+}
+
+// dataset generates the configured dataset of DatasetSize entries.
+func (cfg Config) dataset() (*Dataset, error) {
+	return NewDatasetFromSpec(cfg.DatasetSize, DatasetSpec{
+		KeyPattern:    cfg.KeyPattern,
+		ValueDist:     cfg.ValueDist,
+		ValueSize:     cfg.ValueSize,
+		ValueMin:      cfg.ValueMin,
+		ValueMax:      cfg.ValueMax,
+		ValueStdDev:   cfg.ValueStdDev,
+		CompressRatio: cfg.CompressRatio,
+	}, cfg.Seed)
+}
+
+// keyDist returns the named distribution with the configured parameters.
+// Parameters left at zero fall back to the YCSB defaults.
+func (cfg Config) keyDist(name string) KeyDist {
+	d := DefaultKeyDist(name)
+	if cfg.ZipfTheta != 0 {
+		d.ZipfTheta = cfg.ZipfTheta
+	}
+	if cfg.HotSetFraction != 0 {
+		d.HotSetFraction = cfg.HotSetFraction
+	}
+	if cfg.HotOpFraction != 0 {
+		d.HotOpFraction = cfg.HotOpFraction
+	}
+	return d
 }
 
 // IndexDef defines an index implementation and a factory function to create it.
@@ -36,93 +106,22 @@ type IndexDef struct {
 	NewFunc func(path string) (index.Index, error)
 }
 
-// Indexes returns a slice of index implementations to be benchmarked.
+// Indexes returns a slice of index implementations to be benchmarked: the
+// default variants of DefaultIndexSpecs.
 func Indexes(cfg Config) []IndexDef {
-	return []IndexDef{
-		{
-			Name: "btree_4k",
-			NewFunc: func(path string) (index.Index, error) {
-				return btree.Open(path, cfg.CachePages, 4096)
-			},
-		},
-		{
-			Name: "btree_8k",
-			NewFunc: func(path string) (index.Index, error) {
-				return btree.Open(path, cfg.CachePages, 8192)
-			},
-		},
-		{
-			Name: "btree_16k",
-			NewFunc: func(path string) (index.Index, error) {
-				return btree.Open(path, cfg.CachePages, 16384)
-			},
-		},
-		{
-			Name: "bptree_4k",
-			NewFunc: func(path string) (index.Index, error) {
-				return bptree.Open(path, cfg.CachePages, 4096)
-			},
-		},
-		{
-			Name: "bptree_8k",
-			NewFunc: func(path string) (index.Index, error) {
-				return bptree.Open(path, cfg.CachePages, 8192)
-			},
-		},
-		{
-			Name: "bptree_16k",
-			NewFunc: func(path string) (index.Index, error) {
-				return bptree.Open(path, cfg.CachePages, 16384)
-			},
-		},
-		{
-			Name: "lsm_pebble_16m",
-			NewFunc: func(path string) (index.Index, error) {
-				return lsm.Open(path, 16)
-			},
-		},
-		{
-			Name: "lsm_pebble_32m",
-			NewFunc: func(path string) (index.Index, error) {
-				return lsm.Open(path, 32)
-			},
-		},
-		{
-			Name: "lsm_pebble_64m",
-			NewFunc: func(path string) (index.Index, error) {
-				return lsm.Open(path, 64)
-			},
-		},
+	var defs []IndexDef
+	for _, spec := range DefaultIndexSpecs(cfg.Baselines) {
+		def, _ := spec.Def(cfg) // the defaults use known types only
+		defs = append(defs, def)
 	}
+	return defs
 }
 
-// RunBenchmarks runs the full suite of benchmarks for all index implementations defined in the configuration.
+// RunBenchmarks runs the default suite (see DefaultSuite) for all index implementations defined in the configuration.
 func RunBenchmarks(cfg Config) error {
-	indices := Indexes(cfg)
-	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
-		return fmt.Errorf("create data dir: %w", err)
-	}
-
-	if err := os.MkdirAll(cfg.OutDir, 0755); err != nil {
-		return fmt.Errorf("create results dir: %w", err)
-	}
-	/*if err := RunBenchmarkT1(indices, cfg); err != nil {
+	s, err := DefaultSuite(cfg)
+	if err != nil {
 		return err
 	}
-
-	if err := RunBenchmarkT2(indices, cfg); err != nil {
-		return err
-	}
-		if err := RunBenchmarkT3(indices, cfg); err != nil {
-		return err
-	}
-	*/
-	if err := RunBenchmarkT4(indices, cfg); err != nil {
-		return err
-	}
-	if err := RunBenchmarkT5(indices, cfg); err != nil {
-		return err
-	}
-
-	return nil
+	return s.Run(cfg, nil)
 }
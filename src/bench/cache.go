@@ -0,0 +1,134 @@
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+)
+
+// Cache states in which T1 and T2 measure.
+const (
+	CacheWarm = "warm" // measure after the fill and the warm-up phase
+	CacheCold = "cold" // close and reopen the index first, so that its caches start empty
+	CacheBoth = "both" // measure cold, then warm up and measure warm
+)
+
+// CacheModes lists the values of Config.CacheMode.
+var CacheModes = []string{CacheWarm, CacheCold, CacheBoth}
+
+// cachePhases returns the cache states to measure in, in order.
+func (cfg Config) cachePhases() ([]string, error) {
+	switch cfg.CacheMode {
+	case CacheWarm, "":
+		return []string{CacheWarm}, nil
+	case CacheCold:
+		return []string{CacheCold}, nil
+	case CacheBoth:
+		return []string{CacheCold, CacheWarm}, nil
+	}
+	return nil, fmt.Errorf("unknown cache mode %q (want one of %v)", cfg.CacheMode, CacheModes)
+}
+
+// Warmup is the length of the warm-up phase run before measuring: a number
+// of operations, or a duration such as "5s". The zero value disables it.
+type Warmup struct {
+	Ops      int
+	Duration time.Duration
+}
+
+// ParseWarmup parses an operation count ("10000") or a time.Duration ("5s").
+func ParseWarmup(s string) (Warmup, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return Warmup{}, fmt.Errorf("warm-up %q: negative count", s)
+		}
+		return Warmup{Ops: n}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return Warmup{}, fmt.Errorf("warm-up %q: want an operation count or a duration", s)
+	}
+	if d < 0 {
+		return Warmup{}, fmt.Errorf("warm-up %q: negative duration", s)
+	}
+	return Warmup{Duration: d}, nil
+}
+
+func (w Warmup) String() string {
+	if w.Duration > 0 {
+		return w.Duration.String()
+	}
+	return strconv.Itoa(w.Ops)
+}
+
+// MarshalText implements encoding.TextMarshaler using the ParseWarmup format.
+func (w Warmup) MarshalText() ([]byte, error) { return []byte(w.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler. An empty string disables the warm-up.
+func (w *Warmup) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*w = Warmup{}
+		return nil
+	}
+	v, err := ParseWarmup(string(text))
+	if err != nil {
+		return err
+	}
+	*w = v
+	return nil
+}
+
+// Set implements flag.Value.
+func (w *Warmup) Set(s string) error { return w.UnmarshalText([]byte(s)) }
+
+// warmUp issues point queries on keys[kc.Next(len(keys))] until w is over
+// and returns how many it issued. Nothing of it is recorded.
+func warmUp(label, name string, idx index.Index, keys []int64, kc KeyChooser, w Warmup) int {
+	if (w.Ops == 0 && w.Duration == 0) || len(keys) == 0 {
+		return 0
+	}
+	start := time.Now()
+	n := 0
+	for ; w.Duration > 0 && time.Since(start) < w.Duration || n < w.Ops; n++ {
+		_, _ = idx.Get(keys[kc.Next(len(keys))])
+	}
+	fmt.Printf("[%s] %s: warm-up: %d point queries in %s\n", label, name, n, time.Since(start).Round(time.Millisecond))
+	return n
+}
+
+// errNotPersistent is returned by reopenCold for indexes that keep their
+// data in memory only.
+var errNotPersistent = errors.New("index keeps no data on disk")
+
+// reopenCold closes idx and opens the index at path again, so that its page
+// or block cache starts empty. With dropOS it also evicts the index files
+// from the OS page cache. In-memory indexes, which would come back empty,
+// are left open and errNotPersistent is returned. On other errors the
+// returned index is nil.
+func reopenCold(def IndexDef, idx index.Index, path string, dropOS bool) (index.Index, error) {
+	if diskUsage(path) == 0 {
+		return idx, errNotPersistent
+	}
+	if err := idx.Close(); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+	if dropOS {
+		if err := dropOSCache(path); err != nil {
+			return nil, err
+		}
+	}
+	idx, err := def.NewFunc(path)
+	if err != nil {
+		return nil, fmt.Errorf("reopen: %w", err)
+	}
+	// Like after the fill: no periodic syncs during the read-only measurements.
+	if s, ok := idx.(interface{ SetSyncInterval(int) }); ok {
+		s.SetSyncInterval(0)
+	}
+	return idx, nil
+}
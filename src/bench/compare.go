@@ -0,0 +1,395 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// compareSpec selects what Compare compares in one result file. Rows are
+// aligned by the key columns; several rows with the same key (e.g. the T3
+// windows of an index) are averaged.
+type compareSpec struct {
+	test    string
+	file    string
+	keys    []string
+	metrics []compareMetric
+}
+
+type compareMetric struct {
+	column       string
+	higherBetter bool
+}
+
+var (
+	latencyMetrics = []compareMetric{{"p50_ns", false}, {"p95_ns", false}, {"p99_ns", false}, {"ops_per_sec", true}}
+	compareSpecs   = []compareSpec{
+		{"T1", "t1_point_query.csv", []string{"index", "cache"}, append([]compareMetric{{"avg_ns", false}}, latencyMetrics...)},
+		{"T2", "t2_range_query.csv", []string{"index", "range_size", "cache"}, []compareMetric{{"total_ms", false}, {"ops_per_sec", true}}},
+		{"T3", "t3_write_throughput.csv", []string{"index"}, []compareMetric{{"ops_per_sec", true}}},
+		{"Space", SpaceFile, []string{"test", "index", "phase"}, []compareMetric{{"disk_bytes", false}}},
+	}
+	// keyDefaults fill in key columns that older result files lack.
+	keyDefaults = map[string]string{"cache": CacheWarm}
+)
+
+// Status of a compared value.
+const (
+	StatusRegression  = "regression"
+	StatusImprovement = "improvement"
+	StatusUnchanged   = "unchanged"
+)
+
+// MetricChange is one metric of one aligned row of two result directories.
+type MetricChange struct {
+	Test, Key, Metric string
+	Base, New         float64
+	Change            float64 // (New - Base) / Base
+	Significant       string  // "yes", "no", or "n/a" without confidence intervals
+	Status            string  // one of the Status* constants
+}
+
+// Comparison is the result of Compare.
+type Comparison struct {
+	BaseDir, NewDir string
+	Threshold       float64 // relative change that counts as a regression or improvement
+	Changes         []MetricChange
+	Missing         []string // rows or files present in only one directory
+}
+
+// Regressions returns the number of changes with StatusRegression.
+func (c *Comparison) Regressions() int {
+	n := 0
+	for _, ch := range c.Changes {
+		if ch.Status == StatusRegression {
+			n++
+		}
+	}
+	return n
+}
+
+// sample is the mean of the values aligned under one key, with the half-width
+// of its 95% confidence interval (0 if unknown).
+type sample struct {
+	mean, ci float64
+}
+
+// Compare aligns the T1–T5, YCSB and space results of baseDir and newDir and
+// computes the relative change of their main metrics. A change is significant
+// if it exceeds the combined 95% confidence intervals, which are known only
+// for results of repeated runs (see Config.Repetitions). Changes for the worse
+// beyond threshold (e.g. 0.05) that are not known to be insignificant are
+// regressions.
+func Compare(baseDir, newDir string, threshold float64) (*Comparison, error) {
+	c := &Comparison{BaseDir: baseDir, NewDir: newDir, Threshold: threshold}
+	specs := append([]compareSpec{}, compareSpecs...)
+	var summaries []string
+	for _, dir := range []string{baseDir, newDir} {
+		paths, err := filepath.Glob(filepath.Join(dir, "*_summary.csv"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			summaries = append(summaries, filepath.Base(path))
+		}
+	}
+	slices.Sort(summaries)
+	for _, file := range slices.Compact(summaries) {
+		specs = append(specs, compareSpec{summaryTest(file), file, []string{"index", "op_type"}, latencyMetrics})
+	}
+
+	for _, spec := range specs {
+		base, err := readCSV(filepath.Join(baseDir, spec.file))
+		if err != nil {
+			return nil, err
+		}
+		cur, err := readCSV(filepath.Join(newDir, spec.file))
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case len(base) == 0 && len(cur) == 0:
+			continue
+		case len(base) == 0:
+			c.Missing = append(c.Missing, fmt.Sprintf("%s: %s only in %s", spec.test, spec.file, newDir))
+			continue
+		case len(cur) == 0:
+			c.Missing = append(c.Missing, fmt.Sprintf("%s: %s only in %s", spec.test, spec.file, baseDir))
+			continue
+		}
+		c.compareFile(spec, base, cur)
+	}
+	return c, nil
+}
+
+// summaryTest returns the name of the mixed workload test that wrote the
+// summary CSV file, e.g. "T4" or "YCSB A".
+func summaryTest(file string) string {
+	name := strings.TrimSuffix(file, "_summary.csv") // e.g. t4_read_heavy or ycsb_a
+	if wl, ok := strings.CutPrefix(name, "ycsb_"); ok {
+		return "YCSB " + strings.ToUpper(wl)
+	}
+	if name == "replay" {
+		return "Replay"
+	}
+	return strings.ToUpper(strings.SplitN(name, "_", 2)[0])
+}
+
+func (c *Comparison) compareFile(spec compareSpec, base, cur [][]string) {
+	baseRows, baseOrder := alignRows(spec, base)
+	curRows, curOrder := alignRows(spec, cur)
+	for _, key := range baseOrder {
+		if curRows[key] == nil {
+			c.Missing = append(c.Missing, fmt.Sprintf("%s: %s only in %s", spec.test, key, c.BaseDir))
+		}
+	}
+	for _, key := range curOrder {
+		if baseRows[key] == nil {
+			c.Missing = append(c.Missing, fmt.Sprintf("%s: %s only in %s", spec.test, key, c.NewDir))
+		}
+	}
+
+	for _, key := range baseOrder {
+		b, n := baseRows[key], curRows[key]
+		if n == nil {
+			continue
+		}
+		for _, m := range spec.metrics {
+			bs, ok1 := b[m.column]
+			ns, ok2 := n[m.column]
+			if !ok1 || !ok2 {
+				continue
+			}
+			c.Changes = append(c.Changes, c.change(spec.test, key, m, bs, ns))
+		}
+	}
+}
+
+func (c *Comparison) change(test, key string, m compareMetric, base, cur sample) MetricChange {
+	ch := MetricChange{Test: test, Key: key, Metric: m.column, Base: base.mean, New: cur.mean, Significant: "n/a"}
+	if base.mean != 0 {
+		ch.Change = (cur.mean - base.mean) / math.Abs(base.mean)
+	}
+	if base.ci > 0 || cur.ci > 0 {
+		ch.Significant = "no"
+		if math.Abs(cur.mean-base.mean) > math.Hypot(base.ci, cur.ci) {
+			ch.Significant = "yes"
+		}
+	}
+	worse := ch.Change > 0
+	if m.higherBetter {
+		worse = ch.Change < 0
+	}
+	ch.Status = StatusUnchanged
+	if math.Abs(ch.Change) > c.Threshold && ch.Significant != "no" {
+		ch.Status = StatusImprovement
+		if worse {
+			ch.Status = StatusRegression
+		}
+	}
+	return ch
+}
+
+// alignRows groups the rows of records by the key columns of spec and returns
+// the metrics of each key, along with the keys in order of appearance.
+func alignRows(spec compareSpec, records [][]string) (map[string]map[string]sample, []string) {
+	header := records[0]
+	col := make(map[string]int)
+	for i, name := range header {
+		col[name] = i
+	}
+	values := make(map[string]map[string][]float64)
+	cis := make(map[string]map[string][]float64)
+	var order []string
+	for _, rec := range records[1:] {
+		var parts []string
+		for _, k := range spec.keys {
+			v := keyDefaults[k]
+			if i, ok := col[k]; ok && i < len(rec) {
+				v = rec[i]
+			}
+			if v != "" {
+				parts = append(parts, v)
+			}
+		}
+		key := strings.Join(parts, " ")
+		if values[key] == nil {
+			order = append(order, key)
+			values[key] = make(map[string][]float64)
+			cis[key] = make(map[string][]float64)
+		}
+		for _, m := range spec.metrics {
+			i, ok := col[m.column]
+			if !ok || i >= len(rec) {
+				continue
+			}
+			v, err := strconv.ParseFloat(rec[i], 64)
+			if err != nil {
+				continue
+			}
+			values[key][m.column] = append(values[key][m.column], v)
+			if ci, ok := col[m.column+ci95Suffix]; ok && ci < len(rec) {
+				if v, err := strconv.ParseFloat(rec[ci], 64); err == nil {
+					cis[key][m.column] = append(cis[key][m.column], v)
+				}
+			}
+		}
+	}
+
+	rows := make(map[string]map[string]sample)
+	for key, metrics := range values {
+		rows[key] = make(map[string]sample)
+		for name, vs := range metrics {
+			// The spread of rows averaged within one run, like the T3
+			// windows, is no run-to-run noise; only the intervals of repeated
+			// runs count, combined as for the mean of independent values.
+			s := sample{}
+			for _, v := range vs {
+				s.mean += v / float64(len(vs))
+			}
+			if c := cis[key][name]; len(c) == len(vs) {
+				for _, ci := range c {
+					s.ci += ci * ci
+				}
+				s.ci = math.Sqrt(s.ci) / float64(len(vs))
+			}
+			rows[key][name] = s
+		}
+	}
+	return rows, order
+}
+
+// WriteMarkdown writes the comparison as a Markdown table, followed by the
+// rows that could not be aligned.
+func (c *Comparison) WriteMarkdown(w io.Writer) {
+	fmt.Fprintf(w, "## Comparison of `%s` (base) and `%s` (new)\n\n", c.BaseDir, c.NewDir)
+	fmt.Fprintf(w, "Threshold: %.1f%%. %d regressions, %d improvements.\n\n",
+		100*c.Threshold, c.Regressions(), c.count(StatusImprovement))
+	fmt.Fprintln(w, "| Test | Row | Metric | Base | New | Change | Significant | Status |")
+	fmt.Fprintln(w, "|---|---|---|---:|---:|---:|---|---|")
+	for _, ch := range c.Changes {
+		status := ch.Status
+		if status != StatusUnchanged {
+			status = "**" + status + "**"
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %+.1f%% | %s | %s |\n",
+			ch.Test, ch.Key, ch.Metric, formatValue(ch.Base), formatValue(ch.New), 100*ch.Change, ch.Significant, status)
+	}
+	if len(c.Missing) > 0 {
+		fmt.Fprintln(w, "\nNot compared:")
+		fmt.Fprintln(w)
+		for _, m := range c.Missing {
+			fmt.Fprintf(w, "- %s\n", m)
+		}
+	}
+}
+
+func (c *Comparison) count(status string) int {
+	n := 0
+	for _, ch := range c.Changes {
+		if ch.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+func formatValue(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// WriteHTML renders the relative changes as bar charts, one per test, with
+// regressions in red and improvements in green.
+func (c *Comparison) WriteHTML(path string) error {
+	var tests []string
+	byTest := make(map[string][]MetricChange)
+	for _, ch := range c.Changes {
+		if byTest[ch.Test] == nil {
+			tests = append(tests, ch.Test)
+		}
+		byTest[ch.Test] = append(byTest[ch.Test], ch)
+	}
+
+	page := components.NewPage()
+	page.SetLayout(components.PageFlexLayout)
+	page.PageTitle = "Comparison"
+	for _, test := range tests {
+		changes := byTest[test]
+		var rows, metrics []string
+		seenRow, seenMetric := make(map[string]bool), make(map[string]bool)
+		for _, ch := range changes {
+			if !seenRow[ch.Key] {
+				seenRow[ch.Key] = true
+				rows = append(rows, ch.Key)
+			}
+			if !seenMetric[ch.Metric] {
+				seenMetric[ch.Metric] = true
+				metrics = append(metrics, ch.Metric)
+			}
+		}
+
+		bar := charts.NewBar()
+		bar.SetGlobalOptions(
+			charts.WithTitleOpts(opts.Title{Title: test + " — Change vs. Base", Subtitle: fmt.Sprintf("threshold ±%.1f%%", 100*c.Threshold)}),
+			charts.WithYAxisOpts(opts.YAxis{Name: "%", Type: "value"}),
+			charts.WithXAxisOpts(opts.XAxis{
+				AxisLabel: &opts.AxisLabel{Show: opts.Bool(true), Interval: "0", Rotate: 30},
+			}),
+			charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+			charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "8%"}),
+			charts.WithInitializationOpts(opts.Initialization{Width: chartWidth, Height: chartHeight}),
+		)
+		bar.SetXAxis(rows)
+		for _, metric := range metrics {
+			items := make([]opts.BarData, len(rows))
+			for i, row := range rows {
+				items[i] = opts.BarData{Value: 0}
+				for _, ch := range changes {
+					if ch.Key == row && ch.Metric == metric {
+						items[i] = opts.BarData{Value: math.Round(1000*ch.Change) / 10, ItemStyle: statusStyle(ch.Status)}
+					}
+				}
+			}
+			bar.AddSeries(metric, items)
+		}
+		page.AddCharts(bar)
+	}
+	return renderPage(page, path, "[Compare]")
+}
+
+func statusStyle(status string) *opts.ItemStyle {
+	switch status {
+	case StatusRegression:
+		return &opts.ItemStyle{Color: "#ef3b2c"}
+	case StatusImprovement:
+		return &opts.ItemStyle{Color: "#41ab5d"}
+	}
+	return &opts.ItemStyle{Color: "#bdbdbd"}
+}
+
+// WriteMarkdownFile writes the Markdown table to path, or to stdout for "-".
+func (c *Comparison) WriteMarkdownFile(path string) error {
+	if path == "-" {
+		c.WriteMarkdown(os.Stdout)
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c.WriteMarkdown(f)
+	return nil
+}
@@ -2,10 +2,58 @@ package bench
 
 import (
 	"encoding/binary"
+	"fmt"
+	"math"
 	"math/rand"
 	"slices"
 )
 
+// Key patterns understood by NewDatasetFromSpec.
+const (
+	KeysDense       = "dense"       // 1..n in random order
+	KeysSparse      = "sparse"      // unique random non-negative 64-bit keys
+	KeysClustered   = "clustered"   // runs of consecutive keys at random places in the key space
+	KeysTimeOrdered = "timeordered" // increasing nanosecond timestamps, inserted nearly in order
+	KeysGaps        = "gaps"        // dense runs separated by gaps of random length
+)
+
+// KeyPatterns lists the names accepted as DatasetSpec.KeyPattern.
+var KeyPatterns = []string{KeysDense, KeysSparse, KeysClustered, KeysTimeOrdered, KeysGaps}
+
+// Value size distributions understood by NewDatasetFromSpec.
+const (
+	ValuesFixed   = "fixed"   // always ValueSize bytes
+	ValuesUniform = "uniform" // uniform in [ValueMin, ValueMax]
+	ValuesNormal  = "normal"  // normal around ValueSize with ValueStdDev
+	ValuesPareto  = "pareto"  // heavy-tailed Pareto with mean ValueSize
+)
+
+// ValueDists lists the names accepted as DatasetSpec.ValueDist.
+var ValueDists = []string{ValuesFixed, ValuesUniform, ValuesNormal, ValuesPareto}
+
+const (
+	clusterSize = 1000 // keys per run of the clustered pattern
+	gapRunSize  = 100  // keys per run of the gaps pattern
+	gapMaxLen   = 10_000
+	paretoAlpha = 1.5 // shape of the Pareto value sizes; smaller is heavier-tailed
+)
+
+// DatasetSpec describes how a Dataset's keys and values are generated. Sizes
+// drawn from a distribution are clamped to [ValueMin, ValueMax].
+type DatasetSpec struct {
+	KeyPattern  string
+	ValueDist   string
+	ValueSize   int     // fixed size, and mean of the normal and Pareto sizes
+	ValueMin    int     // smallest value size
+	ValueMax    int     // largest value size
+	ValueStdDev float64 // normal: standard deviation (0 means ValueSize/4)
+
+	// CompressRatio is the fraction of each value that is random; the rest
+	// repeats it, so a value compresses to roughly that fraction of its size.
+	// 1 (or 0) yields incompressible values.
+	CompressRatio float64
+}
+
 // Dataset represents a collection of keys and values used for benchmarking.
 type Dataset struct {
 	Keys   []int64
@@ -15,27 +63,156 @@ type Dataset struct {
 
 // NewDataset initializes n unique keys and corresponding random values.
 func NewDataset(n int, valueSize int, seed int64) *Dataset {
+	ds, _ := NewDatasetFromSpec(n, DatasetSpec{KeyPattern: KeysDense, ValueDist: ValuesFixed, ValueSize: valueSize}, seed)
+	return ds
+}
+
+// NewDatasetFromSpec initializes n unique keys and corresponding values as described by spec.
+// Values of at least 8 bytes start with their key.
+func NewDatasetFromSpec(n int, spec DatasetSpec, seed int64) (*Dataset, error) {
 	rng := rand.New(rand.NewSource(seed))
 
-	keys := make([]int64, n)
-	for i := range keys {
-		keys[i] = int64(i + 1)
+	keys, err := generateKeys(n, spec.KeyPattern, rng)
+	if err != nil {
+		return nil, err
+	}
+	sizeOf, err := valueSizer(spec, rng)
+	if err != nil {
+		return nil, err
 	}
-	rng.Shuffle(n, func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
 
 	values := make([][]byte, n)
 	for i := range values {
-		v := make([]byte, valueSize)
-		binary.LittleEndian.PutUint64(v, uint64(keys[i]))
-		rng.Read(v[8:])
-		values[i] = v
+		values[i] = fillValue(make([]byte, sizeOf()), keys[i], spec.CompressRatio, rng)
 	}
 
 	return &Dataset{
 		Keys:   keys,
 		Values: values,
 		rng:    rng,
+	}, nil
+}
+
+func generateKeys(n int, pattern string, rng *rand.Rand) ([]int64, error) {
+	keys := make([]int64, 0, n)
+	switch pattern {
+	case KeysDense, "":
+		for i := 0; i < n; i++ {
+			keys = append(keys, int64(i+1))
+		}
+
+	case KeysSparse:
+		seen := make(map[int64]bool, n)
+		for len(keys) < n {
+			if k := rng.Int63(); !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+		return keys, nil // already in random order
+
+	case KeysClustered:
+		seen := make(map[int64]bool, n/clusterSize+1)
+		for len(keys) < n {
+			// Cluster bases are multiples of clusterSize, so clusters never overlap.
+			base := rng.Int63n(math.MaxInt64/clusterSize) * clusterSize
+			if seen[base] {
+				continue
+			}
+			seen[base] = true
+			for i := int64(0); i < clusterSize && len(keys) < n; i++ {
+				keys = append(keys, base+i)
+			}
+		}
+
+	case KeysTimeOrdered:
+		// Nanosecond timestamps from 2024-01-01 with exponential inter-arrival
+		// times (mean 1µs). Neighbours are swapped now and then, like events
+		// arriving slightly late.
+		ts := int64(1_704_067_200_000_000_000)
+		for i := 0; i < n; i++ {
+			ts += 1 + int64(rng.ExpFloat64()*1000)
+			keys = append(keys, ts)
+		}
+		for i := 1; i < n; i++ {
+			if rng.Intn(10) == 0 {
+				keys[i-1], keys[i] = keys[i], keys[i-1]
+			}
+		}
+		return keys, nil
+
+	case KeysGaps:
+		next := int64(1)
+		for len(keys) < n {
+			for i := 0; i < gapRunSize && len(keys) < n; i++ {
+				keys = append(keys, next)
+				next++
+			}
+			next += 1 + rng.Int63n(gapMaxLen)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown key pattern %q (want one of %v)", pattern, KeyPatterns)
+	}
+	rng.Shuffle(n, func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	return keys, nil
+}
+
+// valueSizer returns a function drawing value sizes according to spec.
+func valueSizer(spec DatasetSpec, rng *rand.Rand) (func() int, error) {
+	if spec.ValueDist == ValuesFixed || spec.ValueDist == "" {
+		return func() int { return spec.ValueSize }, nil
+	}
+	// Unbounded draws of the heavy-tailed distributions would not fit a page.
+	lo, hi := spec.ValueMin, spec.ValueMax
+	if hi <= 0 {
+		return nil, fmt.Errorf("%s value sizes need a ValueMax", spec.ValueDist)
+	}
+	if lo > hi {
+		return nil, fmt.Errorf("value size range [%d, %d] is empty", lo, hi)
+	}
+	clamp := func(v float64) int {
+		return int(math.Max(float64(lo), math.Min(float64(hi), math.Round(v))))
 	}
+
+	switch spec.ValueDist {
+	case ValuesUniform:
+		return func() int { return lo + rng.Intn(hi-lo+1) }, nil
+	case ValuesNormal:
+		sd := spec.ValueStdDev
+		if sd == 0 {
+			sd = float64(spec.ValueSize) / 4
+		}
+		return func() int { return clamp(float64(spec.ValueSize) + rng.NormFloat64()*sd) }, nil
+	case ValuesPareto:
+		// Scale so that the untruncated mean alpha*xm/(alpha-1) is ValueSize.
+		xm := float64(spec.ValueSize) * (paretoAlpha - 1) / paretoAlpha
+		return func() int { return clamp(xm / math.Pow(1-rng.Float64(), 1/paretoAlpha)) }, nil
+	}
+	return nil, fmt.Errorf("unknown value distribution %q (want one of %v)", spec.ValueDist, ValueDists)
+}
+
+// fillValue writes key into the first 8 bytes of v, if v has room, and fills
+// the rest with random bytes. With ratio in (0, 1), only that fraction of the
+// rest is random and the remainder repeats it.
+func fillValue(v []byte, key int64, ratio float64, rng *rand.Rand) []byte {
+	body := v
+	if len(v) >= 8 {
+		binary.LittleEndian.PutUint64(v, uint64(key))
+		body = v[8:]
+	}
+	if len(body) == 0 {
+		return v
+	}
+	random := len(body)
+	if ratio > 0 && ratio < 1 {
+		random = max(1, int(float64(len(body))*ratio))
+	}
+	rng.Read(body[:random])
+	for i := random; i < len(body); i += random {
+		copy(body[i:], body[:random])
+	}
+	return v
 }
 
 // SortedKeys returns a copy of all keys in the dataset, sorted in ascending order.
@@ -46,6 +223,15 @@ func (d *Dataset) SortedKeys() []int64 {
 	return sorted
 }
 
+// Bytes returns the logical size of the dataset: 8 bytes per key plus the values.
+func (d *Dataset) Bytes() int64 {
+	total := int64(8 * len(d.Keys))
+	for _, v := range d.Values {
+		total += int64(len(v))
+	}
+	return total
+}
+
 // RandomKeys returns a random subset of m keys from the dataset.
 func (d *Dataset) RandomKeys(m int) []int64 {
 	perm := d.rng.Perm(len(d.Keys))
@@ -55,3 +241,14 @@ func (d *Dataset) RandomKeys(m int) []int64 {
 	}
 	return out
 }
+
+// ChooseKeys returns m keys drawn from the sorted keys of the dataset by kc.
+// Unlike RandomKeys, keys may repeat, and m may exceed the dataset size.
+func (d *Dataset) ChooseKeys(m int, kc KeyChooser) []int64 {
+	sorted := d.SortedKeys()
+	out := make([]int64, m)
+	for i := range out {
+		out[i] = sorted[kc.Next(len(sorted))]
+	}
+	return out
+}
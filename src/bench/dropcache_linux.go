@@ -0,0 +1,28 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// dropOSCache evicts the files of the closed index at path from the OS page
+// cache. Dirty pages cannot be dropped, so each file is synced first.
+func dropOSCache(path string) error {
+	for _, name := range indexFiles(path) {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("drop page cache: %w", err)
+		}
+		err = f.Sync()
+		if err == nil {
+			err = unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED)
+		}
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("drop page cache of %s: %w", name, err)
+		}
+	}
+	return nil
+}
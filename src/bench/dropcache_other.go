@@ -0,0 +1,10 @@
+//go:build !linux
+
+package bench
+
+import "errors"
+
+// dropOSCache is only implemented on Linux, which has posix_fadvise.
+func dropOSCache(string) error {
+	return errors.New("dropping the OS page cache is only supported on Linux")
+}
@@ -0,0 +1,307 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-echarts/go-echarts/v2/components"
+)
+
+// Export formats: static images of every chart and Typst table snippets of
+// the results.
+const (
+	ExportSVG   = "svg"
+	ExportPNG   = "png"
+	ExportTypst = "typ"
+)
+
+// ExportFormats lists the values of ExportOptions.Formats.
+var ExportFormats = []string{ExportSVG, ExportPNG, ExportTypst}
+
+// ExportOptions make PlotAllExport write, besides the HTML pages, every
+// chart as a static image and the main results as Typst tables, e.g. into
+// the thesis' assets/results directory.
+type ExportOptions struct {
+	Dir     string   // output directory
+	Formats []string // of ExportFormats
+}
+
+func (o ExportOptions) has(format string) bool {
+	for _, f := range o.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// exporting is set while PlotAllExport runs; renderPage then also exports
+// the charts of each page.
+var exporting *ExportOptions
+
+// PlotAllExport plots the results in outDir like PlotAll and exports the
+// charts and tables as set by o.
+func PlotAllExport(outDir string, o ExportOptions) error {
+	for _, f := range o.Formats {
+		if !(ExportOptions{Formats: ExportFormats}).has(f) {
+			return fmt.Errorf("unknown export format %q (want some of %v)", f, ExportFormats)
+		}
+	}
+	if err := os.MkdirAll(o.Dir, 0755); err != nil {
+		return fmt.Errorf("create export dir: %w", err)
+	}
+	exporting = &o
+	defer func() { exporting = nil }()
+	if err := PlotAll(outDir); err != nil {
+		return err
+	}
+	if o.has(ExportTypst) {
+		return exportTables(outDir, o.Dir)
+	}
+	return nil
+}
+
+var nonAlnum = regexp.MustCompile(`[^a-z0-9]+`)
+
+// figureName returns the file name, without extension, of an exported
+// chart: its title in lower case with dashes, e.g. "t1-point-query-throughput".
+func figureName(title string) string {
+	return strings.Trim(nonAlnum.ReplaceAllString(strings.ToLower(title), "-"), "-")
+}
+
+// exportPage writes the charts of page as static images.
+func exportPage(page *components.Page, o *ExportOptions) error {
+	for _, c := range page.Charts {
+		f, ok := figureOf(c)
+		if !ok {
+			continue
+		}
+		base := filepath.Join(o.Dir, figureName(f.title))
+		if o.has(ExportSVG) {
+			svg := newSVGCanvas()
+			f.draw(svg)
+			if err := os.WriteFile(base+".svg", svg.bytes(), 0644); err != nil {
+				return fmt.Errorf("write %s.svg: %w", base, err)
+			}
+		}
+		if o.has(ExportPNG) {
+			r := newRasterCanvas()
+			f.draw(r)
+			data, err := r.png()
+			if err != nil {
+				return fmt.Errorf("encode %s.png: %w", base, err)
+			}
+			if err := os.WriteFile(base+".png", data, 0644); err != nil {
+				return fmt.Errorf("write %s.png: %w", base, err)
+			}
+		}
+	}
+	return nil
+}
+
+// typstColumn is a result column shown in a Typst table.
+type typstColumn struct {
+	name, heading string
+	format        func(string) string // nil to show the value as is
+}
+
+// typstTable selects the columns of a result CSV for a Typst table.
+type typstTable struct {
+	file    string // glob, relative to the results directory
+	columns []typstColumn
+	last    bool // only the last row of each index, e.g. the end of T3
+}
+
+var (
+	colIndex   = typstColumn{"index", "Index", nil}
+	colCache   = typstColumn{"cache", "Cache", nil}
+	colP50     = typstColumn{"p50_ns", "P50 (ns)", formatInt}
+	colP95     = typstColumn{"p95_ns", "P95 (ns)", formatInt}
+	colP99     = typstColumn{"p99_ns", "P99 (ns)", formatInt}
+	colOps     = typstColumn{"ops_per_sec", "Ops/s", formatInt}
+	colReadAmp = typstColumn{"read_amp", "Read Amp.", nil}
+	colSpace   = typstColumn{"space_amp", "Space Amp.", nil}
+)
+
+var typstTables = []typstTable{
+	{file: "t1_point_query.csv", columns: []typstColumn{colIndex, colCache, colP50, colP95, colP99, colOps, colReadAmp}},
+	{file: "t2_range_query.csv", columns: []typstColumn{
+		colIndex, {"range_size", "Range Size", nil}, colCache, {"total_ms", "Total (ms)", formatInt}, colOps, colReadAmp,
+	}},
+	{file: "t3_write_throughput.csv", last: true, columns: []typstColumn{
+		colIndex, {"cumulative_ops", "Entries", nil}, colOps, {"write_amp", "Write Amp.", nil}, colSpace,
+	}},
+	{file: "*_summary.csv", columns: []typstColumn{
+		colIndex, {"op_type", "Operation", nil}, {"count", "Count", nil}, colP50, colP95, colP99, colOps,
+	}},
+	{file: SpaceFile, columns: []typstColumn{
+		{"test", "Test", nil}, colIndex, {"phase", "Phase", nil}, {"disk_bytes", "Size (MiB)", formatMiB},
+		{"pages", "Pages", nil}, {"avg_fill", "Avg. Fill", formatPercent}, colSpace,
+	}},
+}
+
+func formatInt(s string) string {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
+
+func formatMiB(s string) string {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	return strconv.FormatFloat(v/(1<<20), 'f', 1, 64)
+}
+
+func formatPercent(s string) string {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	return strconv.FormatFloat(100*v, 'f', 1, 64) + "%"
+}
+
+// exportTables writes a Typst table for every result CSV in outDir that
+// typstTables covers, and one of the machine from RunInfoFile.
+func exportTables(outDir, dir string) error {
+	for _, t := range typstTables {
+		files, err := filepath.Glob(filepath.Join(outDir, t.file))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			records, err := readCSV(file)
+			if err != nil {
+				return err
+			}
+			if len(records) < 2 {
+				continue
+			}
+			name := strings.TrimSuffix(filepath.Base(file), ".csv") + ".typ"
+			if err := writeTypst(filepath.Join(dir, name), filepath.Base(file), typstRows(t, records)); err != nil {
+				return err
+			}
+		}
+	}
+	info, err := LoadRunInfo(outDir)
+	if err != nil {
+		return nil // no run.json, e.g. results from before it existed
+	}
+	e := info.Env
+	rows := [][]string{
+		{"Component", "Specification"},
+		{"Processor", fmt.Sprintf("%s (%d threads)", e.CPUModel, e.NumCPU)},
+		{"Memory", fmt.Sprintf("%.0f GiB", float64(e.MemoryBytes)/(1<<30))},
+		{"OS", fmt.Sprintf("%s/%s, kernel %s", e.OS, e.Arch, e.Kernel)},
+		{"File System", e.DataDirFS},
+		{"Go Runtime", e.GoVersion},
+	}
+	return writeTypst(filepath.Join(dir, "environment.typ"), RunInfoFile, rows)
+}
+
+// typstRows returns the heading and rows of t for the records of a CSV.
+// Values of aggregated repetitions get their confidence interval appended.
+func typstRows(t typstTable, records [][]string) [][]string {
+	header := records[0]
+	var shown []typstColumn
+	var cols, cis []int
+	var heading []string
+	for _, c := range t.columns {
+		for i, name := range header {
+			if name == c.name {
+				shown = append(shown, c)
+				cols, cis = append(cols, i), append(cis, ciColumn(header, i))
+				heading = append(heading, c.heading)
+			}
+		}
+	}
+	body := records[1:]
+	if t.last {
+		var lastOf []int
+		pos := make(map[string]int)
+		for i, rec := range body {
+			if p, ok := pos[rec[0]]; ok {
+				lastOf[p] = i
+			} else {
+				pos[rec[0]] = len(lastOf)
+				lastOf = append(lastOf, i)
+			}
+		}
+		var last [][]string
+		for _, i := range lastOf {
+			last = append(last, body[i])
+		}
+		body = last
+	}
+	rows := [][]string{heading}
+	for _, rec := range body {
+		var row []string
+		for k, i := range cols {
+			col := shown[k]
+			v := ""
+			if i < len(rec) {
+				v = rec[i]
+			}
+			if col.format != nil {
+				v = col.format(v)
+			}
+			if ci := parseCI(rec, cis[k]); ci > 0 {
+				ciText := formatStat(ci)
+				if col.format != nil {
+					ciText = col.format(ciText)
+				}
+				v += " ± " + ciText
+			}
+			row = append(row, v)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// writeTypst writes rows, the first being the heading, as a Typst table in
+// the style of the thesis, to be placed with #include in a #figure.
+func writeTypst(path, source string, rows [][]string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated from %s by the plot command; changes will be overwritten.\n", source)
+	b.WriteString("#table(\n")
+	fmt.Fprintf(&b, "  columns: (%s),\n", strings.TrimSuffix(strings.Repeat("auto, ", len(rows[0])), ", "))
+	b.WriteString("  inset: 10pt,\n  align: horizon,\n")
+	var heading []string
+	for _, h := range rows[0] {
+		heading = append(heading, "[*"+typstEscape(h)+"*]")
+	}
+	fmt.Fprintf(&b, "  table.header(%s),\n", strings.Join(heading, ", "))
+	for _, row := range rows[1:] {
+		var cells []string
+		for _, v := range row {
+			cells = append(cells, "["+typstEscape(v)+"]")
+		}
+		fmt.Fprintf(&b, "  %s,\n", strings.Join(cells, ", "))
+	}
+	b.WriteString(")\n")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	fmt.Printf("[Export] Typst table written to %s\n", path)
+	return nil
+}
+
+// typstEscape escapes the characters with a meaning in Typst markup.
+func typstEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\*_#$@<>[]~`+"`", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,571 @@
+package bench
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// Size of exported figures in SVG user units (PNGs get figureScale pixels
+// per unit), the same as the HTML charts.
+const (
+	figureWidth  = 1000
+	figureHeight = 500
+	figureScale  = 2
+)
+
+// echartsPalette are the series colors ECharts uses when none is set.
+var echartsPalette = []string{
+	"#5470c6", "#91cc75", "#fac858", "#ee6666", "#73c0de",
+	"#3ba272", "#fc8452", "#9a60b4", "#ea7ccc",
+}
+
+// figure is a chart of the plotter reduced to what a static image of it
+// needs: bar, line, box plot, heatmap and error bar (see errorBars) series
+// over a category, value or log x axis.
+type figure struct {
+	title, xName, yName string
+	xType               string   // ECharts axis type: "category" (or ""), "value" or "log"
+	categories          []string // of a category x axis
+	yCategories         []string // of the y axis of heatmaps; other figures have a value y axis
+	rotateLabels        float64  // degrees, counterclockwise
+	visualMax           float64  // heatmap value that gets the last of visualColors
+	visualColors        []string // heatmap color scale
+	visualLabel         string
+	series              []figureSeries
+}
+
+type figureSeries struct {
+	name, kind string // kind is the ECharts series type: bar, line, boxplot, heatmap or custom
+	color      string
+	values     []float64    // bar and line on a category axis
+	points     [][2]float64 // line on a value or log axis
+	colors     []string     // per item, "" for the series color
+	boxes      [][5]float64 // boxplot: lower whisker, q1, median, q3, upper whisker; NaN if empty
+	cells      [][3]float64 // heatmap: x category, y category, value
+	errors     [][5]float64 // custom: category, low, high, bar, bars
+}
+
+// figureOf extracts the figure of a chart on a page. It reports false for
+// chart types the static renderer does not handle.
+func figureOf(c components.Charter) (*figure, bool) {
+	var rc *charts.RectChart
+	switch c := c.(type) {
+	case *charts.Bar:
+		rc = &c.RectChart
+	case *charts.Line:
+		rc = &c.RectChart
+	case *charts.BoxPlot:
+		rc = &c.RectChart
+	case *charts.HeatMap:
+		rc = &c.RectChart
+	default:
+		return nil, false
+	}
+	f := &figure{title: rc.Title.Title}
+	if len(rc.XAxisList) > 0 {
+		x := rc.XAxisList[0]
+		f.xName, f.xType = x.Name, x.Type
+		f.categories, _ = x.Data.([]string)
+		if x.AxisLabel != nil {
+			f.rotateLabels = x.AxisLabel.Rotate
+		}
+	}
+	if len(rc.YAxisList) > 0 {
+		f.yName = rc.YAxisList[0].Name
+		f.yCategories, _ = rc.YAxisList[0].Data.([]string)
+	}
+	if len(rc.VisualMapList) > 0 {
+		vm := rc.VisualMapList[0]
+		f.visualMax = float64(vm.Max)
+		if vm.InRange != nil {
+			f.visualColors = vm.InRange.Color
+		}
+		if len(vm.Text) > 0 {
+			f.visualLabel = vm.Text[0]
+		}
+	}
+	for i, s := range rc.MultiSeries {
+		fs := figureSeries{name: s.Name, kind: s.Type, color: echartsPalette[i%len(echartsPalette)]}
+		switch {
+		case s.LineStyle != nil && s.LineStyle.Color != "":
+			fs.color = s.LineStyle.Color
+		case s.ItemStyle != nil && s.ItemStyle.Color != "":
+			fs.color = s.ItemStyle.Color
+		}
+		itemColor := func(st *opts.ItemStyle) string {
+			if st == nil {
+				return ""
+			}
+			return st.Color
+		}
+		switch data := s.Data.(type) {
+		case []opts.BarData:
+			for _, d := range data {
+				fs.values = append(fs.values, toFloat(d.Value))
+				fs.colors = append(fs.colors, itemColor(d.ItemStyle))
+			}
+		case []opts.LineData:
+			for _, d := range data {
+				if xy, ok := d.Value.([]interface{}); ok && len(xy) == 2 {
+					fs.points = append(fs.points, [2]float64{toFloat(xy[0]), toFloat(xy[1])})
+				} else {
+					fs.values = append(fs.values, toFloat(d.Value))
+				}
+			}
+		case []opts.BoxPlotData:
+			for _, d := range data {
+				box := [5]float64{math.NaN(), math.NaN(), math.NaN(), math.NaN(), math.NaN()}
+				vs, _ := d.Value.([]interface{})
+				for j := 0; j < len(box) && j < len(vs); j++ {
+					box[j] = toFloat(vs[j])
+				}
+				fs.boxes = append(fs.boxes, box)
+				fs.colors = append(fs.colors, itemColor(d.ItemStyle))
+			}
+		case []opts.HeatMapData:
+			for _, d := range data {
+				if v, ok := d.Value.([3]interface{}); ok {
+					fs.cells = append(fs.cells, [3]float64{toFloat(v[0]), toFloat(v[1]), toFloat(v[2])})
+				}
+			}
+		case []opts.CustomData:
+			for _, d := range data {
+				var e [5]float64
+				vs, _ := d.Value.([]interface{})
+				for j := 0; j < len(e) && j < len(vs); j++ {
+					e[j] = toFloat(vs[j])
+				}
+				fs.errors = append(fs.errors, e)
+			}
+		default:
+			continue
+		}
+		f.series = append(f.series, fs)
+	}
+	return f, true
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			return f
+		}
+	}
+	return math.NaN()
+}
+
+// canvas is a drawing surface for figures, in SVG user units with y growing
+// downwards. Colors are "#rrggbb".
+type canvas interface {
+	rect(x, y, w, h float64, fill string)
+	line(x1, y1, x2, y2 float64, stroke string, width float64)
+	polyline(pts [][2]float64, stroke string, width float64)
+	dot(x, y, r float64, fill string)
+	// text draws s vertically centered on y and, depending on anchor
+	// ("start", "middle" or "end"), starting, centered or ending at x,
+	// rotated counterclockwise by rotate degrees around (x, y).
+	text(x, y float64, s string, size float64, anchor string, rotate float64, fill string, bold bool)
+}
+
+// textWidth estimates the width of s in a sans-serif font of the given size.
+func textWidth(s string, size float64) float64 {
+	return float64(len([]rune(s))) * 0.6 * size
+}
+
+// draw renders f onto c, laid out like ECharts' defaults for the charts of
+// the plotter: title top left, legend centered below it, axes, then the
+// series in the order bars, boxes, heatmap cells, lines, error bars.
+func (f *figure) draw(c canvas) {
+	const (
+		axisColor = "#6e7079"
+		gridColor = "#e0e6f1"
+		left      = 80.0
+	)
+	right := figureWidth - 30.0
+	if len(f.visualColors) > 0 {
+		right = figureWidth - 130 // room for the color scale
+	}
+	c.rect(0, 0, figureWidth, figureHeight, "#ffffff")
+	c.text(10, 20, f.title, 18, "start", 0, "#464646", true)
+
+	// Legend: one entry per named series, wrapped into centered rows.
+	type entry struct {
+		name, color string
+		width       float64
+	}
+	var entries []entry
+	seen := make(map[string]bool)
+	for _, s := range f.series {
+		if s.kind == "custom" || s.kind == "heatmap" || seen[s.name] {
+			continue
+		}
+		seen[s.name] = true
+		color := s.color
+		if len(s.colors) > 0 && s.colors[0] != "" {
+			color = s.colors[0]
+		}
+		entries = append(entries, entry{s.name, color, 25 + textWidth(s.name, 12)})
+	}
+	legendY := 50.0
+	for len(entries) > 0 {
+		n, width := 0, 0.0
+		for n < len(entries) && (n == 0 || width+entries[n].width+10 <= right-left) {
+			width += entries[n].width + 10
+			n++
+		}
+		x := (figureWidth - width + 10) / 2
+		for _, e := range entries[:n] {
+			c.rect(x, legendY-7, 20, 14, e.color)
+			c.text(x+25, legendY, e.name, 12, "start", 0, "#333333", false)
+			x += e.width + 10
+		}
+		entries = entries[n:]
+		legendY += 22
+	}
+
+	top := legendY + 20
+	bottom := figureHeight - 50.0
+	if f.rotateLabels != 0 {
+		bottom = figureHeight - 100.0
+	}
+
+	// Y axis: categories in equal bands for heatmaps, otherwise from 0 (or
+	// below) to the largest value at round ticks.
+	var py func(v float64) float64
+	axisY := bottom
+	if ny := len(f.yCategories); ny > 0 {
+		yBand := (bottom - top) / float64(ny)
+		py = func(v float64) float64 { return bottom - (v+0.5)*yBand }
+		every := int(math.Ceil(16 / yBand))
+		for i, cat := range f.yCategories {
+			c.line(left-5, bottom-float64(i)*yBand, left, bottom-float64(i)*yBand, axisColor, 1)
+			if i%every == 0 {
+				c.text(left-8, py(float64(i)), cat, 12, "end", 0, axisColor, false)
+			}
+		}
+		c.line(left, top, left, bottom, axisColor, 1)
+	} else {
+		lo, hi := 0.0, 0.0
+		extend := func(v float64) {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				lo, hi = min(lo, v), max(hi, v)
+			}
+		}
+		for _, s := range f.series {
+			for _, v := range s.values {
+				extend(v)
+			}
+			for _, p := range s.points {
+				extend(p[1])
+			}
+			for _, b := range s.boxes {
+				extend(b[0])
+				extend(b[4])
+			}
+			for _, e := range s.errors {
+				extend(e[1])
+				extend(e[2])
+			}
+		}
+		if hi == lo {
+			hi = lo + 1
+		}
+		step := niceStep((hi - lo) / 5)
+		lo, hi = math.Floor(lo/step)*step, math.Ceil(hi/step)*step
+		py = func(v float64) float64 { return bottom - (v-lo)/(hi-lo)*(bottom-top) }
+		for v := lo; v <= hi+step/2; v += step {
+			c.line(left, py(v), right, py(v), gridColor, 1)
+			c.text(left-8, py(v), formatTick(v), 12, "end", 0, axisColor, false)
+		}
+		axisY = py(0)
+	}
+	c.text(left, top-14, f.yName, 12, "middle", 0, axisColor, false)
+
+	// X axis: a value or log axis for series of points, otherwise categories
+	// in equal bands with the labels thinned out if they would overlap.
+	c.line(left, axisY, right, axisY, axisColor, 1)
+	n := max(len(f.categories), 1)
+	band := (right - left) / float64(n)
+	cx := func(i int) float64 { return left + (float64(i)+0.5)*band }
+	var px func(v float64) float64
+	if f.xType == "log" || f.xType == "value" {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, s := range f.series {
+			for _, p := range s.points {
+				if p[0] > 0 || f.xType == "value" {
+					lo, hi = min(lo, p[0]), max(hi, p[0])
+				}
+			}
+		}
+		if math.IsInf(lo, 0) {
+			lo, hi = 1, 10
+		}
+		var ticks []float64
+		if f.xType == "log" {
+			lo, hi = math.Floor(math.Log10(lo)), math.Ceil(math.Log10(hi))
+			hi = max(hi, lo+1)
+			px = func(v float64) float64 { return left + (math.Log10(v)-lo)/(hi-lo)*(right-left) }
+			for e := lo; e <= hi; e++ {
+				ticks = append(ticks, math.Pow(10, e))
+			}
+		} else {
+			lo = min(lo, 0)
+			hi = max(hi, lo+1)
+			step := niceStep((hi - lo) / 8)
+			lo, hi = math.Floor(lo/step)*step, math.Ceil(hi/step)*step
+			px = func(v float64) float64 { return left + (v-lo)/(hi-lo)*(right-left) }
+			for v := lo; v <= hi+step/2; v += step {
+				ticks = append(ticks, v)
+			}
+		}
+		for _, t := range ticks {
+			c.line(px(t), axisY, px(t), axisY+5, axisColor, 1)
+			c.text(px(t), bottom+14, formatTick(t), 12, "middle", 0, axisColor, false)
+		}
+	} else {
+		labelSpace := 70.0
+		if f.rotateLabels != 0 {
+			labelSpace = 20
+		}
+		every := int(math.Ceil(labelSpace / band))
+		for i, cat := range f.categories {
+			c.line(left+float64(i)*band, axisY, left+float64(i)*band, axisY+5, axisColor, 1)
+			if i%every != 0 {
+				continue
+			}
+			if f.rotateLabels != 0 {
+				c.text(cx(i), bottom+12, cat, 12, "end", f.rotateLabels, axisColor, false)
+			} else {
+				c.text(cx(i), bottom+14, cat, 12, "middle", 0, axisColor, false)
+			}
+		}
+		c.line(right, axisY, right, axisY+5, axisColor, 1)
+	}
+	if f.xName != "" {
+		c.text((left+right)/2, figureHeight-16, f.xName, 12, "middle", 0, axisColor, false)
+	}
+
+	// Grouped bars and boxes follow ECharts' default layout (see errorBarRender).
+	groups := make(map[string]int)
+	for _, s := range f.series {
+		groups[s.kind]++
+	}
+	groupWidth := func(kind string) float64 {
+		k := float64(groups[kind])
+		return 0.8 * band / (k + 0.3*(k-1))
+	}
+	member := make(map[string]int)
+	for _, s := range f.series {
+		k := member[s.kind]
+		member[s.kind]++
+		itemColor := func(i int) string {
+			if i < len(s.colors) && s.colors[i] != "" {
+				return s.colors[i]
+			}
+			return s.color
+		}
+		switch s.kind {
+		case "bar":
+			w := groupWidth("bar")
+			for i, v := range s.values {
+				if math.IsNaN(v) {
+					continue
+				}
+				x := cx(i) - 0.4*band + float64(k)*1.3*w
+				y0, y1 := py(max(v, 0)), py(min(v, 0))
+				c.rect(x, y0, w, y1-y0, itemColor(i))
+			}
+		case "boxplot":
+			for i, b := range s.boxes {
+				if math.IsNaN(b[0]) {
+					continue
+				}
+				x, w := cx(i), min(0.5*band, 50)
+				if groups["boxplot"] > 1 {
+					gw := groupWidth("boxplot")
+					x, w = cx(i)-0.4*band+float64(k)*1.3*gw+gw/2, min(gw, 50)
+				}
+				c.line(x, py(b[0]), x, py(b[1]), "#333333", 1)
+				c.line(x, py(b[3]), x, py(b[4]), "#333333", 1)
+				c.line(x-w/4, py(b[0]), x+w/4, py(b[0]), "#333333", 1)
+				c.line(x-w/4, py(b[4]), x+w/4, py(b[4]), "#333333", 1)
+				c.rect(x-w/2, py(b[3]), w, py(b[1])-py(b[3]), itemColor(i))
+				strokeRect(c, x-w/2, py(b[3]), w, py(b[1])-py(b[3]), "#333333", 1)
+				c.line(x-w/2, py(b[2]), x+w/2, py(b[2]), "#333333", 2)
+			}
+		case "heatmap":
+			yBand := (bottom - top) / float64(max(len(f.yCategories), 1))
+			for _, cell := range s.cells {
+				x0 := left + cell[0]*band
+				c.rect(x0, py(cell[1])-yBand/2, band, yBand, gradient(f.visualColors, cell[2]/f.visualMax))
+			}
+		}
+	}
+
+	for _, s := range f.series {
+		if s.kind != "line" {
+			continue
+		}
+		var pts [][2]float64
+		for i, v := range s.values {
+			if !math.IsNaN(v) {
+				pts = append(pts, [2]float64{cx(i), py(v)})
+			}
+		}
+		for _, p := range s.points {
+			if px != nil && !math.IsNaN(p[1]) && (f.xType != "log" || p[0] > 0) {
+				pts = append(pts, [2]float64{px(p[0]), py(p[1])})
+			}
+		}
+		c.polyline(pts, s.color, 2)
+		if len(s.values) > 0 && len(pts) <= 50 {
+			for _, p := range pts {
+				c.dot(p[0], p[1], 3, s.color)
+			}
+		}
+	}
+
+	for _, s := range f.series {
+		for _, e := range s.errors {
+			x, half := cx(int(e[0])), band/8
+			if e[3] >= 0 && e[4] > 0 {
+				w := 0.8 * band / (e[4] + 0.3*(e[4]-1))
+				x += -0.4*band + e[3]*1.3*w + w/2
+				half = w / 4
+			}
+			c.line(x-half, py(e[2]), x+half, py(e[2]), "#333333", 1.2)
+			c.line(x, py(e[2]), x, py(e[1]), "#333333", 1.2)
+			c.line(x-half, py(e[1]), x+half, py(e[1]), "#333333", 1.2)
+		}
+	}
+
+	// Color scale of a heatmap, like ECharts' continuous visual map.
+	if len(f.visualColors) > 0 {
+		const steps = 40
+		x, h := right+50, (bottom-top)*0.6
+		y0 := top + (bottom-top-h)/2
+		for i := 0; i < steps; i++ {
+			c.rect(x, y0+h*float64(i)/steps, 20, h/steps+0.5, gradient(f.visualColors, 1-(float64(i)+0.5)/steps))
+		}
+		c.text(x+10, y0-24, f.visualLabel, 12, "middle", 0, "#333333", false)
+		c.text(x+10, y0-10, formatTick(math.Round(f.visualMax*10)/10), 12, "middle", 0, axisColor, false)
+		c.text(x+10, y0+h+10, "0", 12, "middle", 0, axisColor, false)
+	}
+}
+
+// gradient returns the color at t (0 to 1) of a scale running evenly
+// through colors.
+func gradient(colors []string, t float64) string {
+	if len(colors) == 0 {
+		return "#000000"
+	}
+	t = max(0, min(1, t))
+	pos := t * float64(len(colors)-1)
+	i := min(int(pos), len(colors)-2)
+	if i < 0 {
+		return colors[0]
+	}
+	a, b := parseColor(colors[i]), parseColor(colors[i+1])
+	f := pos - float64(i)
+	mix := func(x, y uint8) uint8 { return uint8(math.Round(float64(x) + f*(float64(y)-float64(x)))) }
+	return fmt.Sprintf("#%02x%02x%02x", mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B))
+}
+
+func strokeRect(c canvas, x, y, w, h float64, stroke string, width float64) {
+	c.polyline([][2]float64{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}, {x, y}}, stroke, width)
+}
+
+// niceStep rounds step up to 1, 2 or 5 times a power of ten.
+func niceStep(step float64) float64 {
+	if step <= 0 {
+		return 1
+	}
+	p := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, m := range []float64{1, 2, 5} {
+		if m*p >= step {
+			return m * p
+		}
+	}
+	return 10 * p
+}
+
+// formatTick formats an axis value compactly, e.g. 1500000 as 1.5M.
+func formatTick(v float64) string {
+	for _, u := range []struct {
+		div    float64
+		suffix string
+	}{{1e9, "G"}, {1e6, "M"}, {1e3, "k"}} {
+		if math.Abs(v) >= u.div {
+			return strconv.FormatFloat(v/u.div, 'f', -1, 64) + u.suffix
+		}
+	}
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
+
+// svgCanvas draws a figure as an SVG document.
+type svgCanvas struct {
+	b strings.Builder
+}
+
+func newSVGCanvas() *svgCanvas {
+	c := &svgCanvas{}
+	fmt.Fprintf(&c.b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif">`+"\n",
+		figureWidth, figureHeight, figureWidth, figureHeight)
+	return c
+}
+
+func (c *svgCanvas) rect(x, y, w, h float64, fill string) {
+	fmt.Fprintf(&c.b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`+"\n", x, y, w, h, fill)
+}
+
+func (c *svgCanvas) line(x1, y1, x2, y2 float64, stroke string, width float64) {
+	fmt.Fprintf(&c.b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%g"/>`+"\n", x1, y1, x2, y2, stroke, width)
+}
+
+func (c *svgCanvas) polyline(pts [][2]float64, stroke string, width float64) {
+	var points []string
+	for _, p := range pts {
+		points = append(points, fmt.Sprintf("%.1f,%.1f", p[0], p[1]))
+	}
+	fmt.Fprintf(&c.b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%g" stroke-linejoin="round"/>`+"\n",
+		strings.Join(points, " "), stroke, width)
+}
+
+func (c *svgCanvas) dot(x, y, r float64, fill string) {
+	fmt.Fprintf(&c.b, `<circle cx="%.1f" cy="%.1f" r="%g" fill="%s"/>`+"\n", x, y, r, fill)
+}
+
+func (c *svgCanvas) text(x, y float64, s string, size float64, anchor string, rotate float64, fill string, bold bool) {
+	attrs := fmt.Sprintf(`x="%.1f" y="%.1f" font-size="%g" fill="%s" text-anchor="%s" dominant-baseline="central"`, x, y, size, fill, anchor)
+	if bold {
+		attrs += ` font-weight="bold"`
+	}
+	if rotate != 0 {
+		attrs += fmt.Sprintf(` transform="rotate(%g %.1f %.1f)"`, -rotate, x, y)
+	}
+	fmt.Fprintf(&c.b, "<text %s>%s</text>\n", attrs, html.EscapeString(s))
+}
+
+// bytes returns the finished document.
+func (c *svgCanvas) bytes() []byte {
+	return []byte(c.b.String() + "</svg>\n")
+}
@@ -0,0 +1,186 @@
+package bench
+
+import (
+	"encoding/csv"
+	"math/bits"
+	"strconv"
+)
+
+// histSubBits sets the precision of Histogram: every power of two is split
+// into 2^(histSubBits-1) buckets, so a recorded value is off by less than
+// 1/2^(histSubBits-1) (1.6%), while values below 2^histSubBits are exact.
+const histSubBits = 7
+
+// Histogram records non-negative values (latencies in ns) in log-scaled
+// buckets, in the manner of HdrHistogram. Unlike a sorted slice of all
+// samples it needs a few KiB however many values it holds, answers any
+// percentile, and histograms of different clients or intervals can be merged.
+// The zero value is an empty histogram.
+type Histogram struct {
+	counts   []uint64
+	count    uint64
+	sum      float64
+	min, max int64
+}
+
+// histBucket returns the index of the bucket holding v.
+func histBucket(v int64) int {
+	if v < 1<<histSubBits {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - histSubBits
+	return shift<<(histSubBits-1) + int(v>>shift)
+}
+
+// histBucketRange returns the smallest and largest value of bucket i.
+func histBucketRange(i int) (lo, hi int64) {
+	if i < 1<<histSubBits {
+		return int64(i), int64(i)
+	}
+	half := 1 << (histSubBits - 1)
+	shift := i/half - 1
+	top := int64(i - shift*half)
+	lo = top << shift
+	return lo, lo + (1 << shift) - 1
+}
+
+// Record adds v; negative values count as 0.
+func (h *Histogram) Record(v int64) {
+	v = max(v, 0)
+	i := histBucket(v)
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	h.max = max(h.max, v)
+	h.count++
+	h.sum += float64(v)
+}
+
+// Merge adds all values recorded in o to h.
+func (h *Histogram) Merge(o *Histogram) {
+	if o.count == 0 {
+		return
+	}
+	if len(o.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, len(o.counts)-len(h.counts))...)
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	h.max = max(h.max, o.max)
+	h.count += o.count
+	h.sum += o.sum
+}
+
+// Count returns the number of recorded values.
+func (h *Histogram) Count() int { return int(h.count) }
+
+// Min returns the smallest recorded value, or 0 if h is empty.
+func (h *Histogram) Min() int64 { return h.min }
+
+// Max returns the largest recorded value, or 0 if h is empty.
+func (h *Histogram) Max() int64 { return h.max }
+
+// Mean returns the average of the recorded values, or 0 if h is empty.
+func (h *Histogram) Mean() int64 {
+	if h.count == 0 {
+		return 0
+	}
+	return int64(h.sum / float64(h.count))
+}
+
+// Percentile returns the value below which p percent of the recorded values
+// fall, rounded up to the end of its bucket but never beyond Max. Like an
+// index of p·n/100 into the sorted values, p = 0 gives Min and p = 100 Max.
+func (h *Histogram) Percentile(p float64) int64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := min(uint64(p/100*float64(h.count))+1, h.count)
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			_, hi := histBucketRange(i)
+			return max(min(hi, h.max), h.min)
+		}
+	}
+	return h.max
+}
+
+// HistogramBucket is one non-empty bucket of a Histogram.
+type HistogramBucket struct {
+	Value      int64   // largest value of the bucket (Max for the last one)
+	Count      uint64  // values in the bucket
+	Cumulative float64 // fraction of all values up to and including this bucket
+}
+
+// Buckets returns the non-empty buckets in ascending order: the full
+// distribution, e.g. for a CDF plot.
+func (h *Histogram) Buckets() []HistogramBucket {
+	var out []HistogramBucket
+	var seen uint64
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		seen += c
+		_, hi := histBucketRange(i)
+		out = append(out, HistogramBucket{
+			Value:      min(hi, h.max),
+			Count:      c,
+			Cumulative: float64(seen) / float64(h.count),
+		})
+	}
+	return out
+}
+
+// latencyHeader is the header of the latency distribution CSVs, which hold
+// one row per non-empty histogram bucket.
+var latencyHeader = []string{"index", "type", "latency_ns", "count", "cumulative"}
+
+// writeLatency appends the distribution of h to a latency distribution CSV.
+func writeLatency(w *csv.Writer, index, op string, h *Histogram) {
+	for _, b := range h.Buckets() {
+		_ = w.Write([]string{
+			index, op, strconv.FormatInt(b.Value, 10),
+			strconv.FormatUint(b.Count, 10), strconv.FormatFloat(b.Cumulative, 'f', 6, 64),
+		})
+	}
+}
+
+// heatmapWindows is the number of equal windows of operations a mixed
+// workload's latencies over time are split into.
+const heatmapWindows = 50
+
+// heatmapHeader is the header of the latency over time CSVs of the mixed
+// workloads: per window of operations starting at op_count, how many took
+// between latency_ns and twice that (or 0 and 1 ns).
+var heatmapHeader = []string{"index", "op_count", "latency_ns", "count"}
+
+// writeHeatmap appends the distribution of h, the latencies of the window
+// starting at operation start, in power-of-two buckets to a heatmap CSV.
+func writeHeatmap(w *csv.Writer, index string, start int, h *Histogram) {
+	var lows []int64
+	counts := make(map[int64]uint64)
+	for _, b := range h.Buckets() {
+		low := int64(0)
+		if b.Value > 0 {
+			low = 1 << (bits.Len64(uint64(b.Value)) - 1)
+		}
+		if _, ok := counts[low]; !ok {
+			lows = append(lows, low)
+		}
+		counts[low] += b.Count
+	}
+	for _, low := range lows {
+		_ = w.Write([]string{index, strconv.Itoa(start), strconv.FormatInt(low, 10), strconv.FormatUint(counts[low], 10)})
+	}
+}
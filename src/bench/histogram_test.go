@@ -0,0 +1,164 @@
+package bench_test
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/btree-query-bench/bmark/bench"
+)
+
+// histError is the relative error of a recorded value (see Histogram).
+const histError = 1.0 / 64
+
+var histPercentiles = []float64{0, 1, 10, 25, 50, 75, 90, 95, 99, 99.9, 100}
+
+// histSamples are the value sets the Histogram tests record.
+func histSamples() []struct {
+	name   string
+	values []int64
+} {
+	rng := rand.New(rand.NewSource(5))
+	gen := func(n int, f func() int64) []int64 {
+		v := make([]int64, n)
+		for i := range v {
+			v[i] = f()
+		}
+		return v
+	}
+	return []struct {
+		name   string
+		values []int64
+	}{
+		{"single", []int64{4711}},
+		{"constant", gen(1000, func() int64 { return 250 })},
+		{"small", gen(5000, func() int64 { return rng.Int63n(128) })},
+		{"uniform", gen(20000, func() int64 { return rng.Int63n(1_000_000) })},
+		{"exponential", gen(20000, func() int64 { return int64(rng.ExpFloat64() * 5000) })},
+		{"heavy tail", gen(20000, func() int64 { return int64(1000 / math.Pow(1-rng.Float64(), 1/1.2)) })},
+		{"two points", append(gen(990, func() int64 { return 800 }), gen(10, func() int64 { return 3_000_000 })...)},
+	}
+}
+
+func record(values []int64) *bench.Histogram {
+	var h bench.Histogram
+	for _, v := range values {
+		h.Record(v)
+	}
+	return &h
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	for _, tc := range histSamples() {
+		t.Run(tc.name, func(t *testing.T) {
+			h := record(tc.values)
+			sorted := append([]int64(nil), tc.values...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			n := len(sorted)
+
+			if h.Count() != n || h.Min() != sorted[0] || h.Max() != sorted[n-1] {
+				t.Errorf("Count, Min, Max = %d, %d, %d; want %d, %d, %d",
+					h.Count(), h.Min(), h.Max(), n, sorted[0], sorted[n-1])
+			}
+			var sum float64
+			for _, v := range sorted {
+				sum += float64(v)
+			}
+			if want := int64(sum / float64(n)); h.Mean() != want {
+				t.Errorf("Mean = %d, want %d", h.Mean(), want)
+			}
+			for _, p := range histPercentiles {
+				want := sorted[min(int(p/100*float64(n)), n-1)]
+				got := h.Percentile(p)
+				if got < want || float64(got) > float64(want)*(1+histError) || got > h.Max() {
+					t.Errorf("Percentile(%g) = %d, want %d within %.1f%%", p, got, want, 100*histError)
+				}
+			}
+		})
+	}
+}
+
+func TestHistogramBucketBounds(t *testing.T) {
+	// Every bucket ends at a value hi such that hi+1 starts the next one, and
+	// spans less than the relative error.
+	for _, v := range []int64{0, 1, 127, 128, 129, 255, 256, 1000, 65_535, 1 << 20, 123_456_789, 1 << 40} {
+		h := record([]int64{v, 1 << 50})
+		hi := h.Buckets()[0].Value
+		if hi < v || float64(hi) > float64(v)*(1+histError) {
+			t.Errorf("bucket of %d ends at %d", v, hi)
+			continue
+		}
+		h = record([]int64{v, hi, hi + 1, 1 << 50})
+		b := h.Buckets()
+		if len(b) != 3 || b[0].Count != 2 || b[0].Value != hi || b[1].Count != 1 {
+			t.Errorf("buckets of %d, %d, %d = %+v, want %d and %d in one bucket and %d in the next",
+				v, hi, hi+1, b, v, hi, hi+1)
+		}
+	}
+	if h := record([]int64{-5}); h.Min() != 0 || h.Percentile(50) != 0 {
+		t.Errorf("negative value recorded as %d, want 0", h.Min())
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	for _, tc := range histSamples() {
+		t.Run(tc.name, func(t *testing.T) {
+			want := record(tc.values)
+			half := len(tc.values) / 2
+			got := record(tc.values[:half])
+			got.Merge(record(tc.values[half:]))
+			got.Merge(&bench.Histogram{})
+
+			var empty bench.Histogram
+			empty.Merge(want)
+			for _, h := range []*bench.Histogram{got, &empty} {
+				if h.Count() != want.Count() || h.Min() != want.Min() || h.Max() != want.Max() || h.Mean() != want.Mean() {
+					t.Errorf("merged Count, Min, Max, Mean = %d, %d, %d, %d; want %d, %d, %d, %d",
+						h.Count(), h.Min(), h.Max(), h.Mean(), want.Count(), want.Min(), want.Max(), want.Mean())
+				}
+				for _, p := range histPercentiles {
+					if h.Percentile(p) != want.Percentile(p) {
+						t.Errorf("merged Percentile(%g) = %d, want %d", p, h.Percentile(p), want.Percentile(p))
+					}
+				}
+				if !reflect.DeepEqual(h.Buckets(), want.Buckets()) {
+					t.Errorf("merged Buckets differ")
+				}
+			}
+		})
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	for _, tc := range histSamples() {
+		t.Run(tc.name, func(t *testing.T) {
+			h := record(tc.values)
+			b := h.Buckets()
+			var seen uint64
+			for i, bk := range b {
+				seen += bk.Count
+				if bk.Count == 0 {
+					t.Errorf("bucket %d is empty", i)
+				}
+				if i > 0 && bk.Value <= b[i-1].Value {
+					t.Errorf("bucket %d ends at %d, not after %d", i, bk.Value, b[i-1].Value)
+				}
+				if want := float64(seen) / float64(len(tc.values)); math.Abs(bk.Cumulative-want) > 1e-12 {
+					t.Errorf("bucket %d cumulative = %g, want %g", i, bk.Cumulative, want)
+				}
+				// The percentile at the share of values before this bucket falls into it.
+				if p := 100 * float64(seen-bk.Count) / float64(len(tc.values)); h.Percentile(p) > bk.Value {
+					t.Errorf("Percentile(%g) = %d beyond the bucket ending at %d", p, h.Percentile(p), bk.Value)
+				}
+			}
+			if seen != uint64(len(tc.values)) {
+				t.Errorf("buckets hold %d values, want %d", seen, len(tc.values))
+			}
+			if last := b[len(b)-1]; last.Value != h.Max() || last.Cumulative != 1 {
+				t.Errorf("last bucket = %+v, want Value %d and Cumulative 1", last, h.Max())
+			}
+		})
+	}
+}
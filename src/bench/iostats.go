@@ -0,0 +1,128 @@
+package bench
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+)
+
+// ioHeader lists the I/O columns appended to every result CSV.
+var ioHeader = []string{
+	"cache_hits", "cache_misses", "evictions", "page_reads", "page_writes", "syncs",
+	"bytes_read", "bytes_written", "disk_bytes", "read_amp", "write_amp", "space_amp",
+}
+
+// IOResult is the I/O of an index during one measured phase of a test.
+// Indexes without I/O accounting (the in-memory baselines) report zeros.
+type IOResult struct {
+	index.IOStats
+	DiskBytes int64   // size of the index files when the phase ended, plus an LSM's memtables
+	ReadAmp   float64 // bytes read from disk per byte of keys and values returned
+	WriteAmp  float64 // bytes written to disk per byte of keys and values inserted
+	SpaceAmp  float64 // disk bytes per byte of live keys and values
+}
+
+// row formats r in the order of ioHeader.
+func (r IOResult) row() []string {
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	return []string{
+		u(r.CacheHits), u(r.CacheMisses), u(r.Evictions), u(r.PageReads), u(r.PageWrites), u(r.Syncs),
+		u(r.BytesRead), u(r.BytesWritten), strconv.FormatInt(r.DiskBytes, 10),
+		f(r.ReadAmp), f(r.WriteAmp), f(r.SpaceAmp),
+	}
+}
+
+func (r IOResult) String() string {
+	return fmt.Sprintf("page_reads=%d page_writes=%d syncs=%d cache_hits=%d cache_misses=%d read_amp=%.2f write_amp=%.2f space_amp=%.2f",
+		r.PageReads, r.PageWrites, r.Syncs, r.CacheHits, r.CacheMisses, r.ReadAmp, r.WriteAmp, r.SpaceAmp)
+}
+
+// ioMeter measures the I/O of an index from its creation on and relates it
+// to the logical bytes (8 per key plus the value) the test read and wrote.
+type ioMeter struct {
+	idx                  index.Index
+	path                 string // index data path, as passed to IndexDef.NewFunc
+	start                index.IOStats
+	logicalRead, written int64
+}
+
+func newIOMeter(idx index.Index, path string) *ioMeter {
+	return &ioMeter{idx: idx, path: path, start: ioStats(idx)}
+}
+
+func ioStats(idx index.Index) index.IOStats {
+	if r, ok := idx.(index.IOReporter); ok {
+		return r.IOStats()
+	}
+	return index.IOStats{}
+}
+
+// read records an entry returned to the test.
+func (m *ioMeter) read(value []byte) { m.logicalRead += 8 + int64(len(value)) }
+
+// wrote records an insert (value) or a delete (nil value) issued by the test.
+func (m *ioMeter) wrote(value []byte) { m.written += 8 + int64(len(value)) }
+
+// result returns the I/O since the meter was created. liveBytes is the
+// logical size of the entries the index holds now. Disk usage is measured
+// while the index is open; data an LSM holds in its memtables counts as if
+// it had been flushed, so that small datasets do not look free.
+func (m *ioMeter) result(liveBytes int64) IOResult {
+	r := IOResult{IOStats: ioStats(m.idx).Sub(m.start), DiskBytes: indexSize(m.idx, m.path)}
+	r.ReadAmp = ratio(float64(r.BytesRead), float64(m.logicalRead))
+	r.WriteAmp = ratio(float64(r.BytesWritten), float64(m.written))
+	r.SpaceAmp = ratio(float64(r.DiskBytes), float64(liveBytes))
+	return r
+}
+
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+// indexSize returns the disk usage of the open index idx at path, counting
+// the data in an LSM's memtables as if it had been flushed.
+func indexSize(idx index.Index, path string) int64 {
+	size := diskUsage(path)
+	if u, ok := idx.(interface{ UnflushedBytes() int64 }); ok {
+		size += u.UnflushedBytes()
+	}
+	return size
+}
+
+// diskUsage returns the bytes the index at path occupies on disk: the size of
+// its .bt or .bpt file, or of all files in its directory (LSM).
+func diskUsage(path string) int64 {
+	var total int64
+	for _, name := range indexFiles(path) {
+		if info, err := os.Stat(name); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// indexFiles returns the files of the index at path: its .bt or .bpt file,
+// or the files in its directory (LSM).
+func indexFiles(path string) []string {
+	for _, ext := range []string{".bt", ".bpt"} {
+		if _, err := os.Stat(path + ext); err == nil {
+			return []string{path + ext}
+		}
+	}
+	var files []string
+	_ = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files
+}
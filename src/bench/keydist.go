@@ -0,0 +1,183 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Key access distributions understood by NewKeyChooser.
+const (
+	DistUniform          = "uniform"
+	DistZipfian          = "zipfian"
+	DistScrambledZipfian = "scrambled_zipfian"
+	DistLatest           = "latest"
+	DistHotspot          = "hotspot"
+	DistSequential       = "sequential"
+)
+
+// KeyDists lists the names accepted by NewKeyChooser.
+var KeyDists = []string{DistUniform, DistZipfian, DistScrambledZipfian, DistLatest, DistHotspot, DistSequential}
+
+// KeyDist selects a key access distribution and its parameters.
+type KeyDist struct {
+	Name           string
+	ZipfTheta      float64 // skew of the Zipfian variants (YCSB default 0.99)
+	HotSetFraction float64 // hotspot: fraction of keys that are hot
+	HotOpFraction  float64 // hotspot: fraction of operations that hit the hot keys
+}
+
+// DefaultKeyDist returns the distribution name with the YCSB default parameters.
+func DefaultKeyDist(name string) KeyDist {
+	return KeyDist{Name: name, ZipfTheta: 0.99, HotSetFraction: 0.2, HotOpFraction: 0.8}
+}
+
+// KeyChooser picks positions in a key list. Workloads keep their keys in a
+// slice (loaded keys in ascending order, then inserted keys in insertion
+// order) and request keys[Next(len(keys))], so a chooser stays valid while
+// the list grows.
+type KeyChooser interface {
+	// Next returns a position in [0, n).
+	Next(n int) int
+}
+
+// NewKeyChooser returns a chooser for dist whose random choices are derived from seed.
+func NewKeyChooser(dist KeyDist, seed int64) (KeyChooser, error) {
+	rng := rand.New(rand.NewSource(seed))
+	switch dist.Name {
+	case DistZipfian, DistScrambledZipfian, DistLatest:
+		if dist.ZipfTheta <= 0 || dist.ZipfTheta >= 1 {
+			return nil, fmt.Errorf("%s: theta must be in (0, 1), got %g", dist.Name, dist.ZipfTheta)
+		}
+	}
+	switch dist.Name {
+	case DistUniform, "":
+		return uniformChooser{rng}, nil
+	case DistZipfian:
+		return &zipfianChooser{rng: rng, zipf: newZipfian(dist.ZipfTheta)}, nil
+	case DistScrambledZipfian:
+		return &zipfianChooser{rng: rng, zipf: newZipfian(dist.ZipfTheta), scramble: true}, nil
+	case DistLatest:
+		return &latestChooser{rng: rng, zipf: newZipfian(dist.ZipfTheta)}, nil
+	case DistHotspot:
+		if dist.HotSetFraction <= 0 || dist.HotSetFraction > 1 || dist.HotOpFraction < 0 || dist.HotOpFraction > 1 {
+			return nil, fmt.Errorf("hotspot: fractions must be in (0, 1], got set=%g ops=%g",
+				dist.HotSetFraction, dist.HotOpFraction)
+		}
+		return hotspotChooser{rng, dist.HotSetFraction, dist.HotOpFraction}, nil
+	case DistSequential:
+		return &sequentialChooser{}, nil
+	}
+	return nil, fmt.Errorf("unknown key distribution %q (want one of %v)", dist.Name, KeyDists)
+}
+
+// uniformChooser picks every position with the same probability.
+type uniformChooser struct{ rng *rand.Rand }
+
+func (c uniformChooser) Next(n int) int { return c.rng.Intn(n) }
+
+// zipfianChooser makes position 0 the most popular. Since loaded keys are
+// ascending, the hot keys form one range at the low end of the key space;
+// with scramble the popularity ranks are hashed over the whole list instead.
+type zipfianChooser struct {
+	rng      *rand.Rand
+	zipf     *zipfian
+	scramble bool
+}
+
+func (c *zipfianChooser) Next(n int) int {
+	r := c.zipf.next(c.rng, n)
+	if c.scramble {
+		return int(fnv64(uint64(r)) % uint64(n))
+	}
+	return r
+}
+
+// latestChooser makes the most recently inserted key the most popular.
+type latestChooser struct {
+	rng  *rand.Rand
+	zipf *zipfian
+}
+
+func (c *latestChooser) Next(n int) int { return n - 1 - c.zipf.next(c.rng, n) }
+
+// hotspotChooser sends opFraction of the requests uniformly to the first
+// setFraction of the positions and the rest uniformly to the others.
+type hotspotChooser struct {
+	rng                     *rand.Rand
+	setFraction, opFraction float64
+}
+
+func (c hotspotChooser) Next(n int) int {
+	hot := int(float64(n) * c.setFraction)
+	if hot < 1 {
+		hot = 1
+	}
+	if hot == n || c.rng.Float64() < c.opFraction {
+		return c.rng.Intn(hot)
+	}
+	return hot + c.rng.Intn(n-hot)
+}
+
+// sequentialChooser walks the list in order and wraps around.
+type sequentialChooser struct{ pos int }
+
+func (c *sequentialChooser) Next(n int) int {
+	i := c.pos % n
+	c.pos = i + 1
+	return i
+}
+
+// fnv64 is the 64-bit FNV-1a hash of v's bytes, as used by YCSB's
+// scrambled Zipfian generator.
+func fnv64(v uint64) uint64 {
+	h := uint64(0xcbf29ce484222325)
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= 0x100000001b3
+		v >>= 8
+	}
+	return h
+}
+
+// zipfian draws ranks in [0, n) with P(r) proportional to 1/(r+1)^theta,
+// following Gray et al., "Quickly Generating Billion-Record Synthetic
+// Databases" (the algorithm used by YCSB). The normalisation constant is
+// adjusted incrementally when the item count grows or shrinks.
+type zipfian struct {
+	theta, alpha, zeta2 float64
+	n                   int
+	zetaN, eta          float64
+}
+
+func newZipfian(theta float64) *zipfian {
+	return &zipfian{theta: theta, alpha: 1 / (1 - theta), zeta2: 1 + math.Pow(0.5, theta)}
+}
+
+// resize adjusts zetaN to cover n items.
+func (z *zipfian) resize(n int) {
+	for i := z.n; i < n; i++ {
+		z.zetaN += 1 / math.Pow(float64(i+1), z.theta)
+	}
+	for i := z.n; i > n; i-- {
+		z.zetaN -= 1 / math.Pow(float64(i), z.theta)
+	}
+	z.n = n
+	z.eta = (1 - math.Pow(2/float64(n), 1-z.theta)) / (1 - z.zeta2/z.zetaN)
+}
+
+func (z *zipfian) next(rng *rand.Rand, n int) int {
+	if n != z.n {
+		z.resize(n)
+	}
+	u := rng.Float64()
+	uz := u * z.zetaN
+	switch {
+	case uz < 1:
+		return 0
+	case uz < z.zeta2:
+		return 1
+	}
+	// The bounds only guard against rounding in zetaN and at u close to 1.
+	return max(0, min(n-1, int(float64(n)*math.Pow(z.eta*u-z.eta+1, z.alpha))))
+}
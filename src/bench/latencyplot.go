@@ -0,0 +1,231 @@
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// heatmapColors is the color scale of the latency heatmaps, from no
+// operations to the largest share of a window.
+var heatmapColors = []string{"#ffffcc", "#fd8d3c", "#800026"}
+
+// column returns the index of the column called name, or -1.
+func column(header []string, name string) int {
+	for i, col := range header {
+		if col == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// boxItem returns the box of a latency distribution given by its minimum,
+// quartiles and maximum. The whiskers reach 1.5 IQR beyond the box but not
+// past the minimum and maximum.
+func boxItem(minVal, q1, p50, q3, maxVal float64, color string) opts.BoxPlotData {
+	iqr := q3 - q1
+	item := opts.BoxPlotData{Value: []interface{}{max(q1-1.5*iqr, minVal), q1, p50, q3, min(q3+1.5*iqr, maxVal)}}
+	if color != "" {
+		item.ItemStyle = &opts.ItemStyle{Color: color}
+	}
+	return item
+}
+
+// latencyCDFs returns a CDF chart of the latency distribution CSV records
+// (see latencyHeader) per operation type, with one line per index. Cold
+// cache distributions go into the chart of their warm counterpart.
+func latencyCDFs(records [][]string, title string) []*charts.Line {
+	if len(records) < 2 {
+		return nil
+	}
+	var ops []string
+	series := make(map[string][]string) // op -> series in order
+	points := make(map[string]map[string][]opts.LineData)
+	for _, rec := range records[1:] {
+		op, name := strings.TrimSuffix(rec[1], "_"+CacheCold), rec[0]
+		if op != rec[1] {
+			name += " (cold)"
+		}
+		if points[op] == nil {
+			ops = append(ops, op)
+			points[op] = make(map[string][]opts.LineData)
+		}
+		if points[op][name] == nil {
+			series[op] = append(series[op], name)
+		}
+		latency, _ := strconv.ParseFloat(rec[2], 64)
+		cum, _ := strconv.ParseFloat(rec[4], 64)
+		points[op][name] = append(points[op][name], opts.LineData{Value: []interface{}{max(latency, 1), cum}})
+	}
+
+	var out []*charts.Line
+	for _, op := range ops {
+		line := charts.NewLine()
+		line.SetGlobalOptions(
+			charts.WithTitleOpts(opts.Title{Title: fmt.Sprintf("%s — CDF (%s)", title, op)}),
+			charts.WithXAxisOpts(opts.XAxis{Name: "ns", Type: "log"}),
+			charts.WithYAxisOpts(opts.YAxis{Name: "Cumulative fraction", Type: "value", Min: 0, Max: 1}),
+			charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true), Trigger: "axis"}),
+			charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "8%"}),
+			charts.WithInitializationOpts(opts.Initialization{Width: chartWidth, Height: chartHeight}),
+		)
+		counters := make(map[string]int)
+		for _, name := range series[op] {
+			color := pickColor(name, counters)
+			line.AddSeries(name, points[op][name],
+				charts.WithLineChartOpts(opts.LineChart{ShowSymbol: opts.Bool(false)}),
+				charts.WithLineStyleOpts(opts.LineStyle{Color: color, Width: 2}),
+				charts.WithItemStyleOpts(opts.ItemStyle{Color: color}),
+			)
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// mixedBoxPlot returns a box plot of the latencies of each operation type
+// per index from the quartile columns of a mixed workload summary, or nil
+// for summaries written before they existed.
+func mixedBoxPlot(records [][]string, title string) *charts.BoxPlot {
+	header := records[0]
+	cols := []int{column(header, "min_ns"), column(header, "q1_ns"), column(header, "p50_ns"), column(header, "q3_ns"), column(header, "max_ns")}
+	for _, c := range cols {
+		if c < 0 {
+			return nil
+		}
+	}
+	var labels, opOrder []string
+	seen := make(map[string]bool)
+	boxes := make(map[string]map[string][5]float64)
+	for _, rec := range records[1:] {
+		idxName, opType := rec[0], rec[1]
+		if !seen[idxName] {
+			labels = append(labels, idxName)
+			seen[idxName] = true
+		}
+		if boxes[opType] == nil {
+			opOrder = append(opOrder, opType)
+			boxes[opType] = make(map[string][5]float64)
+		}
+		var b [5]float64
+		for i, c := range cols {
+			b[i], _ = strconv.ParseFloat(rec[c], 64)
+		}
+		boxes[opType][idxName] = b
+	}
+
+	box := charts.NewBoxPlot()
+	box.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: title + " — Distribution"}),
+		charts.WithYAxisOpts(opts.YAxis{Name: "ns", Type: "value"}),
+		charts.WithXAxisOpts(opts.XAxis{
+			AxisLabel: &opts.AxisLabel{Show: opts.Bool(true), Interval: "0", Rotate: 30},
+		}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "8%"}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+		charts.WithInitializationOpts(opts.Initialization{Width: chartWidth, Height: chartHeight}),
+	)
+	box.SetXAxis(labels)
+	for _, opType := range opOrder {
+		var items []opts.BoxPlotData
+		for _, name := range labels {
+			b, ok := boxes[opType][name]
+			if !ok {
+				items = append(items, opts.BoxPlotData{Value: []interface{}{}})
+				continue
+			}
+			items = append(items, boxItem(b[0], b[1], b[2], b[3], b[4], ""))
+		}
+		box.AddSeries(strings.ToUpper(opType[:1])+opType[1:], items)
+	}
+	return box
+}
+
+// latencyHeatmaps returns a heatmap per index of a latency over time CSV
+// (see heatmapHeader): the share of each window's operations per latency
+// bucket. All share the buckets and the color scale.
+func latencyHeatmaps(records [][]string, title string) []*charts.HeatMap {
+	if len(records) < 2 {
+		return nil
+	}
+	var indexOrder []string
+	windows := make(map[string][]string) // index -> window starts in order
+	windowPos := make(map[[2]string]int) // index and window start -> position
+	totals := make(map[[2]string]float64)
+	lowSet := make(map[int64]bool)
+	for _, rec := range records[1:] {
+		key := [2]string{rec[0], rec[1]}
+		if _, ok := windows[rec[0]]; !ok {
+			indexOrder = append(indexOrder, rec[0])
+		}
+		if _, ok := windowPos[key]; !ok {
+			windowPos[key] = len(windows[rec[0]])
+			windows[rec[0]] = append(windows[rec[0]], rec[1])
+		}
+		n, _ := strconv.ParseFloat(rec[3], 64)
+		totals[key] += n
+		low, _ := strconv.ParseInt(rec[2], 10, 64)
+		lowSet[low] = true
+	}
+	var lows []int64
+	for low := range lowSet {
+		lows = append(lows, low)
+	}
+	sort.Slice(lows, func(i, j int) bool { return lows[i] < lows[j] })
+	lowPos := make(map[int64]int)
+	var yLabels []string
+	for i, low := range lows {
+		lowPos[low] = i
+		yLabels = append(yLabels, formatNs(low))
+	}
+
+	data := make(map[string][]opts.HeatMapData)
+	var maxShare float64
+	for _, rec := range records[1:] {
+		n, _ := strconv.ParseFloat(rec[3], 64)
+		low, _ := strconv.ParseInt(rec[2], 10, 64)
+		key := [2]string{rec[0], rec[1]}
+		share := 100 * n / totals[key]
+		maxShare = max(maxShare, share)
+		data[rec[0]] = append(data[rec[0]], opts.HeatMapData{Value: [3]interface{}{windowPos[key], lowPos[low], share}})
+	}
+
+	var out []*charts.HeatMap
+	for _, name := range indexOrder {
+		hm := charts.NewHeatMap()
+		hm.SetGlobalOptions(
+			charts.WithTitleOpts(opts.Title{Title: fmt.Sprintf("%s — Heatmap (%s)", title, name)}),
+			charts.WithXAxisOpts(opts.XAxis{Name: "Op Count", Type: "category", Data: windows[name]}),
+			charts.WithYAxisOpts(opts.YAxis{Name: "Response Time", Type: "category", Data: yLabels}),
+			charts.WithVisualMapOpts(opts.VisualMap{
+				Calculable: opts.Bool(true), Min: 0, Max: float32(maxShare),
+				Text: []string{"% of ops", ""}, Right: "0", Top: "center",
+				InRange: &opts.VisualMapInRange{Color: heatmapColors},
+			}),
+			charts.WithGridOpts(opts.Grid{Right: "120"}),
+			charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+			charts.WithInitializationOpts(opts.Initialization{Width: chartWidth, Height: chartHeight}),
+		)
+		hm.AddSeries("% of ops", data[name])
+		out = append(out, hm)
+	}
+	return out
+}
+
+// formatNs formats a latency in ns with the largest fitting unit, e.g. 2µs.
+func formatNs(ns int64) string {
+	switch {
+	case ns >= 1e9:
+		return strconv.FormatFloat(float64(ns)/1e9, 'g', 3, 64) + "s"
+	case ns >= 1e6:
+		return strconv.FormatFloat(float64(ns)/1e6, 'g', 3, 64) + "ms"
+	case ns >= 1e3:
+		return strconv.FormatFloat(float64(ns)/1e3, 'g', 3, 64) + "µs"
+	}
+	return strconv.FormatInt(ns, 10) + "ns"
+}
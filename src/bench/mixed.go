@@ -0,0 +1,475 @@
+package bench
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operation types of a mixed workload, as written to the type and op_type CSV columns.
+const (
+	OpRead   = "read"   // Get of an existing key
+	OpUpdate = "update" // Insert over an existing key
+	OpInsert = "insert" // Insert of a new key
+	OpDelete = "delete" // Delete of an existing key
+	OpScan   = "scan"   // short Range from an existing key
+	OpRMW    = "rmw"    // Get followed by Insert of the same key
+)
+
+// defaultScanLen is the longest scan of a mix that does not set MaxScanLen (YCSB's maxscanlength).
+const defaultScanLen = 100
+
+// OpMix gives the percentage of each operation type in a mixed workload.
+// The percentages add up to 100.
+type OpMix struct {
+	Read, Update, Insert, Delete, Scan, RMW int
+	MaxScanLen                              int // scan lengths are uniform in [1, MaxScanLen]
+}
+
+type opShare struct {
+	op  string
+	pct int
+}
+
+// parts returns the operation types with their percentages, in a fixed order.
+func (m OpMix) parts() []opShare {
+	return []opShare{{OpRead, m.Read}, {OpUpdate, m.Update}, {OpInsert, m.Insert}, {OpDelete, m.Delete}, {OpScan, m.Scan}, {OpRMW, m.RMW}}
+}
+
+// Validate reports whether the percentages are non-negative and add up to 100.
+func (m OpMix) Validate() error {
+	sum := 0
+	for _, p := range m.parts() {
+		if p.pct < 0 {
+			return fmt.Errorf("op mix %s: negative %s percentage", m, p.op)
+		}
+		sum += p.pct
+	}
+	if sum != 100 {
+		return fmt.Errorf("op mix %s: percentages add up to %d, want 100", m, sum)
+	}
+	return nil
+}
+
+// pick maps a uniform draw in [0, 100) to an operation type.
+func (m OpMix) pick(decision int) string {
+	for _, p := range m.parts() {
+		if decision < p.pct {
+			return p.op
+		}
+		decision -= p.pct
+	}
+	return OpRead
+}
+
+func (m OpMix) scanLen() int {
+	if m.MaxScanLen > 0 {
+		return m.MaxScanLen
+	}
+	return defaultScanLen
+}
+
+// String formats the mix as accepted by ParseOpMix, e.g. "read=95,insert=5".
+func (m OpMix) String() string {
+	var parts []string
+	for _, p := range m.parts() {
+		if p.pct != 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", p.op, p.pct))
+		}
+	}
+	if m.Scan != 0 {
+		parts = append(parts, fmt.Sprintf("scanlen=%d", m.scanLen()))
+	}
+	return strings.Join(parts, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler using the ParseOpMix format.
+func (m OpMix) MarshalText() ([]byte, error) { return []byte(m.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler. An empty string yields the zero mix.
+func (m *OpMix) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*m = OpMix{}
+		return nil
+	}
+	mix, err := ParseOpMix(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mix
+	return nil
+}
+
+// Set implements flag.Value.
+func (m *OpMix) Set(s string) error { return m.UnmarshalText([]byte(s)) }
+
+// ParseOpMix parses a comma-separated list of op=percent pairs, e.g.
+// "read=50,update=30,scan=20,scanlen=50". Operations that are not listed get
+// 0%; scanlen sets the longest scan.
+func ParseOpMix(s string) (OpMix, error) {
+	var m OpMix
+	fields := map[string]*int{
+		OpRead: &m.Read, OpUpdate: &m.Update, OpInsert: &m.Insert, OpDelete: &m.Delete,
+		OpScan: &m.Scan, OpRMW: &m.RMW, "scanlen": &m.MaxScanLen,
+	}
+	for _, part := range strings.Split(s, ",") {
+		name, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		dst := fields[strings.ToLower(name)]
+		if !ok || dst == nil {
+			return OpMix{}, fmt.Errorf("op mix %q: bad entry %q (want op=percent)", s, part)
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return OpMix{}, fmt.Errorf("op mix %q: %w", s, err)
+		}
+		*dst = n
+	}
+	return m, m.Validate()
+}
+
+// mixedRun describes one run of the mixed-workload engine.
+type mixedRun struct {
+	label    string // log prefix, e.g. "T4"
+	fileName string // per-operation log; the summary goes to <name>_summary.csv
+	dataName string // suffix of the index data files
+	mix      OpMix
+	dist     KeyDist
+
+	// orderedInserts makes new keys continue upwards from the largest key,
+	// as YCSB does; otherwise they are random non-negative 64-bit keys.
+	orderedInserts bool
+}
+
+// runMixed loads every index with the dataset and issues cfg.MixedOpsTotal
+// operations drawn from run.mix, back to back or, if cfg.TargetRate is set,
+// open-loop at that rate. Every cfg.LogInterval-th response time is
+// logged, and a latency summary per operation type is written for each index.
+func runMixed(indices []IndexDef, cfg Config, run mixedRun) error {
+	if err := run.mix.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", run.label, err)
+	}
+	if _, err := NewKeyChooser(run.dist, cfg.Seed); err != nil {
+		return fmt.Errorf("%s: %w", run.label, err)
+	}
+	sched, err := cfg.newSchedule()
+	if err != nil {
+		return fmt.Errorf("%s: %w", run.label, err)
+	}
+	ds, err := cfg.dataset()
+	if err != nil {
+		return fmt.Errorf("%s: %w", run.label, err)
+	}
+	sortedKeys := ds.SortedKeys()
+
+	// Detailed log file
+	f, err := os.Create(filepath.Join(cfg.OutDir, run.fileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	_ = w.Write([]string{"index", "op_count", "responetime_ns", "type"})
+
+	// Summary file
+	sumFile, err := os.Create(filepath.Join(cfg.OutDir, run.fileName[:len(run.fileName)-len(".csv")]+"_summary.csv"))
+	if err != nil {
+		return err
+	}
+	defer sumFile.Close()
+	sw := csv.NewWriter(sumFile)
+	defer sw.Flush()
+	_ = sw.Write(mixedSummaryHeader)
+
+	// Latency distribution file
+	lf, err := os.Create(filepath.Join(cfg.OutDir, run.fileName[:len(run.fileName)-len(".csv")]+"_latency.csv"))
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+	lw := csv.NewWriter(lf)
+	defer lw.Flush()
+	_ = lw.Write(latencyHeader)
+
+	// Latency over time file
+	hf, err := os.Create(filepath.Join(cfg.OutDir, run.fileName[:len(run.fileName)-len(".csv")]+"_heatmap.csv"))
+	if err != nil {
+		return err
+	}
+	defer hf.Close()
+	hw := csv.NewWriter(hf)
+	defer hw.Flush()
+	_ = hw.Write(heatmapHeader)
+	windowOps := max(1, (cfg.MixedOpsTotal+heatmapWindows-1)/heatmapWindows)
+
+	ts, err := newTimeseries(cfg, run.fileName)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	rec, err := newTraceRecorder(cfg, run.fileName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", run.label, err)
+	}
+	defer rec.Close()
+
+	var space []SpaceResult
+	var shapes []StructureResult
+	for _, def := range indices {
+		fmt.Printf("[%s] %s: Starting %s workload (%s keys)...\n", run.label, def.Name, run.mix, run.dist.Name)
+
+		idxPath := filepath.Join(cfg.DataDir, def.Name+"_"+run.dataName)
+		idx, err := def.NewFunc(idxPath)
+		if err != nil {
+			continue
+		}
+
+		tr := rec.forIndex(def.Name)
+		if err := fillIndex(idx, *ds, ts.sampler(def.Name, PhaseFill, idx), tr); err != nil {
+			fmt.Printf("[%s] %s: fill failed: %v — skipping\n", run.label, def.Name, err)
+			_ = idx.Close()
+			if cfg.CleanupData {
+				cleanupIndexData(idxPath)
+			}
+			continue
+		}
+
+		shape, ok := logIndexShape(run.label, def.Name, idx)
+		if ok {
+			shapes = append(shapes, shape)
+		}
+		space = append(space, measureSpace(run.label, def.Name, PhaseFill, idx, idxPath, ds.Bytes(), shape.Tree))
+		warmKeys, _ := NewKeyChooser(run.dist, cfg.Seed+5)
+		warmUp(run.label, def.Name, idx, sortedKeys, warmKeys, cfg.Warmup)
+
+		// Re-enable sync for the actual benchmark workload.
+		if s, ok := idx.(interface{ SetSyncInterval(int) }); ok {
+			s.SetSyncInterval(500)
+		}
+
+		rng := rand.New(rand.NewSource(cfg.Seed + 2))
+		chooser, _ := NewKeyChooser(run.dist, cfg.Seed+4)
+		keys := &workloadKeys{chooser: chooser}
+		for _, k := range sortedKeys {
+			keys.add(k)
+		}
+		newValue := func() []byte {
+			v := make([]byte, cfg.ValueSize)
+			rng.Read(v)
+			return v
+		}
+		newKey := func() int64 {
+			if run.orderedInserts {
+				return keys.maxKey + 1
+			}
+			return rng.Int63()
+		}
+
+		hists := make(map[string]*Histogram)
+		var opOrder []string
+		var window Histogram
+		meter := newIOMeter(idx, idxPath)
+		samp := ts.sampler(def.Name, PhaseWorkload, idx)
+		startTotal := time.Now()
+		if sched != nil {
+			sched.begin()
+		}
+
+		for i := 0; i < cfg.MixedOpsTotal; i++ {
+			var intended time.Time
+			if sched != nil {
+				intended = sched.wait()
+			}
+			op := run.mix.pick(rng.Intn(100))
+			if keys.len() == 0 && op != OpInsert {
+				op = OpInsert // everything was deleted; nothing else has a key to work on
+			}
+
+			var start time.Time
+			switch op {
+			case OpRead:
+				key := keys.next()
+				tr.record(TraceOp{Op: TraceGet, Key: key})
+				start = time.Now()
+				val, _ := idx.Get(key)
+				meter.read(val)
+
+			case OpUpdate:
+				key, val := keys.next(), newValue()
+				tr.record(TraceOp{Op: TraceInsert, Key: key, ValueSize: len(val)})
+				start = time.Now()
+				_ = idx.Insert(key, val)
+				meter.wrote(val)
+
+			case OpInsert:
+				key := newKey()
+				val := newValue()
+				tr.record(TraceOp{Op: TraceInsert, Key: key, ValueSize: len(val)})
+				start = time.Now()
+				_ = idx.Insert(key, val)
+				keys.add(key)
+				meter.wrote(val)
+
+			case OpDelete:
+				key := keys.remove()
+				tr.record(TraceOp{Op: TraceDelete, Key: key})
+				start = time.Now()
+				_ = idx.Delete(key)
+				meter.wrote(nil)
+
+			case OpScan:
+				key := keys.next()
+				length := 1 + rng.Intn(run.mix.scanLen())
+				tr.record(TraceOp{Op: TraceRange, Key: key, End: math.MaxInt64, Limit: length})
+				start = time.Now()
+				if it, err := idx.Range(key, math.MaxInt64); err == nil {
+					for n := 0; n < length && it.Next(); n++ {
+						meter.read(it.Value())
+					}
+					_ = it.Close()
+				}
+
+			case OpRMW:
+				key, val := keys.next(), newValue()
+				tr.record(TraceOp{Op: TraceGet, Key: key})
+				tr.record(TraceOp{Op: TraceInsert, Key: key, ValueSize: len(val)})
+				start = time.Now()
+				old, _ := idx.Get(key)
+				_ = idx.Insert(key, val)
+				meter.read(old)
+				meter.wrote(val)
+			}
+			responetime := time.Since(start).Nanoseconds()
+			if sched != nil {
+				// Open loop: the client has been waiting since the operation was due.
+				responetime += max(0, start.Sub(intended).Nanoseconds())
+			}
+
+			if _, ok := hists[op]; !ok {
+				hists[op] = &Histogram{}
+				opOrder = append(opOrder, op)
+			}
+			hists[op].Record(responetime)
+			window.Record(responetime)
+			samp.record(responetime)
+			if (i+1)%windowOps == 0 || i+1 == cfg.MixedOpsTotal {
+				writeHeatmap(hw, def.Name, i+1-window.Count(), &window)
+				window = Histogram{}
+			}
+			if i%cfg.LogInterval == 0 {
+				_ = w.Write([]string{def.Name, strconv.Itoa(i), strconv.FormatInt(responetime, 10), op})
+			}
+		}
+		samp.flush()
+		durationTotal := time.Since(startTotal)
+		if rate := float64(cfg.MixedOpsTotal) / durationTotal.Seconds(); sched != nil && rate < 0.95*cfg.TargetRate {
+			fmt.Printf("[%s] %s: reached only %.0f of the target %.0f ops/s; the response times include the growing backlog\n",
+				run.label, def.Name, rate, cfg.TargetRate)
+		}
+		// Value sizes are not tracked per key; estimate the live data from the entry count.
+		avgEntry := ds.Bytes() / int64(max(1, len(ds.Keys)))
+		io := meter.result(int64(keys.len()) * avgEntry)
+		space = append(space, measureSpace(run.label, def.Name, PhaseWorkload, idx, idxPath, int64(keys.len())*avgEntry, nil))
+		_ = idx.Close()
+		fmt.Printf("[%s] %s: %s\n", run.label, def.Name, io)
+
+		// Calculate and Write Summaries
+		var all Histogram
+		for _, op := range opOrder {
+			h := hists[op]
+			all.Merge(h)
+			r := MixedSummaryResult{
+				Index:     def.Name,
+				OpType:    op,
+				Count:     h.Count(),
+				MinNs:     h.Min(),
+				Q1Ns:      h.Percentile(25),
+				P50Ns:     h.Percentile(50),
+				Q3Ns:      h.Percentile(75),
+				MaxNs:     h.Max(),
+				AvgNs:     h.Mean(),
+				P95Ns:     h.Percentile(95),
+				P99Ns:     h.Percentile(99),
+				OpsPerSec: float64(h.Count()) / durationTotal.Seconds(),
+				IO:        io,
+
+				TargetOpsPerSec: cfg.TargetRate,
+			}
+
+			fmt.Printf("[%s] %s %-6s: count=%-6d avg=%-8dns p50=%-8dns p95=%-8dns tput=%-8.0f ops/s\n",
+				run.label, r.Index, r.OpType, r.Count, r.AvgNs, r.P50Ns, r.P95Ns, r.OpsPerSec)
+
+			_ = sw.Write(r.row())
+			writeLatency(lw, r.Index, r.OpType, h)
+		}
+		writeLatency(lw, def.Name, "all", &all)
+
+		if cfg.CleanupData {
+			cleanupIndexData(idxPath)
+		}
+	}
+
+	fmt.Printf("[%s] results written to %s\n", run.label, filepath.Join(cfg.OutDir, run.fileName))
+	if err := writeStructureResults(cfg.OutDir, run.label, shapes); err != nil {
+		return err
+	}
+	return writeSpaceResults(cfg.OutDir, run.label, space)
+}
+
+// workloadKeys is the list of live keys a mixed workload picks from: the
+// loaded keys in ascending order followed by the inserted ones. A deleted key
+// stays in the list as a tombstone, so the others keep their positions, and
+// the chooser draws again when it hits one. Once half the list is
+// tombstones, it is compacted in order.
+type workloadKeys struct {
+	keys    []int64
+	dead    []bool // tombstones, parallel to keys
+	nDead   int
+	maxKey  int64
+	chooser KeyChooser
+}
+
+// len returns the number of live keys.
+func (k *workloadKeys) len() int { return len(k.keys) - k.nDead }
+
+// pick returns the position of a live key chosen by the key distribution.
+func (k *workloadKeys) pick() int {
+	for {
+		if i := k.chooser.Next(len(k.keys)); !k.dead[i] {
+			return i
+		}
+	}
+}
+
+// next returns an existing key chosen by the key distribution.
+func (k *workloadKeys) next() int64 { return k.keys[k.pick()] }
+
+func (k *workloadKeys) add(key int64) {
+	k.keys = append(k.keys, key)
+	k.dead = append(k.dead, false)
+	k.maxKey = max(k.maxKey, key)
+}
+
+// remove drops a key chosen by the key distribution and returns it.
+func (k *workloadKeys) remove() int64 {
+	i := k.pick()
+	key := k.keys[i]
+	k.dead[i] = true
+	k.nDead++
+	if 2*k.nDead > len(k.keys) {
+		live := k.keys[:0]
+		for j, key := range k.keys {
+			if !k.dead[j] {
+				live = append(live, key)
+			}
+		}
+		k.keys, k.dead, k.nDead = live, make([]bool, len(live)), 0
+	}
+	return key
+}
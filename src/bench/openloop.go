@@ -0,0 +1,80 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+// Arrival processes of the open-loop mode (see Config.TargetRate).
+const (
+	ArrivalConstant = "constant" // operations start at fixed intervals
+	ArrivalPoisson  = "poisson"  // exponentially distributed intervals with the same mean
+)
+
+// ArrivalProcesses lists the values of Config.Arrivals.
+var ArrivalProcesses = []string{ArrivalConstant, ArrivalPoisson}
+
+// spinWait is how close to an intended start time the schedule stops
+// sleeping and spins: short sleeps can take a whole timer tick (about 1ms on
+// some systems), longer ones overshoot by up to a few hundred microseconds.
+const spinWait = 2 * time.Millisecond
+
+// schedule issues the operations of an open-loop workload: it knows when
+// each one is meant to start, independent of how long earlier ones took.
+type schedule struct {
+	rng      *rand.Rand
+	interval float64 // mean gap between operations, in ns
+	poisson  bool
+	start    time.Time
+	offset   float64 // intended start of the last operation, in ns after start
+}
+
+// newSchedule returns the schedule of an open-loop workload as configured,
+// or nil for a closed loop (TargetRate 0).
+func (cfg Config) newSchedule() (*schedule, error) {
+	if cfg.TargetRate < 0 {
+		return nil, fmt.Errorf("negative target rate %g", cfg.TargetRate)
+	}
+	if cfg.TargetRate == 0 {
+		return nil, nil
+	}
+	s := &schedule{rng: rand.New(rand.NewSource(cfg.Seed + 6)), interval: 1e9 / cfg.TargetRate}
+	switch cfg.Arrivals {
+	case ArrivalPoisson, "":
+		s.poisson = true
+	case ArrivalConstant:
+	default:
+		return nil, fmt.Errorf("unknown arrival process %q (want one of %v)", cfg.Arrivals, ArrivalProcesses)
+	}
+	return s, nil
+}
+
+// begin starts the schedule with the first operation due now.
+func (s *schedule) begin() {
+	s.start, s.offset = time.Now(), 0
+}
+
+// wait blocks until the next operation is due and returns its intended start
+// time. If the workload has fallen behind, it returns at once with a time in
+// the past: the operation has been waiting in the client's queue since then.
+func (s *schedule) wait() time.Time {
+	intended := s.start.Add(time.Duration(s.offset))
+	if s.poisson {
+		s.offset += s.rng.ExpFloat64() * s.interval
+	} else {
+		s.offset += s.interval
+	}
+	for {
+		d := time.Until(intended)
+		switch {
+		case d <= 0:
+			return intended
+		case d > spinWait:
+			time.Sleep(d - spinWait)
+		default:
+			runtime.Gosched()
+		}
+	}
+}
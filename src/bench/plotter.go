@@ -1,9 +1,9 @@
 package bench
 
 import (
+	"bytes"
 	"encoding/csv"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -18,12 +18,14 @@ var indexColorHex = []string{
 	"#9ecae1", "#4292c6", "#084594", // B-Tree (Blue)
 	"#a1d99b", "#41ab5d", "#00441b", // B+ Tree (Green)
 	"#fc9272", "#ef3b2c", "#67000d", // LSM (Red)
+	"#bdbdbd", "#737373", "#252525", // In-memory baselines (Grey)
 }
 
 var colorBaseIndex = map[string]int{
-	"btree":  0,
-	"bptree": 3,
-	"lsm":    6,
+	"btree":    0,
+	"bptree":   3,
+	"lsm":      6,
+	"baseline": 9,
 }
 
 const (
@@ -42,6 +44,8 @@ func colorKey(name string) string {
 		return "btree"
 	case strings.HasPrefix(n, "lsm"), strings.HasPrefix(n, "pebble"):
 		return "lsm"
+	case strings.HasPrefix(n, "sorted"), strings.HasPrefix(n, "mem"):
+		return "baseline"
 	default:
 		return "btree"
 	}
@@ -57,42 +61,151 @@ func pickColor(name string, counters map[string]int) string {
 	return indexColorHex[idx]
 }
 
+// renderPage writes page to path. If the directory holds a RunInfoFile, a
+// summary of it is shown above the charts. While PlotAll runs, the page is
+// also added to the report, and while PlotAllExport runs, its charts are
+// exported.
 func renderPage(page *components.Page, path, label string) error {
-	out, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("create %s: %w", path, err)
-	}
-	defer out.Close()
-	if err := page.Render(io.Writer(out)); err != nil {
+	var buf bytes.Buffer
+	if err := page.Render(&buf); err != nil {
 		return fmt.Errorf("render %s: %w", path, err)
 	}
+	out := buf.Bytes()
+	if info, err := LoadRunInfo(filepath.Dir(path)); err == nil {
+		out = bytes.Replace(out, []byte("<body>"), []byte("<body>\n"+runInfoHTML(info)), 1)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
 	fmt.Printf("%s plot written to %s\n", label, path)
+	if reporting != nil {
+		reporting.add(page)
+	}
+	if exporting != nil {
+		if err := exportPage(page, exporting); err != nil {
+			return err
+		}
+		fmt.Printf("%s charts exported to %s\n", label, exporting.Dir)
+	}
 	return nil
 }
 
+// errorBarRender draws one item [category, low, high, bar, bars] of an
+// errorBars series: a whisker from low to high over bar number bar of bars
+// grouped bars, or over the category's center if bar is -1. The offsets
+// follow ECharts' default bar layout (20% category gap, 30% bar gap).
+const errorBarRender = `function (params, api) {
+	var cat = api.value(0), bar = api.value(3), bars = api.value(4);
+	var lo = api.coord([cat, api.value(1)]), hi = api.coord([cat, api.value(2)]);
+	var band = api.size([1, 0])[0], x = lo[0], half = band / 8;
+	if (bar >= 0) {
+		var w = 0.8 * band / (bars + 0.3 * (bars - 1));
+		x += -0.4 * band + bar * 1.3 * w + w / 2;
+		half = w / 4;
+	}
+	var style = {stroke: '#333', lineWidth: 1.2};
+	return {type: 'group', children: [
+		{type: 'line', shape: {x1: x - half, y1: hi[1], x2: x + half, y2: hi[1]}, style: style},
+		{type: 'line', shape: {x1: x, y1: hi[1], x2: x, y2: lo[1]}, style: style},
+		{type: 'line', shape: {x1: x - half, y1: lo[1], x2: x + half, y2: lo[1]}, style: style}
+	]};
+}`
+
+// ciColumn returns the index of the confidence interval column that
+// aggregated repetitions add for column col, or -1 if there is none.
+func ciColumn(header []string, col int) int {
+	for i, name := range header {
+		if name == header[col]+ci95Suffix {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseCI returns the confidence interval column ci of rec, or 0 if ci is -1.
+func parseCI(rec []string, ci int) float64 {
+	if ci < 0 || ci >= len(rec) {
+		return 0
+	}
+	v, _ := strconv.ParseFloat(rec[ci], 64)
+	return v
+}
+
+// errorBars returns a series drawing value ± ci for each category, to be
+// overlapped on a chart. It carries the name of the series it belongs to, so
+// that the legend toggles both. bar and bars place it on grouped bars (see
+// errorBarRender); pass -1 and 0 for lines or ungrouped bars.
+func errorBars(name string, values, cis []float64, bar, bars int) *charts.Custom {
+	var items []opts.CustomData
+	for i, v := range values {
+		if i < len(cis) && cis[i] > 0 {
+			items = append(items, opts.CustomData{Value: []interface{}{i, v - cis[i], v + cis[i], bar, bars}})
+		}
+	}
+	c := charts.NewCustom()
+	c.AddSeries(name, items,
+		charts.WithCustomChartOpts(opts.CustomChart{RenderItem: opts.FuncOpts(errorBarRender)}),
+		charts.WithEncodeOpts(opts.Encode{X: 0, Y: []int{1, 2}}),
+	)
+	return c
+}
+
+// hasCI reports whether any of cis is set, i.e. the results aggregate repetitions.
+func hasCI(cis []float64) bool {
+	for _, v := range cis {
+		if v > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// seriesName returns the name of the index in rec, marked if the row was
+// measured with a cold cache (see CacheCold).
+func seriesName(header, rec []string) string {
+	for i, col := range header {
+		if col == "cache" && i < len(rec) && rec[i] == CacheCold {
+			return rec[0] + " (cold)"
+		}
+	}
+	return rec[0]
+}
+
 // --- Plotters ---
 
+type plotSpec struct {
+	file, label string
+	fn          func(string) error
+}
+
+// PlotAll plots every result CSV found in outDir into a page per test and
+// combines them into the ReportFile.
 func PlotAll(outDir string) error {
-	plots := []struct {
-		file, label string
-		fn          func(string) error
-	}{
+	plots := []plotSpec{
 		{"t1_point_query.csv", "T1", PlotT1},
 		{"t2_range_query.csv", "T2", PlotT2},
 		{"t3_write_throughput.csv", "T3", PlotT3},
 		{"t4_read_heavy.csv", "T4", PlotT4},
 		{"t5_write_heavy.csv", "T5", PlotT5},
 	}
+	for _, wl := range YCSBWorkloads {
+		plots = append(plots, plotSpec{wl.FileName(), "YCSB " + wl.Name, func(dir string) error { return PlotYCSB(dir, wl) }})
+	}
+	plots = append(plots, plotSpec{replayFile, "Replay", PlotReplay}, plotSpec{SpaceFile, "Space", PlotSpace})
+	rep := &report{}
+	reporting = rep
+	defer func() { reporting = nil }()
 	for _, p := range plots {
 		if _, err := os.Stat(filepath.Join(outDir, p.file)); os.IsNotExist(err) {
 			fmt.Printf("[Plotter] Skipping %s: %s not found\n", p.label, p.file)
 			continue
 		}
+		rep.sections = append(rep.sections, reportSection{label: p.label, file: p.file})
 		if err := p.fn(outDir); err != nil {
 			return err
 		}
 	}
-	return nil
+	return rep.write(outDir)
 }
 
 func PlotT1(outDir string) error {
@@ -104,7 +217,9 @@ func PlotT1(outDir string) error {
 	var p95Items []opts.BarData
 	var barItems []opts.BarData
 	var boxItems []opts.BoxPlotData
+	var p95s, p95CIs, tputs, tputCIs []float64
 	counters := make(map[string]int)
+	p95CI, tputCI := ciColumn(records[0], 9), ciColumn(records[0], 11)
 
 	for _, rec := range records[1:] {
 		minVal, _ := strconv.ParseFloat(rec[3], 64)
@@ -114,19 +229,11 @@ func PlotT1(outDir string) error {
 		maxVal, _ := strconv.ParseFloat(rec[7], 64)
 		p95, _ := strconv.ParseFloat(rec[9], 64) // p95 is index 9
 		tput, _ := strconv.ParseFloat(rec[11], 64)
-
-		iqr := q3 - q1
-		wMin := q1 - 1.5*iqr
-		if wMin < minVal {
-			wMin = minVal
-		}
-		wMax := q3 + 1.5*iqr
-		if wMax > maxVal {
-			wMax = maxVal
-		}
+		p95s, p95CIs = append(p95s, p95), append(p95CIs, parseCI(rec, p95CI))
+		tputs, tputCIs = append(tputs, tput), append(tputCIs, parseCI(rec, tputCI))
 
 		color := pickColor(rec[0], counters)
-		labels = append(labels, rec[0])
+		labels = append(labels, seriesName(records[0], rec))
 		p95Items = append(p95Items, opts.BarData{
 			Value:     p95,
 			ItemStyle: &opts.ItemStyle{Color: color},
@@ -135,10 +242,7 @@ func PlotT1(outDir string) error {
 			Value:     tput,
 			ItemStyle: &opts.ItemStyle{Color: color},
 		})
-		boxItems = append(boxItems, opts.BoxPlotData{
-			Value:     []interface{}{wMin, q1, p50, q3, wMax},
-			ItemStyle: &opts.ItemStyle{Color: color},
-		})
+		boxItems = append(boxItems, boxItem(minVal, q1, p50, q3, maxVal, color))
 	}
 
 	p95Bar := charts.NewBar()
@@ -151,6 +255,9 @@ func PlotT1(outDir string) error {
 		charts.WithInitializationOpts(opts.Initialization{Width: chartWidth, Height: chartHeight}),
 	)
 	p95Bar.SetXAxis(labels).AddSeries("P95 Response Time", p95Items)
+	if hasCI(p95CIs) {
+		p95Bar.Overlap(errorBars("P95 Response Time", p95s, p95CIs, -1, 0))
+	}
 
 	bar := charts.NewBar()
 	bar.SetGlobalOptions(charts.WithTitleOpts(opts.Title{Title: "T1 — Point Query Throughput"}),
@@ -161,6 +268,9 @@ func PlotT1(outDir string) error {
 		charts.WithInitializationOpts(opts.Initialization{Width: chartWidth, Height: chartHeight}),
 	)
 	bar.SetXAxis(labels).AddSeries("Throughput", barItems)
+	if hasCI(tputCIs) {
+		bar.Overlap(errorBars("Throughput", tputs, tputCIs, -1, 0))
+	}
 
 	box := charts.NewBoxPlot()
 	box.SetGlobalOptions(
@@ -177,6 +287,20 @@ func PlotT1(outDir string) error {
 	page := components.NewPage()
 	page.SetLayout(components.PageFlexLayout)
 	page.AddCharts(box, p95Bar, bar)
+	latency, err := readCSV(filepath.Join(outDir, "t1_point_query_latency.csv"))
+	if err != nil {
+		return err
+	}
+	for _, cdf := range latencyCDFs(latency, "T1 — Point Query Response Time") {
+		page.AddCharts(cdf)
+	}
+	timeseries, err := readCSV(filepath.Join(outDir, timeseriesFile("t1_point_query.csv")))
+	if err != nil {
+		return err
+	}
+	for _, line := range timeseriesCharts(timeseries, "T1") {
+		page.AddCharts(line)
+	}
 	return renderPage(page, filepath.Join(outDir, "t1.html"), "[T1]")
 }
 
@@ -187,16 +311,23 @@ func genericLinePlot(outDir, file, title, yName, xName, outHtml string, isMixed
 	records, _ := csv.NewReader(f).ReadAll()
 
 	byIndex := make(map[string][]opts.LineData)
+	values := make(map[string][]float64)
+	cis := make(map[string][]float64)
 	var indexOrder []string
 	var xLabels []string
 	seen := make(map[string]bool)
 
+	yCol := 2
+	if file == "t2_range_query.csv" {
+		yCol = 3 // T2 uses totalMs at col 3
+	}
+	ciCol := ciColumn(records[0], yCol)
+
 	for _, rec := range records[1:] {
-		idxName, xVal := rec[0], rec[1]
-		yVal, _ := strconv.ParseFloat(rec[2], 64)
-		if file == "t2_range_query.csv" {
-			yVal, _ = strconv.ParseFloat(rec[3], 64) // T2 uses totalMs at col 3
-		}
+		idxName, xVal := seriesName(records[0], rec), rec[1]
+		yVal, _ := strconv.ParseFloat(rec[yCol], 64)
+		values[idxName] = append(values[idxName], yVal)
+		cis[idxName] = append(cis[idxName], parseCI(rec, ciCol))
 
 		if !seen[idxName] {
 			indexOrder = append(indexOrder, idxName)
@@ -229,11 +360,21 @@ func genericLinePlot(outDir, file, title, yName, xName, outHtml string, isMixed
 			charts.WithLineStyleOpts(opts.LineStyle{Color: color, Width: 2}),
 			charts.WithItemStyleOpts(opts.ItemStyle{Color: color}), // Match dots to line
 		)
+		if hasCI(cis[name]) {
+			line.Overlap(errorBars(name, values[name], cis[name], -1, 0))
+		}
 	}
 
 	page := components.NewPage()
 	page.SetLayout(components.PageFlexLayout)
 	page.AddCharts(line)
+	timeseries, err := readCSV(filepath.Join(outDir, timeseriesFile(file)))
+	if err != nil {
+		return err
+	}
+	for _, ts := range timeseriesCharts(timeseries, title[:2]) {
+		page.AddCharts(ts)
+	}
 	return renderPage(page, filepath.Join(outDir, outHtml), "["+title[:2]+"]")
 }
 
@@ -296,27 +437,33 @@ func PlotMixed(outDir, file, title, outHtml string) error {
 	defer sf.Close()
 	sumRecords, _ := csv.NewReader(sf).ReadAll()
 
-	var sumLabels []string
-	var p50Read, p95Read, p50Write, p95Write []opts.BarData
+	// One P50 and one P95 series per operation type, in order of appearance.
+	var sumLabels, opOrder []string
 	sumSeen := make(map[string]bool)
+	p50ByOp := make(map[string]map[string]float64)
+	p95ByOp := make(map[string]map[string]float64)
+	ciByOp := make(map[string]map[string][2]float64) // P50 and P95
+	p50Col, p95Col := column(sumRecords[0], "p50_ns"), column(sumRecords[0], "p95_ns")
+	p50CI, p95CI := ciColumn(sumRecords[0], p50Col), ciColumn(sumRecords[0], p95Col)
 
 	for _, rec := range sumRecords[1:] {
 		idxName, opType := rec[0], rec[1]
-		p50, _ := strconv.ParseFloat(rec[4], 64)
-		p95, _ := strconv.ParseFloat(rec[5], 64)
+		p50, _ := strconv.ParseFloat(rec[p50Col], 64)
+		p95, _ := strconv.ParseFloat(rec[p95Col], 64)
 
 		if !sumSeen[idxName] {
 			sumLabels = append(sumLabels, idxName)
 			sumSeen[idxName] = true
 		}
-
-		if opType == "read" {
-			p50Read = append(p50Read, opts.BarData{Value: p50})
-			p95Read = append(p95Read, opts.BarData{Value: p95})
-		} else {
-			p50Write = append(p50Write, opts.BarData{Value: p50})
-			p95Write = append(p95Write, opts.BarData{Value: p95})
+		if p50ByOp[opType] == nil {
+			opOrder = append(opOrder, opType)
+			p50ByOp[opType] = make(map[string]float64)
+			p95ByOp[opType] = make(map[string]float64)
+			ciByOp[opType] = make(map[string][2]float64)
 		}
+		p50ByOp[opType][idxName] = p50
+		p95ByOp[opType][idxName] = p95
+		ciByOp[opType][idxName] = [2]float64{parseCI(rec, p50CI), parseCI(rec, p95CI)}
 	}
 
 	sumBar := charts.NewBar()
@@ -329,16 +476,55 @@ func PlotMixed(outDir, file, title, outHtml string) error {
 		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "8%"}),
 		charts.WithInitializationOpts(opts.Initialization{Width: chartWidth, Height: chartHeight}),
 	)
-	sumBar.SetXAxis(sumLabels).
-		AddSeries("Read P50", p50Read).
-		AddSeries("Read P95", p95Read).
-		AddSeries("Write P50", p50Write).
-		AddSeries("Write P95", p95Write)
+	sumBar.SetXAxis(sumLabels)
+	for i, opType := range opOrder {
+		var p50Items, p95Items []opts.BarData
+		var p50s, p95s, p50CIs, p95CIs []float64
+		for _, name := range sumLabels {
+			p50Items = append(p50Items, opts.BarData{Value: p50ByOp[opType][name]})
+			p95Items = append(p95Items, opts.BarData{Value: p95ByOp[opType][name]})
+			p50s, p95s = append(p50s, p50ByOp[opType][name]), append(p95s, p95ByOp[opType][name])
+			p50CIs, p95CIs = append(p50CIs, ciByOp[opType][name][0]), append(p95CIs, ciByOp[opType][name][1])
+		}
+		opName := strings.ToUpper(opType[:1]) + opType[1:]
+		sumBar.AddSeries(opName+" P50", p50Items).AddSeries(opName+" P95", p95Items)
+		if hasCI(p50CIs) || hasCI(p95CIs) {
+			sumBar.Overlap(
+				errorBars(opName+" P50", p50s, p50CIs, 2*i, 2*len(opOrder)),
+				errorBars(opName+" P95", p95s, p95CIs, 2*i+1, 2*len(opOrder)),
+			)
+		}
+	}
 
 	page := components.NewPage()
 	page.SetLayout(components.PageFlexLayout)
 	page.AddCharts(sumBar, lineChart)
-	return renderPage(page, filepath.Join(outDir, outHtml), "["+title[:2]+"]")
+	if box := mixedBoxPlot(sumRecords, title); box != nil {
+		page.AddCharts(box)
+	}
+	base := file[:len(file)-len(".csv")]
+	latency, err := readCSV(filepath.Join(outDir, base+"_latency.csv"))
+	if err != nil {
+		return err
+	}
+	for _, cdf := range latencyCDFs(latency, title) {
+		page.AddCharts(cdf)
+	}
+	heatmap, err := readCSV(filepath.Join(outDir, base+"_heatmap.csv"))
+	if err != nil {
+		return err
+	}
+	for _, hm := range latencyHeatmaps(heatmap, title) {
+		page.AddCharts(hm)
+	}
+	timeseries, err := readCSV(filepath.Join(outDir, timeseriesFile(file)))
+	if err != nil {
+		return err
+	}
+	for _, line := range timeseriesCharts(timeseries, strings.SplitN(title, " — ", 2)[0]) {
+		page.AddCharts(line)
+	}
+	return renderPage(page, filepath.Join(outDir, outHtml), "["+strings.SplitN(title, " — ", 2)[0]+"]")
 }
 
 func PlotT4(outDir string) error {
@@ -348,3 +534,100 @@ func PlotT4(outDir string) error {
 func PlotT5(outDir string) error {
 	return PlotMixed(outDir, "t5_write_heavy.csv", "T5 — Write-Heavy (5/95) Response Time", "t5.html")
 }
+
+// PlotReplay renders the response times of the replay test.
+func PlotReplay(outDir string) error {
+	return PlotMixed(outDir, replayFile, "Replay — Trace Response Time", "replay.html")
+}
+
+// PlotYCSB renders the response times of a YCSB core workload.
+func PlotYCSB(outDir string, wl YCSBWorkload) error {
+	file := wl.FileName()
+	title := "YCSB " + wl.Name + " — " + wl.Description + " Response Time"
+	return PlotMixed(outDir, file, title, file[:len(file)-len(".csv")]+".html")
+}
+
+// PlotSpace renders the disk size, space amplification and page fill of
+// every index after each fill and workload recorded in SpaceFile.
+func PlotSpace(outDir string) error {
+	f, err := os.Open(filepath.Join(outDir, SpaceFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return fmt.Errorf("read %s: %w", SpaceFile, err)
+	}
+
+	// One series per test and phase, e.g. "T4 fill", over the indexes in order of appearance.
+	var labels, seriesOrder []string
+	seen := make(map[string]bool)
+	sizes := make(map[string]map[string]float64)
+	amps := make(map[string]map[string]float64)
+	fills := make(map[string]map[string]float64)
+	cis := map[string]map[string]map[string]float64{"size": {}, "amp": {}, "fill": {}}
+	diskCI, fillCI, ampCI := ciColumn(records[0], 4), ciColumn(records[0], 6), ciColumn(records[0], 7)
+	for _, rec := range records[1:] {
+		series, idxName := rec[0]+" "+rec[2], rec[1]
+		disk, _ := strconv.ParseFloat(rec[4], 64)
+		fill, _ := strconv.ParseFloat(rec[6], 64)
+		amp, _ := strconv.ParseFloat(rec[7], 64)
+		if !seen[idxName] {
+			labels = append(labels, idxName)
+			seen[idxName] = true
+		}
+		if sizes[series] == nil {
+			seriesOrder = append(seriesOrder, series)
+			sizes[series] = make(map[string]float64)
+			amps[series] = make(map[string]float64)
+			fills[series] = make(map[string]float64)
+			for _, m := range cis {
+				m[series] = make(map[string]float64)
+			}
+		}
+		sizes[series][idxName] = disk / (1 << 20)
+		amps[series][idxName] = amp
+		fills[series][idxName] = 100 * fill
+		cis["size"][series][idxName] = parseCI(rec, diskCI) / (1 << 20)
+		cis["amp"][series][idxName] = parseCI(rec, ampCI)
+		cis["fill"][series][idxName] = 100 * parseCI(rec, fillCI)
+	}
+
+	newBar := func(title, yName string, values, ci map[string]map[string]float64) *charts.Bar {
+		bar := charts.NewBar()
+		bar.SetGlobalOptions(
+			charts.WithTitleOpts(opts.Title{Title: title}),
+			charts.WithYAxisOpts(opts.YAxis{Name: yName, Type: "value"}),
+			charts.WithXAxisOpts(opts.XAxis{
+				AxisLabel: &opts.AxisLabel{Show: opts.Bool(true), Interval: "0", Rotate: 30},
+			}),
+			charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+			charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "8%"}),
+			charts.WithInitializationOpts(opts.Initialization{Width: chartWidth, Height: chartHeight}),
+		)
+		bar.SetXAxis(labels)
+		for i, series := range seriesOrder {
+			var items []opts.BarData
+			var vs, vcis []float64
+			for _, name := range labels {
+				items = append(items, opts.BarData{Value: values[series][name]})
+				vs, vcis = append(vs, values[series][name]), append(vcis, ci[series][name])
+			}
+			bar.AddSeries(series, items)
+			if hasCI(vcis) {
+				bar.Overlap(errorBars(series, vs, vcis, i, len(seriesOrder)))
+			}
+		}
+		return bar
+	}
+
+	page := components.NewPage()
+	page.SetLayout(components.PageFlexLayout)
+	page.AddCharts(
+		newBar("Space — Size on Disk", "MiB", sizes, cis["size"]),
+		newBar("Space — Space Amplification", "disk / payload", amps, cis["amp"]),
+		newBar("Space — Average Page Fill (B-Tree, B+ Tree)", "%", fills, cis["fill"]),
+	)
+	return renderPage(page, filepath.Join(outDir, "space.html"), "[Space]")
+}
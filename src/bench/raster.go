@@ -0,0 +1,278 @@
+package bench
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// rasterSupersample is how many pixels per output pixel and axis a
+// rasterCanvas draws, averaged down when encoding to smooth the edges.
+const rasterSupersample = 2
+
+// rasterCanvas draws a figure into an RGBA image, for PNG export without a
+// browser or font files. Text uses the built-in 5×7 pixel font glyphs5x7.
+type rasterCanvas struct {
+	img   *image.RGBA
+	scale float64 // image pixels per SVG user unit
+}
+
+func newRasterCanvas() *rasterCanvas {
+	s := figureScale * rasterSupersample
+	return &rasterCanvas{
+		img:   image.NewRGBA(image.Rect(0, 0, figureWidth*s, figureHeight*s)),
+		scale: float64(s),
+	}
+}
+
+// parseColor parses "#rrggbb"; anything else is black.
+func parseColor(s string) color.RGBA {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil || len(s) != 7 {
+		return color.RGBA{A: 255}
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}
+}
+
+// fill sets the pixels in [x0, x1) × [y0, y1), given in user units.
+func (c *rasterCanvas) fill(x0, y0, x1, y1 float64, col color.RGBA) {
+	b := c.img.Bounds()
+	ix0 := max(int(math.Round(min(x0, x1)*c.scale)), b.Min.X)
+	ix1 := min(int(math.Round(max(x0, x1)*c.scale)), b.Max.X)
+	iy0 := max(int(math.Round(min(y0, y1)*c.scale)), b.Min.Y)
+	iy1 := min(int(math.Round(max(y0, y1)*c.scale)), b.Max.Y)
+	for y := iy0; y < iy1; y++ {
+		for x := ix0; x < ix1; x++ {
+			c.img.SetRGBA(x, y, col)
+		}
+	}
+}
+
+func (c *rasterCanvas) rect(x, y, w, h float64, fill string) {
+	c.fill(x, y, x+w, y+h, parseColor(fill))
+}
+
+// line sets every pixel whose center is within width/2 of the segment.
+func (c *rasterCanvas) line(x1, y1, x2, y2 float64, stroke string, width float64) {
+	col := parseColor(stroke)
+	s := c.scale
+	x1, y1, x2, y2 = x1*s, y1*s, x2*s, y2*s
+	hw := max(width*s/2, 0.5)
+	b := c.img.Bounds()
+	dx, dy := x2-x1, y2-y1
+	l2 := dx*dx + dy*dy
+	for y := max(int(min(y1, y2)-hw), b.Min.Y); y <= min(int(max(y1, y2)+hw), b.Max.Y-1); y++ {
+		for x := max(int(min(x1, x2)-hw), b.Min.X); x <= min(int(max(x1, x2)+hw), b.Max.X-1); x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			t := 0.0
+			if l2 > 0 {
+				t = max(0, min(1, ((px-x1)*dx+(py-y1)*dy)/l2))
+			}
+			if math.Hypot(px-(x1+t*dx), py-(y1+t*dy)) <= hw {
+				c.img.SetRGBA(x, y, col)
+			}
+		}
+	}
+}
+
+func (c *rasterCanvas) polyline(pts [][2]float64, stroke string, width float64) {
+	for i := 1; i < len(pts); i++ {
+		c.line(pts[i-1][0], pts[i-1][1], pts[i][0], pts[i][1], stroke, width)
+	}
+}
+
+func (c *rasterCanvas) dot(x, y, r float64, fill string) {
+	c.line(x, y, x, y, fill, 2*r)
+}
+
+func (c *rasterCanvas) text(x, y float64, s string, size float64, anchor string, rotate float64, fill string, bold bool) {
+	col := parseColor(fill)
+	cell := size / 10 // glyph pixel in user units: glyphs are 0.7·size high, 0.6·size apart
+	runes := []rune(s)
+	width := float64(len(runes))*6*cell - cell
+	var u0 float64
+	switch anchor {
+	case "middle":
+		u0 = -width / 2
+	case "end":
+		u0 = -width
+	}
+	sin, cos := math.Sincos(rotate * math.Pi / 180)
+	for i, r := range runes {
+		g := glyph(r)
+		for row := range g {
+			for col5 := 0; col5 < 5; col5++ {
+				if g[row]&(1<<(4-col5)) == 0 {
+					continue
+				}
+				// Center of the glyph pixel relative to (x, y), then rotated.
+				u := u0 + (float64(i*6+col5)+0.5)*cell
+				v := (float64(row) - 3) * cell
+				px, py := x+u*cos+v*sin, y-u*sin+v*cos
+				w := cell / 2
+				if bold {
+					c.fill(px-w, py-w, px+w+cell/2, py+w, col)
+				} else {
+					c.fill(px-w, py-w, px+w, py+w, col)
+				}
+			}
+		}
+	}
+}
+
+// png encodes the image, averaging each rasterSupersample² block of pixels.
+func (c *rasterCanvas) png() ([]byte, error) {
+	const n = rasterSupersample
+	src := c.img
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()/n, b.Dy()/n))
+	for y := 0; y < dst.Bounds().Dy(); y++ {
+		for x := 0; x < dst.Bounds().Dx(); x++ {
+			var r, g, bl, a int
+			for dy := 0; dy < n; dy++ {
+				for dx := 0; dx < n; dx++ {
+					p := src.RGBAAt(x*n+dx, y*n+dy)
+					r, g, bl, a = r+int(p.R), g+int(p.G), bl+int(p.B), a+int(p.A)
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / (n * n)), uint8(g / (n * n)), uint8(bl / (n * n)), uint8(a / (n * n))})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// glyph returns the rows of r in glyphs5x7, bit 4 being the leftmost
+// column. Characters outside printable ASCII are approximated.
+func glyph(r rune) [8]uint8 {
+	switch r {
+	case '—', '–', '−':
+		r = '-'
+	case 'µ':
+		r = 'u'
+	case '±':
+		r = '+'
+	case '·', '•':
+		r = '.'
+	}
+	if r < ' ' || r > '~' {
+		r = '?'
+	}
+	var g [8]uint8
+	for i, row := range strings.Fields(glyphs5x7[r-' ']) {
+		for _, ch := range row {
+			g[i] <<= 1
+			if ch == '#' {
+				g[i] |= 1
+			}
+		}
+	}
+	return g
+}
+
+// glyphs5x7 is a 5×7 pixel font for printable ASCII, starting at ' ', one
+// string of seven rows per character plus an eighth for descenders.
+var glyphs5x7 = [...]string{
+	"..... ..... ..... ..... ..... ..... .....",       // space
+	"..#.. ..#.. ..#.. ..#.. ..#.. ..... ..#..",       // !
+	".#.#. .#.#. ..... ..... ..... ..... .....",       // "
+	".#.#. .#.#. ##### .#.#. ##### .#.#. .#.#.",       // #
+	"..#.. .#### #.#.. .###. ..#.# ####. ..#..",       // $
+	"##... ##..# ...#. ..#.. .#... #..## ...##",       // %
+	".##.. #..#. #.#.. .#... #.#.# #..#. .##.#",       // &
+	"..#.. ..#.. ..... ..... ..... ..... .....",       // '
+	"...#. ..#.. .#... .#... .#... ..#.. ...#.",       // (
+	".#... ..#.. ...#. ...#. ...#. ..#.. .#...",       // )
+	"..... ..#.. #.#.# .###. #.#.# ..#.. .....",       // *
+	"..... ..#.. ..#.. ##### ..#.. ..#.. .....",       // +
+	"..... ..... ..... ..... .##.. ..#.. .#...",       // ,
+	"..... ..... ..... ##### ..... ..... .....",       // -
+	"..... ..... ..... ..... ..... .##.. .##..",       // .
+	"..... ....# ...#. ..#.. .#... #.... .....",       // /
+	".###. #...# #..## #.#.# ##..# #...# .###.",       // 0
+	"..#.. .##.. ..#.. ..#.. ..#.. ..#.. .###.",       // 1
+	".###. #...# ....# ...#. ..#.. .#... #####",       // 2
+	"##### ...#. ..#.. ...#. ....# #...# .###.",       // 3
+	"...#. ..##. .#.#. #..#. ##### ...#. ...#.",       // 4
+	"##### #.... ####. ....# ....# #...# .###.",       // 5
+	"..##. .#... #.... ####. #...# #...# .###.",       // 6
+	"##### ....# ...#. ..#.. .#... .#... .#...",       // 7
+	".###. #...# #...# .###. #...# #...# .###.",       // 8
+	".###. #...# #...# .#### ....# ...#. .##..",       // 9
+	"..... .##.. .##.. ..... .##.. .##.. .....",       // :
+	"..... .##.. .##.. ..... .##.. ..#.. .#...",       // ;
+	"...#. ..#.. .#... #.... .#... ..#.. ...#.",       // <
+	"..... ..... ##### ..... ##### ..... .....",       // =
+	".#... ..#.. ...#. ....# ...#. ..#.. .#...",       // >
+	".###. #...# ....# ...#. ..#.. ..... ..#..",       // ?
+	".###. #...# ....# .##.# #.#.# #.#.# .###.",       // @
+	".###. #...# #...# ##### #...# #...# #...#",       // A
+	"####. #...# #...# ####. #...# #...# ####.",       // B
+	".###. #...# #.... #.... #.... #...# .###.",       // C
+	"###.. #..#. #...# #...# #...# #..#. ###..",       // D
+	"##### #.... #.... ####. #.... #.... #####",       // E
+	"##### #.... #.... ####. #.... #.... #....",       // F
+	".###. #...# #.... #.### #...# #...# .####",       // G
+	"#...# #...# #...# ##### #...# #...# #...#",       // H
+	".###. ..#.. ..#.. ..#.. ..#.. ..#.. .###.",       // I
+	"..### ...#. ...#. ...#. ...#. #..#. .##..",       // J
+	"#...# #..#. #.#.. ##... #.#.. #..#. #...#",       // K
+	"#.... #.... #.... #.... #.... #.... #####",       // L
+	"#...# ##.## #.#.# #.#.# #...# #...# #...#",       // M
+	"#...# #...# ##..# #.#.# #..## #...# #...#",       // N
+	".###. #...# #...# #...# #...# #...# .###.",       // O
+	"####. #...# #...# ####. #.... #.... #....",       // P
+	".###. #...# #...# #...# #.#.# #..#. .##.#",       // Q
+	"####. #...# #...# ####. #.#.. #..#. #...#",       // R
+	".#### #.... #.... .###. ....# ....# ####.",       // S
+	"##### ..#.. ..#.. ..#.. ..#.. ..#.. ..#..",       // T
+	"#...# #...# #...# #...# #...# #...# .###.",       // U
+	"#...# #...# #...# #...# #...# .#.#. ..#..",       // V
+	"#...# #...# #...# #.#.# #.#.# #.#.# .#.#.",       // W
+	"#...# #...# .#.#. ..#.. .#.#. #...# #...#",       // X
+	"#...# #...# .#.#. ..#.. ..#.. ..#.. ..#..",       // Y
+	"##### ....# ...#. ..#.. .#... #.... #####",       // Z
+	".###. .#... .#... .#... .#... .#... .###.",       // [
+	"..... #.... .#... ..#.. ...#. ....# .....",       // \
+	".###. ...#. ...#. ...#. ...#. ...#. .###.",       // ]
+	"..#.. .#.#. #...# ..... ..... ..... .....",       // ^
+	"..... ..... ..... ..... ..... ..... #####",       // _
+	".#... ..#.. ..... ..... ..... ..... .....",       // `
+	"..... ..... .###. ....# .#### #...# .####",       // a
+	"#.... #.... #.##. ##..# #...# #...# ####.",       // b
+	"..... ..... .###. #.... #.... #...# .###.",       // c
+	"....# ....# .##.# #..## #...# #...# .####",       // d
+	"..... ..... .###. #...# ##### #.... .###.",       // e
+	"..##. .#..# .#... ###.. .#... .#... .#...",       // f
+	"..... ..... .#### #...# #...# .#### ....# .###.", // g
+	"#.... #.... #.##. ##..# #...# #...# #...#",       // h
+	"..#.. ..... .##.. ..#.. ..#.. ..#.. .###.",       // i
+	"...#. ..... ..##. ...#. ...#. ...#. #..#. .##..", // j
+	"#.... #.... #..#. #.#.. ##... #.#.. #..#.",       // k
+	".##.. ..#.. ..#.. ..#.. ..#.. ..#.. .###.",       // l
+	"..... ..... ##.#. #.#.# #.#.# #...# #...#",       // m
+	"..... ..... #.##. ##..# #...# #...# #...#",       // n
+	"..... ..... .###. #...# #...# #...# .###.",       // o
+	"..... ..... ####. #...# #...# ####. #.... #....", // p
+	"..... ..... .#### #...# #...# .#### ....# ....#", // q
+	"..... ..... #.##. ##..# #.... #.... #....",       // r
+	"..... ..... .###. #.... .###. ....# ####.",       // s
+	".#... .#... ###.. .#... .#... .#..# ..##.",       // t
+	"..... ..... #...# #...# #...# #..## .##.#",       // u
+	"..... ..... #...# #...# #...# .#.#. ..#..",       // v
+	"..... ..... #...# #...# #.#.# #.#.# .#.#.",       // w
+	"..... ..... #...# .#.#. ..#.. .#.#. #...#",       // x
+	"..... ..... #...# #...# #...# .#### ....# .###.", // y
+	"..... ..... ##### ...#. ..#.. .#... #####",       // z
+	"...#. ..#.. ..#.. .#... ..#.. ..#.. ...#.",       // {
+	"..#.. ..#.. ..#.. ..#.. ..#.. ..#.. ..#..",       // |
+	".#... ..#.. ..#.. ...#. ..#.. ..#.. .#...",       // }
+	"..... ..... .#... #.#.# ...#. ..... .....",       // ~
+}
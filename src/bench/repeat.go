@@ -0,0 +1,285 @@
+package bench
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RepetitionDir returns the subdirectory of dir holding repetition r (from 1)
+// of a test run with Config.Repetitions > 1.
+func RepetitionDir(dir string, r int) string {
+	return filepath.Join(dir, fmt.Sprintf("rep%d", r))
+}
+
+// keyColumns are the result columns that identify a row rather than measure
+// something; rows of different repetitions with equal keys are aggregated.
+var keyColumns = map[string]bool{
+	"index": true, "test": true, "phase": true, "type": true, "op_type": true,
+	"n_dataset": true, "n_queries": true, "range_size": true, "keys_read": true,
+	"op_count": true, "cumulative_ops": true, "count": true, "cache": true,
+	"target_ops_per_sec": true,
+}
+
+// Suffixes of the columns aggregateCSV adds for every measured column.
+const (
+	stdSuffix  = "_std"  // sample standard deviation
+	ci95Suffix = "_ci95" // half-width of the 95% confidence interval of the mean
+)
+
+// aggregateRepetitions combines the result CSVs of repetitions 1 to n in
+// outDir into CSVs of the same name in outDir. Latency distributions and
+// heatmaps are merged; every other file keeps its columns, with measured values replaced
+// by their mean, and gets a standard deviation and confidence interval column
+// per measured column appended. The StructureFile of the first repetition
+// is copied.
+func aggregateRepetitions(outDir string, n int) error {
+	files, err := filepath.Glob(filepath.Join(RepetitionDir(outDir, 1), "*.csv"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		reps := make([][][]string, 0, n)
+		for r := 1; r <= n; r++ {
+			records, err := readCSV(filepath.Join(RepetitionDir(outDir, r), name))
+			if err != nil {
+				return err
+			}
+			if len(records) > 0 {
+				reps = append(reps, records)
+			}
+		}
+		if len(reps) == 0 {
+			continue
+		}
+		var out [][]string
+		switch {
+		case strings.HasSuffix(name, "_latency.csv"):
+			out = mergeLatency(reps)
+		case strings.HasSuffix(name, "_heatmap.csv"):
+			out = mergeHeatmap(reps)
+		default:
+			out = aggregateCSV(reps)
+		}
+		if err := writeCSV(filepath.Join(outDir, name), out); err != nil {
+			return err
+		}
+	}
+	// Every repetition fills the indexes with the same dataset.
+	if data, err := os.ReadFile(filepath.Join(RepetitionDir(outDir, 1), StructureFile)); err == nil {
+		if err := os.WriteFile(filepath.Join(outDir, StructureFile), data, 0644); err != nil {
+			return fmt.Errorf("write %s: %w", StructureFile, err)
+		}
+	}
+	return nil
+}
+
+func readCSV(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return records, nil
+}
+
+func writeCSV(path string, records [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create csv: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	_ = w.WriteAll(records)
+	return w.Error()
+}
+
+// aggregateCSV combines the records (header first) of several repetitions.
+// Rows are matched by their key columns and, for repeated keys, by order.
+func aggregateCSV(reps [][][]string) [][]string {
+	header := reps[0][0]
+	var measured []int
+	for i, col := range header {
+		if !keyColumns[col] {
+			measured = append(measured, i)
+		}
+	}
+	rowKey := func(rec []string) string {
+		var parts []string
+		for i, col := range header {
+			if keyColumns[col] && i < len(rec) {
+				parts = append(parts, rec[i])
+			}
+		}
+		return strings.Join(parts, "\x00")
+	}
+
+	// rows[key][k] are the k-th rows with that key in each repetition.
+	rows := make(map[string][][][]string)
+	for _, records := range reps {
+		seen := make(map[string]int)
+		for _, rec := range records[1:] {
+			key := rowKey(rec)
+			k := seen[key]
+			seen[key]++
+			if k == len(rows[key]) {
+				rows[key] = append(rows[key], nil)
+			}
+			rows[key][k] = append(rows[key][k], rec)
+		}
+	}
+
+	outHeader := append([]string{}, header...)
+	for _, i := range measured {
+		outHeader = append(outHeader, header[i]+stdSuffix, header[i]+ci95Suffix)
+	}
+	out := [][]string{outHeader}
+	seen := make(map[string]int)
+	for _, rec := range reps[0][1:] {
+		key := rowKey(rec)
+		group := rows[key][seen[key]]
+		seen[key]++
+
+		row := append([]string{}, rec...)
+		var extra []string
+		for _, i := range measured {
+			var samples []float64
+			for _, r := range group {
+				if i < len(r) {
+					if v, err := strconv.ParseFloat(r[i], 64); err == nil {
+						samples = append(samples, v)
+					}
+				}
+			}
+			if len(samples) == 0 {
+				extra = append(extra, "", "") // not a number; keep the first repetition's value
+				continue
+			}
+			mean, std, ci := meanCI(samples)
+			row[i] = formatStat(mean)
+			extra = append(extra, formatStat(std), formatStat(ci))
+		}
+		out = append(out, append(row, extra...))
+	}
+	return out
+}
+
+// meanCI returns the mean, the sample standard deviation and the half-width
+// of the 95% confidence interval of the mean (Student's t) of samples.
+func meanCI(samples []float64) (mean, std, ci float64) {
+	n := float64(len(samples))
+	for _, v := range samples {
+		mean += v
+	}
+	mean /= n
+	if len(samples) < 2 {
+		return mean, 0, 0
+	}
+	var ss float64
+	for _, v := range samples {
+		ss += (v - mean) * (v - mean)
+	}
+	std = math.Sqrt(ss / (n - 1))
+	return mean, std, tQuantile975(len(samples)-1) * std / math.Sqrt(n)
+}
+
+// tQuantile975 returns the 97.5% quantile of Student's t distribution with
+// df degrees of freedom.
+func tQuantile975(df int) float64 {
+	table := []float64{
+		12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+		2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+		2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+	}
+	switch {
+	case df < 1:
+		return 0
+	case df <= len(table):
+		return table[df-1]
+	case df <= 60:
+		return 2.000
+	case df <= 120:
+		return 1.980
+	}
+	return 1.960
+}
+
+func formatStat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+// mergeLatency merges the latency distributions (see latencyHeader) of
+// several repetitions by adding up the counts per latency.
+func mergeLatency(reps [][][]string) [][]string {
+	type group struct{ index, op string }
+	var order []group
+	counts := make(map[group]map[int64]uint64)
+	for _, records := range reps {
+		for _, rec := range records[1:] {
+			g := group{rec[0], rec[1]}
+			if counts[g] == nil {
+				order = append(order, g)
+				counts[g] = make(map[int64]uint64)
+			}
+			v, _ := strconv.ParseInt(rec[2], 10, 64)
+			c, _ := strconv.ParseUint(rec[3], 10, 64)
+			counts[g][v] += c
+		}
+	}
+
+	out := [][]string{latencyHeader}
+	for _, g := range order {
+		var values []int64
+		var total, seen uint64
+		for v, c := range counts[g] {
+			values = append(values, v)
+			total += c
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		for _, v := range values {
+			c := counts[g][v]
+			seen += c
+			out = append(out, []string{
+				g.index, g.op, strconv.FormatInt(v, 10),
+				strconv.FormatUint(c, 10), strconv.FormatFloat(float64(seen)/float64(total), 'f', 6, 64),
+			})
+		}
+	}
+	return out
+}
+
+// mergeHeatmap merges the latency over time CSVs (see heatmapHeader) of
+// several repetitions by adding up the counts per index, window and bucket.
+func mergeHeatmap(reps [][][]string) [][]string {
+	type cell struct{ index, window, latency string }
+	var order []cell
+	counts := make(map[cell]uint64)
+	for _, records := range reps {
+		for _, rec := range records[1:] {
+			c := cell{rec[0], rec[1], rec[2]}
+			if _, ok := counts[c]; !ok {
+				order = append(order, c)
+			}
+			n, _ := strconv.ParseUint(rec[3], 10, 64)
+			counts[c] += n
+		}
+	}
+	out := [][]string{heatmapHeader}
+	for _, c := range order {
+		out = append(out, []string{c.index, c.window, c.latency, strconv.FormatUint(counts[c], 10)})
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package bench
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregateCSV(t *testing.T) {
+	header := []string{"index", "op_type", "count", "p50_ns", "ops_per_sec", "note"}
+	// The repetitions list their rows in different orders; scan appears
+	// twice per repetition, like the windows of T3, and is matched by order.
+	reps := [][][]string{
+		{header,
+			{"a", "read", "10", "100", "1000", "x"},
+			{"a", "insert", "5", "200", "50", "x"},
+			{"b", "read", "10", "300", "900", "y"},
+			{"c", "scan", "1", "10", "20", "z"},
+			{"c", "scan", "1", "20", "20", "z"},
+		},
+		{header,
+			{"b", "read", "10", "330", "1100", "y"},
+			{"c", "scan", "1", "14", "20", "z"},
+			{"a", "read", "10", "110", "1200", "x"},
+			{"c", "scan", "1", "20", "20", "z"},
+			{"a", "insert", "5", "260", "70", "x"},
+		},
+		{header,
+			{"a", "read", "10", "120", "800", "x"},
+			{"a", "insert", "5", "230", "60", "x"},
+			{"c", "scan", "1", "12", "20", "z"},
+			{"b", "read", "10", "360", "1000", "y"},
+			{"c", "scan", "1", "20", "20", "z"},
+		},
+	}
+	// Means, then _std and _ci95 (t = 4.303 for 2 degrees of freedom) of
+	// every column that is not a key like count; note is not a number and
+	// keeps the value of the first repetition.
+	want := [][]string{
+		{"index", "op_type", "count", "p50_ns", "ops_per_sec", "note",
+			"p50_ns_std", "p50_ns_ci95", "ops_per_sec_std", "ops_per_sec_ci95", "note_std", "note_ci95"},
+		{"a", "read", "10", "110", "1000", "x", "10", "24.843", "200", "496.868", "", ""},
+		{"a", "insert", "5", "230", "60", "x", "30", "74.53", "10", "24.843", "", ""},
+		{"b", "read", "10", "330", "1000", "y", "30", "74.53", "100", "248.434", "", ""},
+		{"c", "scan", "1", "12", "20", "z", "2", "4.969", "0", "0", "", ""},
+		{"c", "scan", "1", "20", "20", "z", "0", "0", "0", "0", "", ""},
+	}
+	got := aggregateCSV(reps)
+	if len(got) != len(want) {
+		t.Fatalf("aggregateCSV returned %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("row %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMeanCI(t *testing.T) {
+	tests := []struct {
+		samples       []float64
+		mean, std, ci string
+	}{
+		{[]float64{7}, "7", "0", "0"},
+		{[]float64{1, 3}, "2", "1.414", "12.706"},
+		{[]float64{1, 2, 3, 4, 5}, "3", "1.581", "1.963"},
+	}
+	for _, tc := range tests {
+		mean, std, ci := meanCI(tc.samples)
+		if formatStat(mean) != tc.mean || formatStat(std) != tc.std || formatStat(ci) != tc.ci {
+			t.Errorf("meanCI(%v) = %s, %s, %s; want %s, %s, %s", tc.samples,
+				formatStat(mean), formatStat(std), formatStat(ci), tc.mean, tc.std, tc.ci)
+		}
+	}
+}
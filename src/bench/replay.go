@@ -0,0 +1,313 @@
+package bench
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+)
+
+// replayFile is the per-operation log of the replay test; the summary,
+// latency distributions and heatmap go next to it as for the mixed workloads.
+const replayFile = "replay.csv"
+
+// traceLive returns the logical bytes (keys and values) in an index after
+// the load operations of ops and after all of them.
+func traceLive(ops []TraceOp) (loaded, final int64) {
+	sizes := make(map[int64]int64)
+	var live int64
+	for i, op := range ops {
+		if i > 0 && ops[i-1].Load && !op.Load {
+			loaded = live
+		}
+		switch op.Op {
+		case TraceInsert:
+			live += int64(op.ValueSize) - sizes[op.Key]
+			if _, ok := sizes[op.Key]; !ok {
+				live += 8
+			}
+			sizes[op.Key] = int64(op.ValueSize)
+		case TraceDelete:
+			if size, ok := sizes[op.Key]; ok {
+				live -= 8 + size
+				delete(sizes, op.Key)
+			}
+		}
+	}
+	if len(ops) > 0 && ops[len(ops)-1].Load {
+		loaded = live
+	}
+	return loaded, live
+}
+
+// RunBenchmarkReplay executes the trace cfg.Trace (see TraceOp) against
+// every index: the load operations to fill it, then the others measured like
+// a mixed workload, with a latency summary per trace operation.
+func RunBenchmarkReplay(indices []IndexDef, cfg Config) error {
+	if cfg.Trace == "" {
+		return errors.New("Replay: no trace file given (see --trace)")
+	}
+	ops, err := ReadTrace(cfg.Trace)
+	if err != nil {
+		return fmt.Errorf("Replay: %w", err)
+	}
+	sched, err := cfg.newSchedule()
+	if err != nil {
+		return fmt.Errorf("Replay: %w", err)
+	}
+	var loadKeys []int64
+	measured := 0
+	for _, op := range ops {
+		switch {
+		case op.Load && op.Op == TraceInsert:
+			loadKeys = append(loadKeys, op.Key)
+		case !op.Load:
+			measured++
+		}
+	}
+	sort.Slice(loadKeys, func(i, j int) bool { return loadKeys[i] < loadKeys[j] })
+	loadedBytes, liveBytes := traceLive(ops)
+	fmt.Printf("[Replay] %s: %d load and %d measured operations\n", cfg.Trace, len(ops)-measured, measured)
+
+	base := replayFile[:len(replayFile)-len(".csv")]
+	f, err := os.Create(filepath.Join(cfg.OutDir, replayFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	_ = w.Write([]string{"index", "op_count", "responetime_ns", "type"})
+
+	sumFile, err := os.Create(filepath.Join(cfg.OutDir, base+"_summary.csv"))
+	if err != nil {
+		return err
+	}
+	defer sumFile.Close()
+	sw := csv.NewWriter(sumFile)
+	defer sw.Flush()
+	_ = sw.Write(mixedSummaryHeader)
+
+	lf, err := os.Create(filepath.Join(cfg.OutDir, base+"_latency.csv"))
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+	lw := csv.NewWriter(lf)
+	defer lw.Flush()
+	_ = lw.Write(latencyHeader)
+
+	hf, err := os.Create(filepath.Join(cfg.OutDir, base+"_heatmap.csv"))
+	if err != nil {
+		return err
+	}
+	defer hf.Close()
+	hw := csv.NewWriter(hf)
+	defer hw.Flush()
+	_ = hw.Write(heatmapHeader)
+	windowOps := max(1, (measured+heatmapWindows-1)/heatmapWindows)
+
+	ts, err := newTimeseries(cfg, replayFile)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+
+	var space []SpaceResult
+	var shapes []StructureResult
+	for _, def := range indices {
+		fmt.Printf("[Replay] %s: loading %d keys...\n", def.Name, len(loadKeys))
+
+		idxPath := filepath.Join(cfg.DataDir, def.Name+"_replay")
+		idx, err := def.NewFunc(idxPath)
+		if err != nil {
+			fmt.Printf("[Replay] %s: open failed: %v — skipping\n", def.Name, err)
+			continue
+		}
+
+		// Every index gets the same values.
+		rng := rand.New(rand.NewSource(cfg.Seed + 7))
+		newValue := func(size int) []byte {
+			v := make([]byte, size)
+			rng.Read(v)
+			return v
+		}
+
+		if s, ok := idx.(interface{ SetSyncInterval(int) }); ok {
+			s.SetSyncInterval(0)
+		}
+		samp := ts.sampler(def.Name, PhaseFill, idx)
+		var loadErr error
+		for _, op := range ops {
+			if !op.Load {
+				continue
+			}
+			t := time.Now()
+			if loadErr = replayOp(idx, op, newValue, nil); loadErr != nil {
+				break
+			}
+			samp.record(time.Since(t).Nanoseconds())
+		}
+		samp.flush()
+		if loadErr != nil {
+			fmt.Printf("[Replay] %s: load failed: %v — skipping\n", def.Name, loadErr)
+			_ = idx.Close()
+			if cfg.CleanupData {
+				cleanupIndexData(idxPath)
+			}
+			continue
+		}
+
+		shape, ok := logIndexShape("Replay", def.Name, idx)
+		if ok {
+			shapes = append(shapes, shape)
+		}
+		space = append(space, measureSpace("Replay", def.Name, PhaseFill, idx, idxPath, loadedBytes, shape.Tree))
+		warmKeys, _ := NewKeyChooser(DefaultKeyDist(DistUniform), cfg.Seed+5)
+		warmUp("Replay", def.Name, idx, loadKeys, warmKeys, cfg.Warmup)
+
+		if s, ok := idx.(interface{ SetSyncInterval(int) }); ok {
+			s.SetSyncInterval(500)
+		}
+
+		hists := make(map[string]*Histogram)
+		var opOrder []string
+		var window Histogram
+		meter := newIOMeter(idx, idxPath)
+		samp = ts.sampler(def.Name, PhaseWorkload, idx)
+		startTotal := time.Now()
+		if sched != nil {
+			sched.begin()
+		}
+
+		i := 0
+		for _, op := range ops {
+			if op.Load {
+				continue
+			}
+			var intended time.Time
+			if sched != nil {
+				intended = sched.wait()
+			}
+			start := time.Now()
+			_ = replayOp(idx, op, newValue, meter)
+			responetime := time.Since(start).Nanoseconds()
+			if sched != nil {
+				responetime += max(0, start.Sub(intended).Nanoseconds())
+			}
+
+			if _, ok := hists[op.Op]; !ok {
+				hists[op.Op] = &Histogram{}
+				opOrder = append(opOrder, op.Op)
+			}
+			hists[op.Op].Record(responetime)
+			window.Record(responetime)
+			samp.record(responetime)
+			i++
+			if i%windowOps == 0 || i == measured {
+				writeHeatmap(hw, def.Name, i-window.Count(), &window)
+				window = Histogram{}
+			}
+			if (i-1)%cfg.LogInterval == 0 {
+				_ = w.Write([]string{def.Name, strconv.Itoa(i - 1), strconv.FormatInt(responetime, 10), op.Op})
+			}
+		}
+		samp.flush()
+		durationTotal := time.Since(startTotal)
+		if rate := float64(measured) / durationTotal.Seconds(); sched != nil && rate < 0.95*cfg.TargetRate {
+			fmt.Printf("[Replay] %s: reached only %.0f of the target %.0f ops/s; the response times include the growing backlog\n",
+				def.Name, rate, cfg.TargetRate)
+		}
+		io := meter.result(liveBytes)
+		space = append(space, measureSpace("Replay", def.Name, PhaseWorkload, idx, idxPath, liveBytes, nil))
+		_ = idx.Close()
+		fmt.Printf("[Replay] %s: %s\n", def.Name, io)
+
+		var all Histogram
+		for _, op := range opOrder {
+			h := hists[op]
+			all.Merge(h)
+			r := MixedSummaryResult{
+				Index:     def.Name,
+				OpType:    op,
+				Count:     h.Count(),
+				MinNs:     h.Min(),
+				Q1Ns:      h.Percentile(25),
+				P50Ns:     h.Percentile(50),
+				Q3Ns:      h.Percentile(75),
+				MaxNs:     h.Max(),
+				AvgNs:     h.Mean(),
+				P95Ns:     h.Percentile(95),
+				P99Ns:     h.Percentile(99),
+				OpsPerSec: float64(h.Count()) / durationTotal.Seconds(),
+				IO:        io,
+
+				TargetOpsPerSec: cfg.TargetRate,
+			}
+			fmt.Printf("[Replay] %s %-6s: count=%-6d avg=%-8dns p50=%-8dns p95=%-8dns tput=%-8.0f ops/s\n",
+				r.Index, r.OpType, r.Count, r.AvgNs, r.P50Ns, r.P95Ns, r.OpsPerSec)
+			_ = sw.Write(r.row())
+			writeLatency(lw, r.Index, r.OpType, h)
+		}
+		writeLatency(lw, def.Name, "all", &all)
+
+		if cfg.CleanupData {
+			cleanupIndexData(idxPath)
+		}
+	}
+
+	fmt.Printf("[Replay] results written to %s\n", filepath.Join(cfg.OutDir, replayFile))
+	if err := writeStructureResults(cfg.OutDir, "Replay", shapes); err != nil {
+		return err
+	}
+	return writeSpaceResults(cfg.OutDir, "Replay", space)
+}
+
+// replayOp executes op on idx, accounting its keys and values with meter
+// unless that is nil. Range reads op.Limit entries, or all up to op.End.
+func replayOp(idx index.Index, op TraceOp, newValue func(int) []byte, meter *ioMeter) error {
+	switch op.Op {
+	case TraceGet:
+		val, err := idx.Get(op.Key)
+		if meter != nil {
+			meter.read(val)
+		}
+		return err
+	case TraceInsert:
+		val := newValue(op.ValueSize)
+		if err := idx.Insert(op.Key, val); err != nil {
+			return fmt.Errorf("insert key %d: %w", op.Key, err)
+		}
+		if meter != nil {
+			meter.wrote(val)
+		}
+	case TraceDelete:
+		if err := idx.Delete(op.Key); err != nil {
+			return err
+		}
+		if meter != nil {
+			meter.wrote(nil)
+		}
+	case TraceRange:
+		it, err := idx.Range(op.Key, op.End)
+		if err != nil {
+			return err
+		}
+		for n := 0; (op.Limit == 0 || n < op.Limit) && it.Next(); n++ {
+			if meter != nil {
+				meter.read(it.Value())
+			}
+		}
+		err = it.Error()
+		_ = it.Close()
+		return err
+	}
+	return nil
+}
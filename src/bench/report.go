@@ -0,0 +1,330 @@
+package bench
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-echarts/go-echarts/v2/components"
+)
+
+// ReportFile is the single page PlotAll writes besides the page per test:
+// run info, a scorecard, the summary tables and charts of every test and the
+// index structure, with the charts as inline SVG so that it needs nothing
+// else to be viewed.
+const ReportFile = "report.html"
+
+// report collects the pages PlotAll renders, one section per test.
+type report struct {
+	sections []reportSection
+}
+
+type reportSection struct {
+	label string // e.g. "T1" or "YCSB A"
+	file  string // the result CSV the test is plotted from
+	pages []*components.Page
+}
+
+// reporting is set while PlotAll runs; renderPage then adds each page to the
+// current section.
+var reporting *report
+
+func (r *report) add(page *components.Page) {
+	if len(r.sections) > 0 {
+		s := &r.sections[len(r.sections)-1]
+		s.pages = append(s.pages, page)
+	}
+}
+
+// scoreMetric is a column of the scorecard: the mean (or sum) of a result
+// column over the warm rows of each index.
+type scoreMetric struct {
+	heading, file, column string
+	higherBetter          bool
+	sum                   bool // add up the rows of an index, e.g. the ops/s per op type
+}
+
+var scoreMetrics = []scoreMetric{
+	{heading: "T1 P50 (ns)", file: "t1_point_query.csv", column: "p50_ns"},
+	{heading: "T1 P99 (ns)", file: "t1_point_query.csv", column: "p99_ns"},
+	{heading: "T1 Ops/s", file: "t1_point_query.csv", column: "ops_per_sec", higherBetter: true},
+	{heading: "T2 Ops/s", file: "t2_range_query.csv", column: "ops_per_sec", higherBetter: true},
+	{heading: "T3 Ops/s", file: "t3_write_throughput.csv", column: "ops_per_sec", higherBetter: true},
+	{heading: "T3 Write Amp.", file: "t3_write_throughput.csv", column: "write_amp"},
+}
+
+// write writes the ReportFile to outDir.
+func (r *report) write(outDir string) error {
+	var nav, body strings.Builder
+	section := func(id, title string) {
+		fmt.Fprintf(&nav, `<a href="#%s">%s</a>`+"\n", id, html.EscapeString(title))
+		fmt.Fprintf(&body, `<section id="%s"><h2>%s</h2>`+"\n", id, html.EscapeString(title))
+	}
+
+	body.WriteString("<h1>Benchmark Report</h1>\n")
+	if info, err := LoadRunInfo(outDir); err == nil {
+		section("run", "Run")
+		body.WriteString(runInfoHTML(info))
+		body.WriteString("</section>\n")
+	}
+
+	card, err := scorecard(outDir)
+	if err != nil {
+		return err
+	}
+	if card != "" {
+		section("scorecard", "Scorecard")
+		body.WriteString("<p>Mean of the warm cache rows of each index (sum over the operation types of the mixed workloads); the best value of each column is highlighted.</p>\n")
+		body.WriteString(card)
+		body.WriteString("</section>\n")
+	}
+
+	for _, s := range r.sections {
+		section(figureName(s.label), s.label)
+		base := strings.TrimSuffix(s.file, ".csv")
+		for _, file := range []string{s.file, base + "_summary.csv"} {
+			for _, t := range typstTables {
+				if ok, _ := filepath.Match(t.file, file); !ok {
+					continue
+				}
+				records, err := readCSV(filepath.Join(outDir, file))
+				if err != nil {
+					return err
+				}
+				if len(records) > 1 {
+					body.WriteString(htmlTable(typstRows(t, records), nil))
+				}
+			}
+		}
+		for _, page := range s.pages {
+			for _, c := range page.Charts {
+				f, ok := figureOf(c)
+				if !ok {
+					continue
+				}
+				svg := newSVGCanvas()
+				f.draw(svg)
+				fmt.Fprintf(&body, "<figure>%s</figure>\n", svg.bytes())
+			}
+		}
+		body.WriteString("</section>\n")
+	}
+
+	if shapes, err := LoadStructureResults(outDir); err == nil && len(shapes) > 0 {
+		section("structure", "Index Structure")
+		body.WriteString("<p>After the fill phase. B-tree and B+ tree levels are listed from the root down as pages (average fill), LSM levels as sstables (size), followed by the entries still in the memtables.</p>\n")
+		body.WriteString(htmlTable(structureRows(shapes), nil))
+		body.WriteString("</section>\n")
+	}
+
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Benchmark Report</title>
+<style>
+body { margin: 0; font: 14px Helvetica, Arial, sans-serif; color: #333; }
+nav { position: fixed; top: 0; bottom: 0; left: 0; width: 170px; padding: 16px; overflow-y: auto; background: #f5f6f8; border-right: 1px solid #e0e6f1; }
+nav a { display: block; padding: 4px 0; color: #5470c6; text-decoration: none; }
+main { margin-left: 203px; padding: 8px 24px; max-width: 1100px; }
+section { margin-bottom: 40px; }
+h2 { border-bottom: 1px solid #e0e6f1; padding-bottom: 4px; }
+table { border-collapse: collapse; margin: 12px 0; }
+th, td { padding: 4px 10px; border-bottom: 1px solid #e0e6f1; text-align: left; }
+td.best { background: #e3f1df; font-weight: bold; }
+figure { margin: 16px 0; }
+figure svg { width: 100%; height: auto; }
+</style>
+</head>
+<body>
+<nav>
+<strong>Report</strong>
+`)
+	b.WriteString(nav.String())
+	b.WriteString("</nav>\n<main>\n")
+	b.WriteString(body.String())
+	b.WriteString("</main>\n</body>\n</html>\n")
+
+	path := filepath.Join(outDir, ReportFile)
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	fmt.Printf("[Report] written to %s\n", path)
+	return nil
+}
+
+// scorecard returns an HTML table of scoreMetrics and the throughput of
+// every mixed workload per index, or "" if outDir has none of their files.
+func scorecard(outDir string) (string, error) {
+	metrics := append([]scoreMetric{}, scoreMetrics...)
+	summaries, err := filepath.Glob(filepath.Join(outDir, "*_summary.csv"))
+	if err != nil {
+		return "", err
+	}
+	for _, path := range summaries {
+		file := filepath.Base(path)
+		metrics = append(metrics, scoreMetric{
+			heading: summaryTest(file) + " Ops/s", file: file, column: "ops_per_sec", higherBetter: true, sum: true,
+		})
+	}
+	metrics = append(metrics, scoreMetric{heading: "Space Amp.", file: SpaceFile, column: "space_amp"})
+
+	var indexes, headings []string
+	var columns []map[string]float64
+	var shown []scoreMetric
+	seen := make(map[string]bool)
+	for _, m := range metrics {
+		records, err := readCSV(filepath.Join(outDir, m.file))
+		if err != nil {
+			return "", err
+		}
+		if len(records) < 2 {
+			continue
+		}
+		col, cache := column(records[0], m.column), column(records[0], "cache")
+		idx := column(records[0], "index")
+		if col < 0 || idx < 0 {
+			continue
+		}
+		sums, counts := make(map[string]float64), make(map[string]int)
+		for _, rec := range records[1:] {
+			if cache >= 0 && rec[cache] == CacheCold {
+				continue
+			}
+			v, err := strconv.ParseFloat(rec[col], 64)
+			if err != nil {
+				continue
+			}
+			name := rec[idx]
+			if !seen[name] {
+				seen[name] = true
+				indexes = append(indexes, name)
+			}
+			sums[name] += v
+			counts[name]++
+		}
+		if !m.sum {
+			for name, n := range counts {
+				sums[name] /= float64(n)
+			}
+		}
+		headings = append(headings, m.heading)
+		columns = append(columns, sums)
+		shown = append(shown, m)
+	}
+	if len(columns) == 0 {
+		return "", nil
+	}
+
+	rows := [][]string{append([]string{"Index"}, append(headings, "Best")...)}
+	best := make([][]bool, len(indexes))
+	wins := make([]int, len(indexes))
+	for k, values := range columns {
+		var top float64
+		found := false
+		for _, v := range values {
+			if !found || (shown[k].higherBetter && v > top) || (!shown[k].higherBetter && v < top) {
+				top, found = v, true
+			}
+		}
+		for i, name := range indexes {
+			v, ok := values[name]
+			best[i] = append(best[i], ok && v == top)
+			if ok && v == top {
+				wins[i]++
+			}
+		}
+	}
+	for i, name := range indexes {
+		row := []string{name}
+		for _, values := range columns {
+			v, ok := values[name]
+			if !ok {
+				row = append(row, "–")
+				continue
+			}
+			row = append(row, formatScore(v))
+		}
+		rows = append(rows, append(row, strconv.Itoa(wins[i])))
+	}
+	highlight := make([][]bool, len(rows))
+	for i := range indexes {
+		highlight[i+1] = append(append([]bool{false}, best[i]...), false)
+	}
+	return htmlTable(rows, highlight), nil
+}
+
+// formatScore formats a scorecard value: large ones as integers, ratios
+// with two decimals.
+func formatScore(v float64) string {
+	if math.Abs(v) >= 100 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// structureRows returns the heading and a row per index and test of the
+// shapes in a StructureFile.
+func structureRows(shapes []StructureResult) [][]string {
+	sort.SliceStable(shapes, func(i, j int) bool { return shapes[i].Test < shapes[j].Test })
+	rows := [][]string{{"Test", "Index", "Height", "Entries", "Pages / Files", "Avg. Fill", "Fanout", "Fragmented", "Leaf Chain", "Levels"}}
+	for _, s := range shapes {
+		var levels []string
+		switch {
+		case s.Tree != nil:
+			t := s.Tree
+			for _, l := range t.Levels {
+				levels = append(levels, fmt.Sprintf("%d (%.0f%%)", l.Pages, 100*l.AvgFill))
+			}
+			rows = append(rows, []string{
+				s.Test, s.Index, strconv.Itoa(t.Height()), strconv.Itoa(t.Entries), strconv.Itoa(t.Pages()),
+				fmt.Sprintf("%.1f%%", 100*t.AvgFill), fmt.Sprintf("%.1f", t.AvgFanout),
+				fmt.Sprintf("%d B", t.FragmentedBytes), strconv.Itoa(t.LeafChainLength), strings.Join(levels, " · "),
+			})
+		default:
+			var files int64
+			for _, l := range s.LSM {
+				files += l.Files
+				levels = append(levels, fmt.Sprintf("L%d: %d (%.1f MiB)", l.Level, l.Files, float64(l.Bytes)/(1<<20)))
+			}
+			if s.Unflushed > 0 {
+				levels = append(levels, fmt.Sprintf("memtables (%.1f MiB)", float64(s.Unflushed)/(1<<20)))
+			}
+			rows = append(rows, []string{
+				s.Test, s.Index, strconv.Itoa(len(s.LSM)), "–", strconv.FormatInt(files, 10), "–", "–", "–", "–",
+				strings.Join(levels, " · "),
+			})
+		}
+	}
+	return rows
+}
+
+// htmlTable returns rows, the first being the heading, as an HTML table.
+// Cells set in highlight (indexed like rows) are marked as the best.
+func htmlTable(rows [][]string, highlight [][]bool) string {
+	var b strings.Builder
+	b.WriteString("<table>\n<tr>")
+	for _, h := range rows[0] {
+		fmt.Fprintf(&b, "<th>%s</th>", html.EscapeString(h))
+	}
+	b.WriteString("</tr>\n")
+	for i, row := range rows[1:] {
+		b.WriteString("<tr>")
+		for j, v := range row {
+			class := ""
+			if i+1 < len(highlight) && j < len(highlight[i+1]) && highlight[i+1][j] {
+				class = ` class="best"`
+			}
+			fmt.Fprintf(&b, "<td%s>%s</td>", class, html.EscapeString(v))
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
@@ -0,0 +1,229 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunInfoFile describes how the results in a results directory were produced.
+const RunInfoFile = "run.json"
+
+// RunInfo is the content of RunInfoFile: the configuration of a suite run
+// and the environment it ran in.
+type RunInfo struct {
+	Started  time.Time   `json:"started"`
+	Finished time.Time   `json:"finished,omitzero"` // zero while running or if the run failed
+	Error    string      `json:"error,omitempty"`   // why the run failed
+	Config   Config      `json:"config"`            // resolved configuration, before per-test params
+	Tests    []TestSpec  `json:"tests"`
+	Indexes  []IndexSpec `json:"indexes"` // with the defaults filled in
+	Env      Environment `json:"environment"`
+}
+
+// Environment describes the machine and build a benchmark ran on. Fields
+// that cannot be determined (e.g. /proc entries outside Linux) are empty.
+type Environment struct {
+	GitCommit   string `json:"git_commit,omitempty"`
+	GitDirty    bool   `json:"git_dirty,omitempty"` // uncommitted changes in the working tree
+	GoVersion   string `json:"go_version"`
+	OS          string `json:"os"`
+	Arch        string `json:"arch"`
+	Hostname    string `json:"hostname,omitempty"`
+	Kernel      string `json:"kernel,omitempty"`
+	CPUModel    string `json:"cpu_model,omitempty"`
+	NumCPU      int    `json:"num_cpu"`
+	GOMAXPROCS  int    `json:"gomaxprocs"`
+	MemoryBytes uint64 `json:"memory_bytes,omitempty"`
+	DataDirFS   string `json:"data_dir_fs,omitempty"` // filesystem type of Config.DataDir
+}
+
+// newRunInfo describes a run of s with cfg that starts now.
+func newRunInfo(s *Suite, cfg Config) (*RunInfo, error) {
+	specs := s.Indexes
+	if len(specs) == 0 {
+		specs = DefaultIndexSpecs(cfg.Baselines)
+	}
+	info := &RunInfo{Started: time.Now(), Config: cfg, Tests: s.Tests, Env: captureEnvironment(cfg.DataDir)}
+	for _, spec := range specs {
+		r, err := spec.resolve(cfg)
+		if err != nil {
+			return nil, err
+		}
+		info.Indexes = append(info.Indexes, r)
+	}
+	return info, nil
+}
+
+// write stores info as RunInfoFile in dir.
+func (info *RunInfo) write(dir string) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create results dir: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, RunInfoFile), append(data, '\n'), 0644)
+}
+
+// LoadRunInfo reads RunInfoFile from dir.
+func LoadRunInfo(dir string) (*RunInfo, error) {
+	data, err := os.ReadFile(filepath.Join(dir, RunInfoFile))
+	if err != nil {
+		return nil, err
+	}
+	var info RunInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", RunInfoFile, err)
+	}
+	return &info, nil
+}
+
+func captureEnvironment(dataDir string) Environment {
+	env := Environment{
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		DataDirFS:  filesystemType(dataDir),
+	}
+	env.Hostname, _ = os.Hostname()
+	env.GitCommit, env.GitDirty = gitRevision()
+	if b, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		env.Kernel = strings.TrimSpace(string(b))
+	}
+	env.CPUModel = procField("/proc/cpuinfo", "model name")
+	if kb, err := strconv.ParseUint(strings.TrimSuffix(procField("/proc/meminfo", "MemTotal"), " kB"), 10, 64); err == nil {
+		env.MemoryBytes = kb << 10
+	}
+	return env
+}
+
+// procField returns the value of the first "name: value" line of a /proc file.
+func procField(path, name string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, val, ok := strings.Cut(sc.Text(), ":")
+		if ok && strings.TrimSpace(key) == name {
+			return strings.TrimSpace(val)
+		}
+	}
+	return ""
+}
+
+// gitRevision returns the commit the binary was built from, from the build
+// info or, for "go run", from git in the working directory.
+func gitRevision() (commit string, dirty bool) {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				commit = s.Value
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+	}
+	if commit != "" {
+		return commit, dirty
+	}
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	status, _ := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+	return strings.TrimSpace(string(out)), len(bytes.TrimSpace(status)) > 0
+}
+
+// filesystemType returns the type of the filesystem holding dir, from the
+// mount with the longest matching mount point in /proc/self/mounts.
+func filesystemType(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	if p, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = p
+	}
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var best, fsType string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		mount := strings.ReplaceAll(fields[1], `\040`, " ")
+		if (abs == mount || strings.HasPrefix(abs, strings.TrimSuffix(mount, "/")+"/")) && len(mount) >= len(best) {
+			best, fsType = mount, fields[2]
+		}
+	}
+	return fsType
+}
+
+// runInfoHTML returns the header the plotter puts above the charts of a
+// results directory described by info.
+func runInfoHTML(info *RunInfo) string {
+	e := info.Env
+	commit := e.GitCommit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if e.GitDirty {
+		commit += " (modified)"
+	}
+	finished := "unfinished"
+	if !info.Finished.IsZero() {
+		finished = info.Finished.Format(time.DateTime)
+	}
+	if info.Error != "" {
+		finished = "failed: " + info.Error
+	}
+	var tests []string
+	for _, t := range info.Tests {
+		tests = append(tests, t.Name)
+	}
+	var indexes []string
+	for _, s := range info.Indexes {
+		indexes = append(indexes, s.Name)
+	}
+	lines := []string{
+		fmt.Sprintf("Run %s – %s · commit %s · %s %s/%s",
+			info.Started.Format(time.DateTime), finished, commit, e.GoVersion, e.OS, e.Arch),
+		fmt.Sprintf("%s · %d CPUs (GOMAXPROCS %d) · %.1f GiB RAM · kernel %s · data dir on %s",
+			e.CPUModel, e.NumCPU, e.GOMAXPROCS, float64(e.MemoryBytes)/(1<<30), e.Kernel, e.DataDirFS),
+		fmt.Sprintf("Tests %s · %d entries · seed %d · indexes %s",
+			strings.Join(tests, ", "), info.Config.DatasetSize, info.Config.Seed, strings.Join(indexes, ", ")),
+	}
+	var b strings.Builder
+	b.WriteString(`<div style="font: 13px sans-serif; color: #444; margin: 8px 16px;">`)
+	for _, l := range lines {
+		fmt.Fprintf(&b, "<div>%s</div>", html.EscapeString(l))
+	}
+	if data, err := json.MarshalIndent(info, "", "  "); err == nil {
+		fmt.Fprintf(&b, "<details><summary>%s</summary><pre>%s</pre></details>", RunInfoFile, html.EscapeString(string(data)))
+	}
+	b.WriteString("</div>")
+	return b.String()
+}
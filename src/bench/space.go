@@ -0,0 +1,100 @@
+package bench
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+	"github.com/btree-query-bench/bmark/dbms/index/shared"
+)
+
+// SpaceFile collects the space measurements of all tests.
+const SpaceFile = "space.csv"
+
+// Measurement points of a SpaceResult.
+const (
+	PhaseFill     = "fill"     // after the index was loaded with the dataset
+	PhaseWorkload = "workload" // after the test's operations
+)
+
+// SpaceResult is the on-disk footprint of an index at one point of a test.
+type SpaceResult struct {
+	Test         string
+	Index        string
+	Phase        string  // PhaseFill or PhaseWorkload
+	PayloadBytes int64   // logical size of the live entries: 8 bytes per key plus the value
+	DiskBytes    int64   // file or directory size (see indexSize)
+	Pages        int     // B-tree and B+ tree: pages reachable from the root
+	AvgFill      float64 // B-tree and B+ tree: average used fraction of those pages
+	SpaceAmp     float64 // DiskBytes / PayloadBytes
+}
+
+var spaceHeader = []string{
+	"test", "index", "phase", "payload_bytes", "disk_bytes", "pages", "avg_fill", "space_amp",
+}
+
+// measureSpace measures the open index idx stored at path, which holds
+// payload bytes of keys and values. tree, if not nil, holds the statistics
+// of idx that logIndexShape already collected, which saves walking it again.
+func measureSpace(test, name, phase string, idx index.Index, path string, payload int64, tree *shared.Stats) SpaceResult {
+	r := SpaceResult{
+		Test:         test,
+		Index:        name,
+		Phase:        phase,
+		PayloadBytes: payload,
+		DiskBytes:    indexSize(idx, path),
+	}
+	if tree != nil {
+		r.Pages, r.AvgFill = tree.Pages(), tree.AvgFill
+	} else if f, ok := idx.(interface {
+		PageFill() (int, float64, error)
+	}); ok {
+		if pages, fill, err := f.PageFill(); err == nil {
+			r.Pages, r.AvgFill = pages, fill
+		}
+	}
+	r.SpaceAmp = ratio(float64(r.DiskBytes), float64(payload))
+	fmt.Printf("[%s] %s: %s size=%.1f MiB payload=%.1f MiB pages=%d fill=%.1f%% space_amp=%.2f\n",
+		test, name, phase, float64(r.DiskBytes)/(1<<20), float64(payload)/(1<<20), r.Pages, 100*r.AvgFill, r.SpaceAmp)
+	return r
+}
+
+// writeSpaceResults stores the measurements of one test in SpaceFile in
+// outDir, replacing the rows an earlier run of the same test left there.
+func writeSpaceResults(outDir, test string, results []SpaceResult) error {
+	path := filepath.Join(outDir, SpaceFile)
+	var rows [][]string
+	if f, err := os.Open(path); err == nil {
+		old, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+		for _, rec := range old[min(1, len(old)):] {
+			if rec[0] != test {
+				rows = append(rows, rec)
+			}
+		}
+	}
+	for _, r := range results {
+		rows = append(rows, []string{
+			r.Test, r.Index, r.Phase,
+			strconv.FormatInt(r.PayloadBytes, 10), strconv.FormatInt(r.DiskBytes, 10),
+			strconv.Itoa(r.Pages), strconv.FormatFloat(r.AvgFill, 'f', 4, 64),
+			strconv.FormatFloat(r.SpaceAmp, 'f', 3, 64),
+		})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create csv: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	_ = w.Write(spaceHeader)
+	_ = w.WriteAll(rows)
+	return w.Error()
+}
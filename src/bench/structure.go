@@ -0,0 +1,112 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+	"github.com/btree-query-bench/bmark/dbms/index/lsm"
+	"github.com/btree-query-bench/bmark/dbms/index/shared"
+)
+
+// StructureFile collects the shape of every index after the fill phase of
+// the tests that log it.
+const StructureFile = "structure.json"
+
+// StructureResult is the shape of an index after it was filled: the
+// occupancy statistics of a B-tree or B+ tree, or the levels of an LSM.
+type StructureResult struct {
+	Test  string
+	Index string
+	Tree  *shared.Stats    `json:",omitempty"`
+	LSM   []lsm.LevelStats `json:",omitempty"` // non-empty levels
+
+	// Unflushed is the size of the LSM memtables, which hold the entries not
+	// in any level yet.
+	Unflushed int64 `json:",omitempty"`
+}
+
+// logIndexShape prints the structure of idx after the fill phase and
+// returns it. It reports false for indexes without one, i.e. the in-memory
+// baselines, and if the tree statistics fail.
+func logIndexShape(label, name string, idx index.Index) (StructureResult, bool) {
+	r := StructureResult{Test: label, Index: name}
+	if l, ok := idx.(interface {
+		LevelStats() []lsm.LevelStats
+		UnflushedBytes() int64
+	}); ok {
+		r.LSM, r.Unflushed = l.LevelStats(), l.UnflushedBytes()
+		levels := []string{"none"}
+		if len(r.LSM) > 0 {
+			levels = nil
+		}
+		for _, l := range r.LSM {
+			levels = append(levels, fmt.Sprintf("L%d: %d files (%.1f MiB)", l.Level, l.Files, float64(l.Bytes)/(1<<20)))
+		}
+		fmt.Printf("[%s] %s: lsm levels = %s, memtables = %.1f MiB\n",
+			label, name, strings.Join(levels, ", "), float64(r.Unflushed)/(1<<20))
+		return r, true
+	}
+	t, ok := idx.(interface{ Stats() (shared.Stats, error) })
+	if !ok {
+		return r, false
+	}
+	s, err := t.Stats()
+	if err != nil {
+		fmt.Printf("[%s] %s: tree stats failed: %v\n", label, name, err)
+		return r, false
+	}
+	r.Tree = &s
+	fmt.Printf("[%s] %s: tree height = %d, entries = %d, pages = %d, avg fill = %.1f%%, avg fanout = %.1f, fragmented = %d B, leaf chain = %d\n",
+		label, name, s.Height(), s.Entries, s.Pages(), 100*s.AvgFill, s.AvgFanout, s.FragmentedBytes, s.LeafChainLength)
+	for i, l := range s.Levels {
+		fmt.Printf("[%s] %s:   level %d: pages = %d, keys = %d, avg fill = %.1f%%\n", label, name, i, l.Pages, l.Keys, 100*l.AvgFill)
+	}
+	var hist []string
+	for i, c := range s.FillHistogram {
+		hist = append(hist, fmt.Sprintf("%d-%d%%: %d", i*100/shared.FillBuckets, (i+1)*100/shared.FillBuckets, c))
+	}
+	fmt.Printf("[%s] %s:   fill histogram: %s\n", label, name, strings.Join(hist, ", "))
+	return r, true
+}
+
+// LoadStructureResults reads the StructureFile in dir.
+func LoadStructureResults(dir string) ([]StructureResult, error) {
+	data, err := os.ReadFile(filepath.Join(dir, StructureFile))
+	if err != nil {
+		return nil, err
+	}
+	var results []StructureResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", StructureFile, err)
+	}
+	return results, nil
+}
+
+// writeStructureResults stores the shapes of one test in StructureFile in
+// outDir, replacing the entries an earlier run of the same test left there.
+func writeStructureResults(outDir, test string, results []StructureResult) error {
+	old, err := LoadStructureResults(outDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var all []StructureResult
+	for _, r := range old {
+		if r.Test != test {
+			all = append(all, r)
+		}
+	}
+	all = append(all, results...)
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(outDir, StructureFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
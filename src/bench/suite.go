@@ -0,0 +1,424 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+	"github.com/btree-query-bench/bmark/dbms/index/bptree"
+	"github.com/btree-query-bench/bmark/dbms/index/btree"
+	"github.com/btree-query-bench/bmark/dbms/index/lsm"
+	"github.com/btree-query-bench/bmark/dbms/index/membtree"
+	"github.com/btree-query-bench/bmark/dbms/index/sortedarray"
+)
+
+// Index types understood by IndexSpec.
+const (
+	TypeBTree       = "btree"
+	TypeBPTree      = "bptree"
+	TypeLSM         = "lsm"
+	TypeSortedArray = "sorted_array"
+	TypeMemBTree    = "mem_btree"
+)
+
+// IndexSpec declares one index variant to benchmark. Fields that do not apply
+// to the type are ignored; zero values select the defaults.
+type IndexSpec struct {
+	Name        string `json:"name,omitempty"`          // defaults to e.g. "btree_4k" or "lsm_pebble_16m"
+	Type        string `json:"type"`                    // one of the Type* constants
+	PageSize    uint32 `json:"page_size,omitempty"`     // btree, bptree (default 4096)
+	CachePages  int    `json:"cache_pages,omitempty"`   // btree, bptree (default Config.CachePages)
+	MemtableMB  int64  `json:"memtable_mb,omitempty"`   // lsm (default 64)
+	Degree      int    `json:"degree,omitempty"`        // mem_btree (default membtree.DefaultDegree)
+	Multi       bool   `json:"multi,omitempty"`         // btree, bptree: multi-value mode
+	Counted     bool   `json:"counted,omitempty"`       // btree, bptree: maintain subtree counts
+	CopyOnWrite bool   `json:"copy_on_write,omitempty"` // bptree
+}
+
+// DefaultIndexSpecs returns the variants benchmarked when no suite file lists any.
+func DefaultIndexSpecs(baselines bool) []IndexSpec {
+	var specs []IndexSpec
+	for _, typ := range []string{TypeBTree, TypeBPTree} {
+		for _, size := range []uint32{4096, 8192, 16384} {
+			specs = append(specs, IndexSpec{Type: typ, PageSize: size})
+		}
+	}
+	for _, mb := range []int64{16, 32, 64} {
+		specs = append(specs, IndexSpec{Type: TypeLSM, MemtableMB: mb})
+	}
+	if baselines {
+		specs = append(specs, IndexSpec{Type: TypeSortedArray}, IndexSpec{Type: TypeMemBTree})
+	}
+	return specs
+}
+
+// resolve returns the spec with the defaults from cfg filled in for the
+// fields that apply to its type.
+func (s IndexSpec) resolve(cfg Config) (IndexSpec, error) {
+	r := IndexSpec{Name: s.Name, Type: s.Type}
+	switch s.Type {
+	case TypeBTree, TypeBPTree:
+		r.PageSize, r.CachePages = s.PageSize, s.CachePages
+		if r.PageSize == 0 {
+			r.PageSize = 4096
+		}
+		if r.CachePages == 0 {
+			r.CachePages = cfg.CachePages
+		}
+		r.Multi, r.Counted = s.Multi, s.Counted
+		r.CopyOnWrite = s.CopyOnWrite && s.Type == TypeBPTree
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("%s_%dk", s.Type, r.PageSize/1024)
+		}
+	case TypeLSM:
+		r.MemtableMB = s.MemtableMB
+		if r.MemtableMB == 0 {
+			r.MemtableMB = 64
+		}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("lsm_pebble_%dm", r.MemtableMB)
+		}
+	case TypeMemBTree:
+		r.Degree = s.Degree
+		if r.Degree == 0 {
+			r.Degree = membtree.DefaultDegree
+		}
+	case TypeSortedArray:
+	default:
+		return IndexSpec{}, fmt.Errorf("index %q: unknown type %q", s.Name, s.Type)
+	}
+	if r.Name == "" {
+		r.Name = s.Type
+	}
+	return r, nil
+}
+
+// Def turns the spec into an IndexDef, filling in defaults from cfg.
+func (s IndexSpec) Def(cfg Config) (IndexDef, error) {
+	r, err := s.resolve(cfg)
+	if err != nil {
+		return IndexDef{}, err
+	}
+	var newFunc func(path string) (index.Index, error)
+	switch r.Type {
+	case TypeBTree:
+		newFunc = func(path string) (index.Index, error) {
+			switch {
+			case r.Counted:
+				return btree.OpenCounted(path, r.CachePages, r.PageSize, r.Multi)
+			case r.Multi:
+				return btree.OpenMulti(path, r.CachePages, r.PageSize)
+			}
+			return btree.Open(path, r.CachePages, r.PageSize)
+		}
+	case TypeBPTree:
+		newFunc = func(path string) (index.Index, error) {
+			var t *bptree.BPTree
+			var err error
+			switch {
+			case r.Counted:
+				t, err = bptree.OpenCounted(path, r.CachePages, r.PageSize, r.Multi)
+			case r.Multi:
+				t, err = bptree.OpenMulti(path, r.CachePages, r.PageSize)
+			default:
+				t, err = bptree.Open(path, r.CachePages, r.PageSize)
+			}
+			if err != nil {
+				return nil, err
+			}
+			t.CopyOnWrite = r.CopyOnWrite
+			return t, nil
+		}
+	case TypeLSM:
+		newFunc = func(path string) (index.Index, error) {
+			return lsm.Open(path, r.MemtableMB)
+		}
+	case TypeSortedArray:
+		newFunc = func(_ string) (index.Index, error) {
+			return sortedarray.NewSortedArray(), nil
+		}
+	case TypeMemBTree:
+		newFunc = func(_ string) (index.Index, error) {
+			return membtree.NewMemBTree(r.Degree), nil
+		}
+	}
+	return IndexDef{Name: r.Name, NewFunc: newFunc}, nil
+}
+
+// Suite is a declarative benchmark suite, read from a JSON file:
+//
+//	{
+//	  "config":  {"dataset_size": 1000000, "key_pattern": "sparse"},
+//	  "indexes": [{"type": "bptree", "page_size": 8192}, {"type": "lsm", "memtable_mb": 32}],
+//	  "tests":   [{"name": "t1"}, {"name": "t4", "params": {"t4_mix": "read=90,update=10"}}, {"name": "ycsb_a"}]
+//	}
+//
+// Config and params use the Config JSON names. Without indexes, the default
+// variants are benchmarked.
+type Suite struct {
+	Config  json.RawMessage `json:"config,omitempty"`
+	Indexes []IndexSpec     `json:"indexes,omitempty"`
+	Tests   []TestSpec      `json:"tests"`
+}
+
+// TestSpec selects a test (see TestNames) and overrides Config for it.
+type TestSpec struct {
+	Name   string          `json:"name"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// TestFunc runs one benchmark test.
+type TestFunc func(indices []IndexDef, cfg Config) error
+
+// tests returns the runnable tests by name.
+func tests() map[string]TestFunc {
+	m := map[string]TestFunc{
+		"t1": RunBenchmarkT1,
+		"t2": RunBenchmarkT2,
+		"t3": RunBenchmarkT3,
+		"t4": RunBenchmarkT4,
+		"t5": RunBenchmarkT5,
+
+		"replay": RunBenchmarkReplay,
+	}
+	for _, wl := range YCSBWorkloads {
+		m["ycsb_"+strings.ToLower(wl.Name)] = func(indices []IndexDef, cfg Config) error {
+			return RunBenchmarkYCSB(indices, cfg, wl)
+		}
+	}
+	return m
+}
+
+// TestNames returns the names a suite can list, sorted.
+func TestNames() []string {
+	var names []string
+	for name := range tests() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadSuite reads a suite file. Unknown fields are rejected to catch typos.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read suite: %w", err)
+	}
+	var s Suite
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("parse suite %s: %w", path, err)
+	}
+	known := tests()
+	for _, t := range s.Tests {
+		if known[strings.ToLower(t.Name)] == nil {
+			return nil, fmt.Errorf("suite %s: unknown test %q (want one of %v)", path, t.Name, TestNames())
+		}
+	}
+	return &s, nil
+}
+
+// Select restricts the suite to the named tests, in the given order. A test
+// the suite lists keeps its params (every entry, if listed several times);
+// other tests are added without params.
+func (s *Suite) Select(names []string) error {
+	known := tests()
+	var selected []TestSpec
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if known[name] == nil {
+			return fmt.Errorf("unknown test %q (want one of %v)", name, TestNames())
+		}
+		n := len(selected)
+		for _, t := range s.Tests {
+			if strings.ToLower(t.Name) == name {
+				selected = append(selected, t)
+			}
+		}
+		if len(selected) == n {
+			selected = append(selected, TestSpec{Name: name})
+		}
+	}
+	s.Tests = selected
+	return nil
+}
+
+// DefaultSuite returns the suite run without a suite file: T4, T5 and the
+// YCSB workloads listed in cfg.YCSB, on the default index variants.
+func DefaultSuite(cfg Config) (*Suite, error) {
+	s := &Suite{Tests: []TestSpec{{Name: "t4"}, {Name: "t5"}}}
+	for _, name := range cfg.YCSB {
+		wl, ok := LookupYCSBWorkload(string(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown YCSB workload %q", name)
+		}
+		s.Tests = append(s.Tests, TestSpec{Name: "ycsb_" + strings.ToLower(wl.Name)})
+	}
+	return s, nil
+}
+
+// ApplyConfig decodes the JSON object patch onto cfg, leaving fields it does not name unchanged.
+func ApplyConfig(cfg *Config, patch json.RawMessage) error {
+	if len(patch) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("apply config: %w", err)
+	}
+	return nil
+}
+
+// ConfigPatch returns the JSON object holding only the named fields of cfg,
+// e.g. to re-apply explicitly set command-line flags on top of a suite. A
+// name that is not a field of Config is an error.
+func ConfigPatch(cfg Config, names []string) (json.RawMessage, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	patch := make(map[string]json.RawMessage)
+	for _, name := range names {
+		v, ok := all[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown config field %q", name)
+		}
+		patch[name] = v
+	}
+	return json.Marshal(patch)
+}
+
+// Resolve returns base with the suite's config and then overrides applied.
+func (s *Suite) Resolve(base Config, overrides json.RawMessage) (Config, error) {
+	cfg := base
+	if err := ApplyConfig(&cfg, s.Config); err != nil {
+		return Config{}, fmt.Errorf("suite config: %w", err)
+	}
+	if err := ApplyConfig(&cfg, overrides); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Run executes the suite's tests in order. cfg is the resolved configuration
+// (see Resolve); each test's params are applied on top of it, followed by
+// overrides again so that command-line flags always win.
+//
+// The configuration, index variants and environment are recorded in
+// RunInfoFile in cfg.OutDir, together with the start and end of the run.
+func (s *Suite) Run(cfg Config, overrides json.RawMessage) (err error) {
+	info, err := newRunInfo(s, cfg)
+	if err != nil {
+		return err
+	}
+	if err := info.write(cfg.OutDir); err != nil {
+		return fmt.Errorf("write %s: %w", RunInfoFile, err)
+	}
+	defer func() {
+		if err != nil {
+			info.Error = err.Error()
+		} else {
+			info.Finished = time.Now()
+		}
+		if werr := info.write(cfg.OutDir); werr != nil && err == nil {
+			err = fmt.Errorf("write %s: %w", RunInfoFile, werr)
+		}
+	}()
+
+	known := tests()
+	for _, t := range s.Tests {
+		run := known[strings.ToLower(t.Name)]
+		if run == nil {
+			return fmt.Errorf("unknown test %q (want one of %v)", t.Name, TestNames())
+		}
+		tcfg := cfg
+		if err := ApplyConfig(&tcfg, t.Params); err != nil {
+			return fmt.Errorf("test %s: %w", t.Name, err)
+		}
+		if err := ApplyConfig(&tcfg, overrides); err != nil {
+			return err
+		}
+
+		indices, err := s.indexDefs(tcfg)
+		if err != nil {
+			return err
+		}
+		if tcfg.Repetitions > 1 {
+			if err := runRepeated(run, indices, tcfg); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(tcfg.DataDir, 0755); err != nil {
+			return fmt.Errorf("create data dir: %w", err)
+		}
+		if err := os.MkdirAll(tcfg.OutDir, 0755); err != nil {
+			return fmt.Errorf("create results dir: %w", err)
+		}
+		if err := run(indices, tcfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runRepeated runs a test cfg.Repetitions times, each with an emptied data
+// directory and its own results and trace directories (see RepetitionDir),
+// and then aggregates the results into cfg.OutDir.
+func runRepeated(run TestFunc, indices []IndexDef, cfg Config) error {
+	for r := 1; r <= cfg.Repetitions; r++ {
+		rcfg := cfg
+		rcfg.DataDir = RepetitionDir(cfg.DataDir, r)
+		rcfg.OutDir = RepetitionDir(cfg.OutDir, r)
+		if cfg.RecordTrace != "" {
+			rcfg.RecordTrace = RepetitionDir(cfg.RecordTrace, r)
+		}
+		if err := os.RemoveAll(rcfg.DataDir); err != nil {
+			return fmt.Errorf("clear data dir: %w", err)
+		}
+		if err := os.MkdirAll(rcfg.DataDir, 0755); err != nil {
+			return fmt.Errorf("create data dir: %w", err)
+		}
+		if err := os.MkdirAll(rcfg.OutDir, 0755); err != nil {
+			return fmt.Errorf("create results dir: %w", err)
+		}
+		fmt.Printf("--- repetition %d of %d ---\n", r, cfg.Repetitions)
+		if err := run(indices, rcfg); err != nil {
+			return fmt.Errorf("repetition %d: %w", r, err)
+		}
+	}
+	if err := aggregateRepetitions(cfg.OutDir, cfg.Repetitions); err != nil {
+		return fmt.Errorf("aggregate repetitions: %w", err)
+	}
+	fmt.Printf("aggregated %d repetitions into %s\n", cfg.Repetitions, cfg.OutDir)
+	return nil
+}
+
+// indexDefs returns the suite's index variants, or the defaults if it lists none.
+func (s *Suite) indexDefs(cfg Config) ([]IndexDef, error) {
+	specs := s.Indexes
+	if len(specs) == 0 {
+		specs = DefaultIndexSpecs(cfg.Baselines)
+	}
+	var defs []IndexDef
+	for _, spec := range specs {
+		def, err := spec.Def(cfg)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
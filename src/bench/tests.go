@@ -27,16 +27,20 @@ type T1Result struct {
 	P99Ns     int64
 	OpsPerSec float64
 	TotalMs   int64
+	IO        IOResult
+	Cache     string // CacheWarm or CacheCold
 }
 
-var t1Header = []string{
+var t1Header = append(append([]string{
 	"index", "n_dataset", "n_queries",
 	"min_ns", "q1_ns", "p50_ns", "q3_ns", "max_ns",
 	"avg_ns", "p95_ns", "p99_ns",
 	"ops_per_sec", "total_ms",
-}
+}, ioHeader...), "cache")
 
-func fillIndex(idx index.Index, ds Dataset) error {
+// fillIndex loads idx with the dataset in ascending key order, recording
+// the inserts with samp and, as the load, in the trace tr.
+func fillIndex(idx index.Index, ds Dataset, samp *sampler, tr *traceRecorder) error {
 	// Disable sync for initial fill to speed up preparation.
 	if s, ok := idx.(interface{ SetSyncInterval(int) }); ok {
 		s.SetSyncInterval(0)
@@ -56,10 +60,14 @@ func fillIndex(idx index.Index, ds Dataset) error {
 
 	for _, i := range indices {
 		k := ds.Keys[i]
+		tr.record(TraceOp{Op: TraceInsert, Key: k, ValueSize: len(ds.Values[i]), Load: true})
+		t := time.Now()
 		if err := idx.Insert(k, ds.Values[i]); err != nil {
 			return fmt.Errorf("insert key %d: %w", k, err)
 		}
+		samp.record(time.Since(t).Nanoseconds())
 	}
+	samp.flush()
 	return nil
 }
 
@@ -75,8 +83,28 @@ func cleanupIndexData(path string) {
 // RunBenchmarkT1 executes the point query benchmark (T1).
 // It fills each index with a dataset and measures the response time and throughput of random point queries.
 func RunBenchmarkT1(indices []IndexDef, cfg Config) error {
-	ds := NewDataset(cfg.DatasetSize, cfg.ValueSize, cfg.Seed)
+	ds, err := cfg.dataset()
+	if err != nil {
+		return fmt.Errorf("T1: %w", err)
+	}
 	queryKeys := ds.RandomKeys(cfg.PointQueryCount)
+	if cfg.T1Dist != "" && cfg.T1Dist != DistUniform {
+		kc, err := NewKeyChooser(cfg.keyDist(cfg.T1Dist), cfg.Seed+1)
+		if err != nil {
+			return fmt.Errorf("T1: %w", err)
+		}
+		queryKeys = ds.ChooseKeys(cfg.PointQueryCount, kc)
+	}
+	phases, err := cfg.cachePhases()
+	if err != nil {
+		return fmt.Errorf("T1: %w", err)
+	}
+	// The warm-up reads keys of the same distribution as the queries.
+	sortedKeys := ds.SortedKeys()
+	warmKeys, err := NewKeyChooser(cfg.keyDist(cfg.T1Dist), cfg.Seed+5)
+	if err != nil {
+		return fmt.Errorf("T1: %w", err)
+	}
 
 	if err := os.MkdirAll(cfg.OutDir, 0755); err != nil {
 		return fmt.Errorf("create out dir: %w", err)
@@ -91,6 +119,28 @@ func RunBenchmarkT1(indices []IndexDef, cfg Config) error {
 	defer w.Flush()
 	_ = w.Write(t1Header)
 
+	lf, err := os.Create(filepath.Join(cfg.OutDir, "t1_point_query_latency.csv"))
+	if err != nil {
+		return fmt.Errorf("create csv: %w", err)
+	}
+	defer lf.Close()
+	lw := csv.NewWriter(lf)
+	defer lw.Flush()
+	_ = lw.Write(latencyHeader)
+
+	ts, err := newTimeseries(cfg, "t1_point_query.csv")
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	rec, err := newTraceRecorder(cfg, "t1_point_query.csv")
+	if err != nil {
+		return fmt.Errorf("T1: %w", err)
+	}
+	defer rec.Close()
+
+	var space []SpaceResult
+	var shapes []StructureResult
 	for _, def := range indices {
 		fmt.Printf("[T1] %s: filling index with %d keys...\n", def.Name, cfg.DatasetSize)
 
@@ -101,7 +151,8 @@ func RunBenchmarkT1(indices []IndexDef, cfg Config) error {
 			continue
 		}
 
-		if err := fillIndex(idx, *ds); err != nil {
+		tr := rec.forIndex(def.Name)
+		if err := fillIndex(idx, *ds, ts.sampler(def.Name, PhaseFill, idx), tr); err != nil {
 			fmt.Printf("[T1] %s: fill failed: %v — skipping\n", def.Name, err)
 			_ = idx.Close()
 			if cfg.CleanupData {
@@ -110,76 +161,116 @@ func RunBenchmarkT1(indices []IndexDef, cfg Config) error {
 			continue
 		}
 
-		if h, ok := idx.(interface{ Height() int }); ok {
-			fmt.Printf("[T1] %s: tree height = %d\n", def.Name, h.Height())
-		}
-		if l, ok := idx.(interface{ Levels() string }); ok {
-			fmt.Printf("[T1] %s: lsm levels = %s\n", def.Name, l.Levels())
+		shape, ok := logIndexShape("T1", def.Name, idx)
+		if ok {
+			shapes = append(shapes, shape)
 		}
+		space = append(space, measureSpace("T1", def.Name, PhaseFill, idx, idxPath, ds.Bytes(), shape.Tree))
 
-		fmt.Printf("[T1] %s: running %d point queries...\n", def.Name, cfg.PointQueryCount)
+		for i, phase := range phases {
+			if i > 0 {
+				tr = nil // the queries repeat those of the first phase
+			}
+			if phase == CacheCold {
+				if idx, err = reopenCold(def, idx, idxPath, cfg.DropOSCache); err != nil {
+					fmt.Printf("[T1] %s: cold cache: %v — skipping\n", def.Name, err)
+					if idx == nil {
+						break
+					}
+					continue
+				}
+			} else {
+				warmUp("T1", def.Name, idx, sortedKeys, warmKeys, cfg.Warmup)
+			}
 
-		responetimes := make([]int64, 0, cfg.PointQueryCount)
-		start := time.Now()
+			fmt.Printf("[T1] %s: running %d point queries (%s cache)...\n", def.Name, cfg.PointQueryCount, phase)
 
-		for _, key := range queryKeys {
-			t := time.Now()
-			val, e := idx.Get(key)
-			responetimes = append(responetimes, time.Since(t).Nanoseconds())
-			if e != nil {
-				fmt.Printf("[T1] %s: Get(%d) error: %v\n", def.Name, key, e)
-			} else if val == nil {
-				fmt.Printf("[T1] %s: key %d not found\n", def.Name, key)
+			var hist Histogram
+			meter := newIOMeter(idx, idxPath)
+			samp := ts.sampler(def.Name, phase, idx)
+			start := time.Now()
+
+			for _, key := range queryKeys {
+				tr.record(TraceOp{Op: TraceGet, Key: key})
+				t := time.Now()
+				val, e := idx.Get(key)
+				ns := time.Since(t).Nanoseconds()
+				hist.Record(ns)
+				samp.record(ns)
+				if e != nil {
+					fmt.Printf("[T1] %s: Get(%d) error: %v\n", def.Name, key, e)
+				} else if val == nil {
+					fmt.Printf("[T1] %s: key %d not found\n", def.Name, key)
+				}
+				meter.read(val)
+			}
+			samp.flush()
+
+			totalDuration := time.Since(start)
+
+			r := T1Result{
+				Index:     def.Name,
+				NDataset:  cfg.DatasetSize,
+				NQueries:  cfg.PointQueryCount,
+				MinNs:     hist.Min(),
+				Q1Ns:      hist.Percentile(25),
+				P50Ns:     hist.Percentile(50),
+				Q3Ns:      hist.Percentile(75),
+				MaxNs:     hist.Max(),
+				AvgNs:     hist.Mean(),
+				P95Ns:     hist.Percentile(95),
+				P99Ns:     hist.Percentile(99),
+				OpsPerSec: float64(cfg.PointQueryCount) / totalDuration.Seconds(),
+				TotalMs:   totalDuration.Milliseconds(),
+				IO:        meter.result(ds.Bytes()),
+				Cache:     phase,
+			}
+
+			fmt.Printf("[T1] %s: %s cache: min=%dns p50=%dns avg=%dns p95=%dns p99=%dns tput=%.0f ops/s\n",
+				r.Index, r.Cache, r.MinNs, r.P50Ns, r.AvgNs, r.P95Ns, r.P99Ns, r.OpsPerSec)
+			fmt.Printf("[T1] %s: %s\n", r.Index, r.IO)
+
+			_ = w.Write(append(append([]string{
+				r.Index,
+				strconv.Itoa(r.NDataset),
+				strconv.Itoa(r.NQueries),
+				strconv.FormatInt(r.MinNs, 10),
+				strconv.FormatInt(r.Q1Ns, 10),
+				strconv.FormatInt(r.P50Ns, 10),
+				strconv.FormatInt(r.Q3Ns, 10),
+				strconv.FormatInt(r.MaxNs, 10),
+				strconv.FormatInt(r.AvgNs, 10),
+				strconv.FormatInt(r.P95Ns, 10),
+				strconv.FormatInt(r.P99Ns, 10),
+				strconv.FormatFloat(r.OpsPerSec, 'f', 2, 64),
+				strconv.FormatInt(r.TotalMs, 10),
+			}, r.IO.row()...), r.Cache))
+			op := "get"
+			if r.Cache == CacheCold {
+				op = "get_cold"
+			}
+			writeLatency(lw, r.Index, op, &hist)
+		}
+		if idx == nil {
+			if cfg.CleanupData {
+				cleanupIndexData(idxPath)
 			}
+			continue
 		}
 
-		totalDuration := time.Since(start)
+		space = append(space, measureSpace("T1", def.Name, PhaseWorkload, idx, idxPath, ds.Bytes(), nil))
 		_ = idx.Close()
 
 		if cfg.CleanupData {
 			cleanupIndexData(idxPath)
 		}
-
-		sort.Slice(responetimes, func(i, j int) bool { return responetimes[i] < responetimes[j] })
-
-		r := T1Result{
-			Index:     def.Name,
-			NDataset:  cfg.DatasetSize,
-			NQueries:  cfg.PointQueryCount,
-			MinNs:     responetimes[0],
-			Q1Ns:      pct(responetimes, 25),
-			P50Ns:     pct(responetimes, 50),
-			Q3Ns:      pct(responetimes, 75),
-			MaxNs:     responetimes[len(responetimes)-1],
-			AvgNs:     avg(responetimes),
-			P95Ns:     pct(responetimes, 95),
-			P99Ns:     pct(responetimes, 99),
-			OpsPerSec: float64(cfg.PointQueryCount) / totalDuration.Seconds(),
-			TotalMs:   totalDuration.Milliseconds(),
-		}
-
-		fmt.Printf("[T1] %s: min=%dns p50=%dns avg=%dns p95=%dns p99=%dns tput=%.0f ops/s\n",
-			r.Index, r.MinNs, r.P50Ns, r.AvgNs, r.P95Ns, r.P99Ns, r.OpsPerSec)
-
-		_ = w.Write([]string{
-			r.Index,
-			strconv.Itoa(r.NDataset),
-			strconv.Itoa(r.NQueries),
-			strconv.FormatInt(r.MinNs, 10),
-			strconv.FormatInt(r.Q1Ns, 10),
-			strconv.FormatInt(r.P50Ns, 10),
-			strconv.FormatInt(r.Q3Ns, 10),
-			strconv.FormatInt(r.MaxNs, 10),
-			strconv.FormatInt(r.AvgNs, 10),
-			strconv.FormatInt(r.P95Ns, 10),
-			strconv.FormatInt(r.P99Ns, 10),
-			strconv.FormatFloat(r.OpsPerSec, 'f', 2, 64),
-			strconv.FormatInt(r.TotalMs, 10),
-		})
 	}
 
 	fmt.Printf("[T1] results written to %s\n", filepath.Join(cfg.OutDir, "t1_point_query.csv"))
-	return nil
+	if err := writeStructureResults(cfg.OutDir, "T1", shapes); err != nil {
+		return err
+	}
+	return writeSpaceResults(cfg.OutDir, "T1", space)
 }
 
 type T2Result struct {
@@ -188,18 +279,28 @@ type T2Result struct {
 	KeysRead  int
 	TotalMs   int64
 	OpsPerSec float64
+	IO        IOResult
+	Cache     string // CacheWarm or CacheCold
 }
 
-var t2Header = []string{
+var t2Header = append(append([]string{
 	"index", "range_size", "keys_read",
 	"total_ms", "ops_per_sec",
-}
+}, ioHeader...), "cache")
 
 // RunBenchmarkT2 executes the range query benchmark (T2).
 // It fills each index and measures the performance of scanning various range sizes.
 func RunBenchmarkT2(indices []IndexDef, cfg Config) error {
-	ds := NewDataset(cfg.DatasetSize, cfg.ValueSize, cfg.Seed)
+	ds, err := cfg.dataset()
+	if err != nil {
+		return fmt.Errorf("T2: %w", err)
+	}
 	sortedKeys := ds.SortedKeys()
+	phases, err := cfg.cachePhases()
+	if err != nil {
+		return fmt.Errorf("T2: %w", err)
+	}
+	warmKeys, _ := NewKeyChooser(DefaultKeyDist(DistUniform), cfg.Seed+5)
 
 	if err := os.MkdirAll(cfg.OutDir, 0755); err != nil {
 		return fmt.Errorf("create out dir: %w", err)
@@ -214,6 +315,19 @@ func RunBenchmarkT2(indices []IndexDef, cfg Config) error {
 	defer w.Flush()
 	_ = w.Write(t2Header)
 
+	ts, err := newTimeseries(cfg, "t2_range_query.csv")
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	rec, err := newTraceRecorder(cfg, "t2_range_query.csv")
+	if err != nil {
+		return fmt.Errorf("T2: %w", err)
+	}
+	defer rec.Close()
+
+	var space []SpaceResult
+	var shapes []StructureResult
 	for _, def := range indices {
 		idxPath := filepath.Join(cfg.DataDir, def.Name+"_t2")
 		idx, err := def.NewFunc(idxPath)
@@ -222,7 +336,8 @@ func RunBenchmarkT2(indices []IndexDef, cfg Config) error {
 			continue
 		}
 
-		if err := fillIndex(idx, *ds); err != nil {
+		tr := rec.forIndex(def.Name)
+		if err := fillIndex(idx, *ds, ts.sampler(def.Name, PhaseFill, idx), tr); err != nil {
 			fmt.Printf("[T2] %s: fill failed: %v — skipping\n", def.Name, err)
 			_ = idx.Close()
 			if cfg.CleanupData {
@@ -231,64 +346,48 @@ func RunBenchmarkT2(indices []IndexDef, cfg Config) error {
 			continue
 		}
 
-		if h, ok := idx.(interface{ Height() int }); ok {
-			leaves := 0
-			if l, ok := idx.(interface{ CountLeaves() int }); ok {
-				leaves = l.CountLeaves()
-			}
-			fmt.Printf("[T2] %s: tree height = %d, leaves = %d\n", def.Name, h.Height(), leaves)
+		shape, ok := logIndexShape("T2", def.Name, idx)
+		if ok {
+			shapes = append(shapes, shape)
 		}
+		space = append(space, measureSpace("T2", def.Name, PhaseFill, idx, idxPath, ds.Bytes(), shape.Tree))
 
 		var t2Sizes []int
 		for s := cfg.T2StartSize; s <= cfg.T2MaxSize && s <= len(sortedKeys); s *= 2 {
 			t2Sizes = append(t2Sizes, s)
 		}
 
-		for _, size := range t2Sizes {
-			mid := (len(sortedKeys) - size) / 2
-			startKey := sortedKeys[mid]
-			endKey := sortedKeys[mid+size-1]
-
-			fmt.Printf("[T2] %s: size=%d scanning [%d, %d]...\n", def.Name, size, startKey, endKey)
-
-			start := time.Now()
-			it, err := idx.Range(startKey, endKey)
-			if err != nil {
-				fmt.Printf("[T2] %s: Range() error: %v\n", def.Name, err)
-				continue
+	phases:
+		for i, phase := range phases {
+			if i > 0 {
+				tr = nil // the scans repeat those of the first phase
 			}
-
-			keysRead := 0
-			for it.Next() {
-				keysRead++
+			if phase == CacheWarm {
+				warmUp("T2", def.Name, idx, sortedKeys, warmKeys, cfg.Warmup)
 			}
-			if err := it.Error(); err != nil {
-				fmt.Printf("[T2] %s: iterator error: %v\n", def.Name, err)
+			for _, size := range t2Sizes {
+				// Every cold scan starts from a freshly opened index.
+				if phase == CacheCold {
+					if idx, err = reopenCold(def, idx, idxPath, cfg.DropOSCache); err != nil {
+						fmt.Printf("[T2] %s: cold cache: %v — skipping\n", def.Name, err)
+						if idx == nil {
+							break phases
+						}
+						continue phases
+					}
+				}
+				samp := ts.sampler(def.Name, fmt.Sprintf("%d_%s", size, phase), idx)
+				writeT2Scan(w, samp, tr, def.Name, idx, idxPath, sortedKeys, size, phase, ds.Bytes())
 			}
-			it.Close()
-
-			totalDuration := time.Since(start)
-
-			r := T2Result{
-				Index:     def.Name,
-				RangeSize: size,
-				KeysRead:  keysRead,
-				TotalMs:   totalDuration.Microseconds(),
-				OpsPerSec: float64(keysRead) / totalDuration.Seconds(),
+		}
+		if idx == nil {
+			if cfg.CleanupData {
+				cleanupIndexData(idxPath)
 			}
-
-			fmt.Printf("[T2] %s: size=%d keys_read=%d total=%dµs tput=%.0f keys/s\n",
-				r.Index, r.RangeSize, r.KeysRead, r.TotalMs, r.OpsPerSec)
-
-			_ = w.Write([]string{
-				r.Index,
-				strconv.Itoa(r.RangeSize),
-				strconv.Itoa(r.KeysRead),
-				strconv.FormatInt(r.TotalMs, 10),
-				strconv.FormatFloat(r.OpsPerSec, 'f', 2, 64),
-			})
+			continue
 		}
 
+		space = append(space, measureSpace("T2", def.Name, PhaseWorkload, idx, idxPath, ds.Bytes(), nil))
 		_ = idx.Close()
 		if cfg.CleanupData {
 			cleanupIndexData(idxPath)
@@ -296,7 +395,74 @@ func RunBenchmarkT2(indices []IndexDef, cfg Config) error {
 	}
 
 	fmt.Printf("[T2] results written to %s\n", filepath.Join(cfg.OutDir, "t2_range_query.csv"))
-	return nil
+	if err := writeStructureResults(cfg.OutDir, "T2", shapes); err != nil {
+		return err
+	}
+	return writeSpaceResults(cfg.OutDir, "T2", space)
+}
+
+// t2SampleBatch is the number of keys of a T2 scan recorded together in
+// its time series.
+const t2SampleBatch = 256
+
+// writeT2Scan scans the size keys in the middle of sortedKeys and writes the
+// result row. dataBytes is the logical size of the indexed data.
+func writeT2Scan(w *csv.Writer, samp *sampler, tr *traceRecorder, name string, idx index.Index, idxPath string, sortedKeys []int64, size int, cache string, dataBytes int64) {
+	mid := (len(sortedKeys) - size) / 2
+	startKey := sortedKeys[mid]
+	endKey := sortedKeys[mid+size-1]
+
+	fmt.Printf("[T2] %s: size=%d scanning [%d, %d] (%s cache)...\n", name, size, startKey, endKey, cache)
+
+	tr.record(TraceOp{Op: TraceRange, Key: startKey, End: endKey})
+	meter := newIOMeter(idx, idxPath)
+	start := time.Now()
+	it, err := idx.Range(startKey, endKey)
+	if err != nil {
+		fmt.Printf("[T2] %s: Range() error: %v\n", name, err)
+		return
+	}
+
+	keysRead := 0
+	batchStart := start
+	for it.Next() {
+		keysRead++
+		meter.read(it.Value())
+		if keysRead%t2SampleBatch == 0 {
+			now := time.Now()
+			samp.recordBatch(t2SampleBatch, now.Sub(batchStart).Nanoseconds())
+			batchStart = now
+		}
+	}
+	samp.recordBatch(keysRead%t2SampleBatch, time.Since(batchStart).Nanoseconds())
+	samp.flush()
+	if err := it.Error(); err != nil {
+		fmt.Printf("[T2] %s: iterator error: %v\n", name, err)
+	}
+	it.Close()
+
+	totalDuration := time.Since(start)
+
+	r := T2Result{
+		Index:     name,
+		RangeSize: size,
+		KeysRead:  keysRead,
+		TotalMs:   totalDuration.Microseconds(),
+		OpsPerSec: float64(keysRead) / totalDuration.Seconds(),
+		IO:        meter.result(dataBytes),
+		Cache:     cache,
+	}
+
+	fmt.Printf("[T2] %s: size=%d keys_read=%d total=%dµs tput=%.0f keys/s\n",
+		r.Index, r.RangeSize, r.KeysRead, r.TotalMs, r.OpsPerSec)
+
+	_ = w.Write(append(append([]string{
+		r.Index,
+		strconv.Itoa(r.RangeSize),
+		strconv.Itoa(r.KeysRead),
+		strconv.FormatInt(r.TotalMs, 10),
+		strconv.FormatFloat(r.OpsPerSec, 'f', 2, 64),
+	}, r.IO.row()...), r.Cache))
 }
 
 type T3Result struct {
@@ -304,11 +470,12 @@ type T3Result struct {
 	OpCount       int
 	OpsPerSec     float64
 	CumulativeOps int
+	IO            IOResult // since the start of the test, not just the window
 }
 
-var t3Header = []string{
+var t3Header = append([]string{
 	"index", "op_count", "ops_per_sec", "cumulative_ops",
-}
+}, ioHeader...)
 
 // RunBenchmarkT3 executes the write throughput benchmark (T3).
 // It measures how quickly each index can ingest new random key-value pairs.
@@ -326,8 +493,20 @@ func RunBenchmarkT3(indices []IndexDef, cfg Config) error {
 	defer w.Flush()
 	_ = w.Write(t3Header)
 
+	ts, err := newTimeseries(cfg, "t3_write_throughput.csv")
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	rec, err := newTraceRecorder(cfg, "t3_write_throughput.csv")
+	if err != nil {
+		return fmt.Errorf("T3: %w", err)
+	}
+	defer rec.Close()
+
 	rng := rand.New(rand.NewSource(cfg.Seed))
 
+	var space []SpaceResult
 	for _, def := range indices {
 		fmt.Printf("[T3] %s: running write throughput...\n", def.Name)
 
@@ -341,6 +520,9 @@ func RunBenchmarkT3(indices []IndexDef, cfg Config) error {
 			s.SetSyncInterval(500)
 		}
 
+		meter := newIOMeter(idx, idxPath)
+		samp := ts.sampler(def.Name, PhaseWorkload, idx)
+		tr := rec.forIndex(def.Name)
 		windowStart := time.Now()
 		windowOps := 0
 
@@ -349,9 +531,13 @@ func RunBenchmarkT3(indices []IndexDef, cfg Config) error {
 			val := make([]byte, cfg.ValueSize)
 			rng.Read(val)
 
+			tr.record(TraceOp{Op: TraceInsert, Key: key, ValueSize: len(val)})
+			t := time.Now()
 			if err := idx.Insert(key, val); err != nil {
 				break
 			}
+			samp.record(time.Since(t).Nanoseconds())
+			meter.wrote(val)
 
 			windowOps++
 
@@ -359,24 +545,29 @@ func RunBenchmarkT3(indices []IndexDef, cfg Config) error {
 				duration := time.Since(windowStart).Seconds()
 				opsPerSec := float64(windowOps) / duration
 
-				_ = w.Write([]string{
+				// Keys are random 63-bit numbers, so (almost) every insert adds an entry.
+				io := meter.result(meter.written)
+				_ = w.Write(append([]string{
 					def.Name,
 					strconv.Itoa(i + 1),
 					fmt.Sprintf("%.2f", opsPerSec),
 					strconv.Itoa(i + 1),
-				})
+				}, io.row()...))
 
 				windowStart = time.Now()
 				windowOps = 0
 			}
 
 		}
+		samp.flush()
+		// T3 starts from an empty index, so there is no fill to measure.
+		space = append(space, measureSpace("T3", def.Name, PhaseWorkload, idx, idxPath, meter.written, nil))
 		_ = idx.Close()
 		if cfg.CleanupData {
 			cleanupIndexData(idxPath)
 		}
 	}
-	return nil
+	return writeSpaceResults(cfg.OutDir, "T3", space)
 }
 
 type MixedSummaryResult struct {
@@ -384,167 +575,73 @@ type MixedSummaryResult struct {
 	OpType    string
 	Count     int
 	MinNs     int64
+	Q1Ns      int64
 	P50Ns     int64
+	Q3Ns      int64
+	MaxNs     int64
+	AvgNs     int64
 	P95Ns     int64
 	P99Ns     int64
-	AvgNs     int64
 	OpsPerSec float64
-}
+	IO        IOResult // of the whole workload, repeated on every op type's row
 
-var mixedSummaryHeader = []string{
-	"index", "op_type", "count", "min_ns", "p50_ns", "p95_ns", "p99_ns", "avg_ns", "ops_per_sec",
+	TargetOpsPerSec float64 // open-loop target rate (see Config.TargetRate), 0 for a closed loop
 }
 
-// RunMixedWorkload executes a benchmark with a mix of read and write operations.
-func RunMixedWorkload(indices []IndexDef, cfg Config, readPercent int, testLabel string, fileName string) error {
-	// Detailed log file
-	f, err := os.Create(filepath.Join(cfg.OutDir, fileName))
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	w := csv.NewWriter(f)
-	defer w.Flush()
-	_ = w.Write([]string{"index", "op_count", "responetime_ns", "type"})
-
-	// Summary file
-	sumFile, err := os.Create(filepath.Join(cfg.OutDir, fileName[:len(fileName)-len(".csv")]+"_summary.csv"))
-	if err != nil {
-		return err
-	}
-	defer sumFile.Close()
-	sw := csv.NewWriter(sumFile)
-	defer sw.Flush()
-	_ = sw.Write(mixedSummaryHeader)
-
-	for _, def := range indices {
-		fmt.Printf("[%s] %s: Starting %d/%d workload...\n", testLabel, def.Name, readPercent, 100-readPercent)
-
-		idxPath := filepath.Join(cfg.DataDir, def.Name+"_"+testLabel)
-		idx, err := def.NewFunc(idxPath)
-		if err != nil {
-			continue
-		}
-
-		ds := NewDataset(cfg.DatasetSize, cfg.ValueSize, cfg.Seed)
-		if err := fillIndex(idx, *ds); err != nil {
-			fmt.Printf("[%s] %s: fill failed: %v — skipping\n", testLabel, def.Name, err)
-			_ = idx.Close()
-			if cfg.CleanupData {
-				cleanupIndexData(idxPath)
-			}
-			continue
-		}
-
-		// Re-enable sync for the actual benchmark workload.
-		if s, ok := idx.(interface{ SetSyncInterval(int) }); ok {
-			s.SetSyncInterval(500)
-		}
-
-		rng := rand.New(rand.NewSource(cfg.Seed + 2))
-
-		readTimes := make([]int64, 0, cfg.MixedOpsTotal)
-		writeTimes := make([]int64, 0, cfg.MixedOpsTotal)
-		startTotal := time.Now()
-
-		for i := 0; i < cfg.MixedOpsTotal; i++ {
-			decision := rng.Intn(100)
-
-			if decision < readPercent {
-				// READ
-				key := ds.Keys[rng.Intn(len(ds.Keys))]
-				start := time.Now()
-				_, _ = idx.Get(key)
-				responetime := time.Since(start).Nanoseconds()
-				readTimes = append(readTimes, responetime)
-
-				if i%cfg.LogInterval == 0 {
-					_ = w.Write([]string{def.Name, strconv.Itoa(i), strconv.FormatInt(responetime, 10), "read"})
-				}
-			} else {
-				// WRITE
-				newKey := rng.Int63()
-				val := make([]byte, cfg.ValueSize)
-				rng.Read(val)
-
-				start := time.Now()
-				_ = idx.Insert(newKey, val)
-				responetime := time.Since(start).Nanoseconds()
-				writeTimes = append(writeTimes, responetime)
-
-				if i%cfg.LogInterval == 0 {
-					_ = w.Write([]string{def.Name, strconv.Itoa(i), strconv.FormatInt(responetime, 10), "write"})
-				}
-			}
-		}
-		durationTotal := time.Since(startTotal)
-		_ = idx.Close()
-
-		// Calculate and Write Summaries
-		processStats := func(times []int64, opType string) {
-			if len(times) == 0 {
-				return
-			}
-			sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
-			r := MixedSummaryResult{
-				Index:     def.Name,
-				OpType:    opType,
-				Count:     len(times),
-				MinNs:     times[0],
-				P50Ns:     pct(times, 50),
-				P95Ns:     pct(times, 95),
-				P99Ns:     pct(times, 99),
-				AvgNs:     avg(times),
-				OpsPerSec: float64(len(times)) / durationTotal.Seconds(),
-			}
-
-			fmt.Printf("[%s] %s %-5s: count=%-6d avg=%-8dns p50=%-8dns p95=%-8dns tput=%-8.0f ops/s\n",
-				testLabel, r.Index, r.OpType, r.Count, r.AvgNs, r.P50Ns, r.P95Ns, r.OpsPerSec)
-
-			_ = sw.Write([]string{
-				r.Index, r.OpType, strconv.Itoa(r.Count),
-				strconv.FormatInt(r.MinNs, 10), strconv.FormatInt(r.P50Ns, 10),
-				strconv.FormatInt(r.P95Ns, 10), strconv.FormatInt(r.P99Ns, 10),
-				strconv.FormatInt(r.AvgNs, 10), strconv.FormatFloat(r.OpsPerSec, 'f', 2, 64),
-			})
-		}
-
-		processStats(readTimes, "read")
-		processStats(writeTimes, "write")
+var mixedSummaryHeader = append(append([]string{
+	"index", "op_type", "count",
+	"min_ns", "q1_ns", "p50_ns", "q3_ns", "max_ns",
+	"avg_ns", "p95_ns", "p99_ns",
+	"ops_per_sec",
+}, ioHeader...), "target_ops_per_sec")
+
+// row returns the summary as a record of mixedSummaryHeader.
+func (r MixedSummaryResult) row() []string {
+	return append([]string{
+		r.Index, r.OpType, strconv.Itoa(r.Count),
+		strconv.FormatInt(r.MinNs, 10), strconv.FormatInt(r.Q1Ns, 10), strconv.FormatInt(r.P50Ns, 10),
+		strconv.FormatInt(r.Q3Ns, 10), strconv.FormatInt(r.MaxNs, 10),
+		strconv.FormatInt(r.AvgNs, 10), strconv.FormatInt(r.P95Ns, 10), strconv.FormatInt(r.P99Ns, 10),
+		strconv.FormatFloat(r.OpsPerSec, 'f', 2, 64),
+	}, append(r.IO.row(),
+		strconv.FormatFloat(r.TargetOpsPerSec, 'f', -1, 64),
+	)...)
+}
 
-		if cfg.CleanupData {
-			cleanupIndexData(idxPath)
-		}
-	}
-	return nil
+// RunMixedWorkload executes a benchmark with the given mix of operations.
+// Existing keys are picked with cfg.MixedDist; new keys are random.
+func RunMixedWorkload(indices []IndexDef, cfg Config, mix OpMix, testLabel string, fileName string) error {
+	return runMixed(indices, cfg, mixedRun{
+		label:    testLabel,
+		fileName: fileName,
+		dataName: testLabel,
+		mix:      mix,
+		dist:     cfg.keyDist(cfg.MixedDist),
+	})
 }
 
+// Default operation mixes of the T4 and T5 workloads.
+var (
+	DefaultT4Mix = OpMix{Read: 95, Insert: 5}
+	DefaultT5Mix = OpMix{Read: 5, Insert: 95}
+)
+
 // RunBenchmarkT4 executes the read-heavy mixed workload benchmark (T4).
-// It uses a 95% read / 5% write ratio.
+// It uses cfg.T4Mix, by default 95% reads and 5% inserts.
 func RunBenchmarkT4(indices []IndexDef, cfg Config) error {
-	return RunMixedWorkload(indices, cfg, 95, "T4", "t4_read_heavy.csv")
+	mix := cfg.T4Mix
+	if mix == (OpMix{}) {
+		mix = DefaultT4Mix
+	}
+	return RunMixedWorkload(indices, cfg, mix, "T4", "t4_read_heavy.csv")
 }
 
 // RunBenchmarkT5 executes the write-heavy mixed workload benchmark (T5).
-// It uses a 5% read / 95% write ratio.
+// It uses cfg.T5Mix, by default 5% reads and 95% inserts.
 func RunBenchmarkT5(indices []IndexDef, cfg Config) error {
-	return RunMixedWorkload(indices, cfg, 5, "T5", "t5_write_heavy.csv")
-}
-
-//---
-
-func avg(responetimes []int64) int64 {
-	var sum int64
-	for _, v := range responetimes {
-		sum += v
-	}
-	return sum / int64(len(responetimes))
-}
-
-func pct(responetimes []int64, p int) int64 {
-	i := (p * len(responetimes)) / 100
-	if i >= len(responetimes) {
-		i = len(responetimes) - 1
+	mix := cfg.T5Mix
+	if mix == (OpMix{}) {
+		mix = DefaultT5Mix
 	}
-	return responetimes[i]
+	return RunMixedWorkload(indices, cfg, mix, "T5", "t5_write_heavy.csv")
 }
@@ -0,0 +1,238 @@
+package bench
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// timeseriesHeader lists the columns of the <test>_timeseries.csv files: one
+// row per sampling interval with the operations finished in it, their
+// latencies, and the structural events of the index (see index.Events).
+var timeseriesHeader = []string{
+	"index", "phase", "elapsed_ms", "ops", "ops_per_sec", "p50_ns", "p99_ns", "max_ns",
+	"splits", "root_splits", "flushes", "compactions", "write_stalls",
+}
+
+// eventColumns are the columns of timeseriesHeader counting index.Events.
+var eventColumns = []string{"splits", "root_splits", "flushes", "compactions", "write_stalls"}
+
+// timeseries is the time series CSV of one test. A nil *timeseries, as
+// returned while sampling is off, hands out nil samplers.
+type timeseries struct {
+	f        *os.File
+	w        *csv.Writer
+	interval time.Duration
+}
+
+// timeseriesFile returns the time series file that goes with the result
+// CSV resultFile, e.g. t4_read_heavy_timeseries.csv.
+func timeseriesFile(resultFile string) string {
+	return strings.TrimSuffix(resultFile, ".csv") + "_timeseries.csv"
+}
+
+// newTimeseries creates the time series CSV of the result file resultFile
+// in cfg.OutDir, or returns nil if cfg.SampleInterval is 0.
+func newTimeseries(cfg Config, resultFile string) (*timeseries, error) {
+	if cfg.SampleInterval <= 0 {
+		return nil, nil
+	}
+	f, err := os.Create(filepath.Join(cfg.OutDir, timeseriesFile(resultFile)))
+	if err != nil {
+		return nil, fmt.Errorf("create time series csv: %w", err)
+	}
+	ts := &timeseries{f: f, w: csv.NewWriter(f), interval: time.Duration(cfg.SampleInterval) * time.Millisecond}
+	_ = ts.w.Write(timeseriesHeader)
+	return ts, nil
+}
+
+// Close flushes and closes the file.
+func (ts *timeseries) Close() error {
+	if ts == nil {
+		return nil
+	}
+	ts.w.Flush()
+	if err := ts.w.Error(); err != nil {
+		ts.f.Close()
+		return err
+	}
+	return ts.f.Close()
+}
+
+// sampler returns a sampler for one phase of index name, starting now.
+func (ts *timeseries) sampler(name, phase string, idx index.Index) *sampler {
+	if ts == nil {
+		return nil
+	}
+	now := time.Now()
+	return &sampler{ts: ts, name: name, phase: phase, idx: idx, start: now, last: now, events: indexEvents(idx)}
+}
+
+func indexEvents(idx index.Index) index.Events {
+	if r, ok := idx.(index.EventReporter); ok {
+		return r.Events()
+	}
+	return index.Events{}
+}
+
+// sampler collects the operations of one phase of a test on one index and
+// writes a row per interval. All methods do nothing on a nil *sampler.
+type sampler struct {
+	ts          *timeseries
+	name, phase string
+	idx         index.Index
+	start, last time.Time
+	events      index.Events // at last
+	ops         int
+	calls       int // record calls, to read the clock only every sampleCheck calls
+	window      Histogram
+}
+
+// sampleCheck is how many record calls a sampler lets pass between looks at
+// the clock, which keeps its cost per operation low.
+const sampleCheck = 16
+
+// record adds an operation that took ns nanoseconds.
+func (s *sampler) record(ns int64) {
+	s.recordBatch(1, ns)
+}
+
+// recordBatch adds n operations that took ns nanoseconds together, e.g. the
+// keys of a scan; their latency is the average.
+func (s *sampler) recordBatch(n int, ns int64) {
+	if s == nil || n <= 0 {
+		return
+	}
+	s.ops += n
+	s.window.Record(ns / int64(n))
+	if s.calls++; s.calls%sampleCheck != 0 {
+		return
+	}
+	if now := time.Now(); now.Sub(s.last) >= s.ts.interval {
+		s.emit(now)
+	}
+}
+
+// flush writes the row of the last, partial interval.
+func (s *sampler) flush() {
+	if s == nil || s.ops == 0 {
+		return
+	}
+	s.emit(time.Now())
+}
+
+func (s *sampler) emit(now time.Time) {
+	events := indexEvents(s.idx)
+	e := events.Sub(s.events)
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	_ = s.ts.w.Write([]string{
+		s.name, s.phase,
+		strconv.FormatFloat(float64(now.Sub(s.start).Microseconds())/1000, 'f', 1, 64),
+		strconv.Itoa(s.ops),
+		strconv.FormatFloat(float64(s.ops)/now.Sub(s.last).Seconds(), 'f', 2, 64),
+		strconv.FormatInt(s.window.Percentile(50), 10),
+		strconv.FormatInt(s.window.Percentile(99), 10),
+		strconv.FormatInt(s.window.Max(), 10),
+		u(e.Splits), u(e.RootSplits), u(e.Flushes), u(e.Compactions), u(e.WriteStalls),
+	})
+	s.last, s.events, s.ops, s.window = now, events, 0, Histogram{}
+}
+
+// timeseriesCharts returns line charts of the time series CSV records (see
+// timeseriesHeader): throughput and p99 over time with a line per index, and
+// the index events per interval with a line per index and kind of event that
+// occurred, leaving out root splits, which are also counted as splits and too
+// rare to show. The phases of an index follow each other on the time axis.
+func timeseriesCharts(records [][]string, title string) []*charts.Line {
+	if len(records) < 2 {
+		return nil
+	}
+	header := records[0]
+	elapsedCol, opsCol, p99Col := column(header, "elapsed_ms"), column(header, "ops_per_sec"), column(header, "p99_ns")
+	var indexOrder []string
+	tputs := make(map[string][]opts.LineData)
+	p99s := make(map[string][]opts.LineData)
+	events := make(map[string][]opts.LineData) // "<index> <event>"
+	eventTotals := make(map[string]uint64)
+	phase := make(map[string]string)
+	offset, last := make(map[string]float64), make(map[string]float64)
+	for _, rec := range records[1:] {
+		name := rec[0]
+		if _, ok := phase[name]; !ok {
+			indexOrder = append(indexOrder, name)
+		} else if phase[name] != rec[1] {
+			offset[name] += last[name]
+		}
+		phase[name] = rec[1]
+		elapsed, _ := strconv.ParseFloat(rec[elapsedCol], 64)
+		last[name] = elapsed
+		secs := (offset[name] + elapsed) / 1000
+		tput, _ := strconv.ParseFloat(rec[opsCol], 64)
+		p99, _ := strconv.ParseFloat(rec[p99Col], 64)
+		tputs[name] = append(tputs[name], opts.LineData{Value: []interface{}{secs, tput}})
+		p99s[name] = append(p99s[name], opts.LineData{Value: []interface{}{secs, p99}})
+		for _, ev := range eventColumns {
+			n, _ := strconv.ParseUint(rec[column(header, ev)], 10, 64)
+			key := name + " " + ev
+			eventTotals[key] += n
+			events[key] = append(events[key], opts.LineData{Value: []interface{}{secs, n}})
+		}
+	}
+
+	newLine := func(subtitle, yName string) *charts.Line {
+		line := charts.NewLine()
+		line.SetGlobalOptions(
+			charts.WithTitleOpts(opts.Title{Title: title + " — " + subtitle}),
+			charts.WithXAxisOpts(opts.XAxis{Name: "s", Type: "value"}),
+			charts.WithYAxisOpts(opts.YAxis{Name: yName, Type: "value"}),
+			charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true), Trigger: "axis"}),
+			charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Top: "8%"}),
+			charts.WithInitializationOpts(opts.Initialization{Width: chartWidth, Height: chartHeight}),
+		)
+		return line
+	}
+	tputLine, p99Line := newLine("Throughput over Time", "Ops/sec"), newLine("P99 over Time", "ns")
+	counters := make(map[string]int)
+	for _, name := range indexOrder {
+		color := pickColor(name, counters)
+		for _, s := range []struct {
+			line *charts.Line
+			data []opts.LineData
+		}{{tputLine, tputs[name]}, {p99Line, p99s[name]}} {
+			s.line.AddSeries(name, s.data,
+				charts.WithLineChartOpts(opts.LineChart{ShowSymbol: opts.Bool(false)}),
+				charts.WithLineStyleOpts(opts.LineStyle{Color: color, Width: 2}),
+				charts.WithItemStyleOpts(opts.ItemStyle{Color: color}),
+			)
+		}
+	}
+	out := []*charts.Line{tputLine, p99Line}
+
+	eventLine := newLine("Index Events over Time", "Events per interval")
+	shown := false
+	for _, name := range indexOrder {
+		for _, ev := range eventColumns {
+			key := name + " " + ev
+			if ev == "root_splits" || eventTotals[key] == 0 {
+				continue
+			}
+			shown = true
+			eventLine.AddSeries(key, events[key],
+				charts.WithLineChartOpts(opts.LineChart{ShowSymbol: opts.Bool(false)}),
+				charts.WithLineStyleOpts(opts.LineStyle{Width: 1}),
+			)
+		}
+	}
+	if shown {
+		out = append(out, eventLine)
+	}
+	return out
+}
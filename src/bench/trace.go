@@ -0,0 +1,317 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Operations of a trace (see TraceOp).
+const (
+	TraceGet    = "get"
+	TraceInsert = "insert"
+	TraceDelete = "delete"
+	TraceRange  = "range"
+)
+
+// Trace file formats written by the recorder (see Config.TraceFormat). The
+// reader tells them apart by the binary format's magic number.
+const (
+	TraceBinary = "binary" // compact varint records, written to <test>.trace
+	TraceNDJSON = "ndjson" // one JSON object per line, written to <test>.ndjson
+)
+
+// TraceFormats lists the values of Config.TraceFormat.
+var TraceFormats = []string{TraceBinary, TraceNDJSON}
+
+// traceMagic starts every binary trace file; the last byte is the version.
+var traceMagic = []byte("BMTRACE\x01")
+
+// traceLoad marks a binary record as part of the load (see TraceOp.Load).
+const traceLoad = 0x80
+
+var traceOpCodes = map[string]byte{TraceGet: 1, TraceInsert: 2, TraceDelete: 3, TraceRange: 4}
+
+// TraceOp is one operation of a trace. In NDJSON it is written as e.g.
+// {"op":"insert","key":42,"value_size":128} or
+// {"op":"range","key":10,"end":9223372036854775807,"limit":50}.
+type TraceOp struct {
+	Op        string `json:"op"` // one of the Trace* operations
+	Key       int64  `json:"key"`
+	End       int64  `json:"end,omitempty"`        // range: last key, inclusive
+	Limit     int    `json:"limit,omitempty"`      // range: entries to read, 0 for all up to End
+	ValueSize int    `json:"value_size,omitempty"` // insert: bytes of the value
+	Load      bool   `json:"load,omitempty"`       // loads the index before the measured operations
+}
+
+// TraceWriter writes a trace file.
+type TraceWriter struct {
+	f      *os.File
+	w      *bufio.Writer
+	ndjson bool
+	buf    []byte
+	n      int
+	err    error
+}
+
+// NewTraceWriter creates the trace file path in the given format.
+func NewTraceWriter(path, format string) (*TraceWriter, error) {
+	switch format {
+	case TraceBinary, TraceNDJSON:
+	default:
+		return nil, fmt.Errorf("unknown trace format %q (want one of %v)", format, TraceFormats)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create trace: %w", err)
+	}
+	t := &TraceWriter{f: f, w: bufio.NewWriter(f), ndjson: format == TraceNDJSON}
+	if !t.ndjson {
+		_, t.err = t.w.Write(traceMagic)
+	}
+	return t, nil
+}
+
+// Write appends op to the trace. After a failed write, Write does nothing
+// and Close returns the error.
+func (t *TraceWriter) Write(op TraceOp) error {
+	if t.err != nil {
+		return t.err
+	}
+	if t.ndjson {
+		line, err := json.Marshal(op)
+		if err != nil {
+			t.err = err
+			return err
+		}
+		_, t.err = t.w.Write(append(line, '\n'))
+	} else {
+		code, ok := traceOpCodes[op.Op]
+		if !ok {
+			t.err = fmt.Errorf("unknown trace operation %q", op.Op)
+			return t.err
+		}
+		if op.Load {
+			code |= traceLoad
+		}
+		b := append(t.buf[:0], code)
+		b = binary.AppendVarint(b, op.Key)
+		switch op.Op {
+		case TraceInsert:
+			b = binary.AppendUvarint(b, uint64(op.ValueSize))
+		case TraceRange:
+			b = binary.AppendVarint(b, op.End)
+			b = binary.AppendUvarint(b, uint64(op.Limit))
+		}
+		t.buf = b
+		_, t.err = t.w.Write(b)
+	}
+	if t.err == nil {
+		t.n++
+	}
+	return t.err
+}
+
+// Count returns the number of operations written.
+func (t *TraceWriter) Count() int { return t.n }
+
+// Close flushes and closes the file.
+func (t *TraceWriter) Close() error {
+	if t.err == nil {
+		t.err = t.w.Flush()
+	}
+	if err := t.f.Close(); t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+// TraceReader reads a trace file in either format.
+type TraceReader struct {
+	f   *os.File
+	r   *bufio.Reader
+	dec *json.Decoder // nil for a binary trace
+	n   int
+}
+
+// OpenTrace opens the trace file path.
+func OpenTrace(path string) (*TraceReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open trace: %w", err)
+	}
+	t := &TraceReader{f: f, r: bufio.NewReader(f)}
+	if magic, _ := t.r.Peek(len(traceMagic)); bytes.Equal(magic, traceMagic) {
+		_, _ = t.r.Discard(len(traceMagic))
+	} else {
+		t.dec = json.NewDecoder(t.r)
+		t.dec.DisallowUnknownFields()
+	}
+	return t, nil
+}
+
+// Next returns the next operation, or io.EOF after the last one.
+func (t *TraceReader) Next() (TraceOp, error) {
+	op, err := t.next()
+	if err != nil && err != io.EOF {
+		return TraceOp{}, fmt.Errorf("trace %s: operation %d: %w", t.f.Name(), t.n+1, err)
+	}
+	if err == nil {
+		t.n++
+	}
+	return op, err
+}
+
+func (t *TraceReader) next() (TraceOp, error) {
+	var op TraceOp
+	if t.dec != nil {
+		if err := t.dec.Decode(&op); err != nil {
+			return TraceOp{}, err
+		}
+		if _, ok := traceOpCodes[op.Op]; !ok {
+			return TraceOp{}, fmt.Errorf("unknown operation %q", op.Op)
+		}
+		return op, nil
+	}
+
+	code, err := t.r.ReadByte()
+	if err != nil {
+		return TraceOp{}, err // io.EOF between records
+	}
+	op.Load = code&traceLoad != 0
+	code &^= traceLoad
+	for name, c := range traceOpCodes {
+		if c == code {
+			op.Op = name
+		}
+	}
+	if op.Op == "" {
+		return TraceOp{}, fmt.Errorf("unknown operation code %d", code)
+	}
+	if op.Key, err = binary.ReadVarint(t.r); err != nil {
+		return TraceOp{}, truncated(err)
+	}
+	switch op.Op {
+	case TraceInsert:
+		size, err := binary.ReadUvarint(t.r)
+		if err != nil {
+			return TraceOp{}, truncated(err)
+		}
+		op.ValueSize = int(size)
+	case TraceRange:
+		if op.End, err = binary.ReadVarint(t.r); err != nil {
+			return TraceOp{}, truncated(err)
+		}
+		limit, err := binary.ReadUvarint(t.r)
+		if err != nil {
+			return TraceOp{}, truncated(err)
+		}
+		op.Limit = int(limit)
+	}
+	return op, nil
+}
+
+// truncated turns the io.EOF of a record cut short into an error.
+func truncated(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Close closes the file.
+func (t *TraceReader) Close() error { return t.f.Close() }
+
+// ReadTrace reads all operations of the trace file path.
+func ReadTrace(path string) ([]TraceOp, error) {
+	t, err := OpenTrace(path)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+	var ops []TraceOp
+	for {
+		op, err := t.Next()
+		if errors.Is(err, io.EOF) {
+			return ops, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+}
+
+// traceRecorder records the operations a test issues on its first index
+// into a trace file in cfg.RecordTrace. Every index sees the same
+// operations, so one is enough. All methods do nothing on a nil
+// *traceRecorder, as returned while recording is off.
+type traceRecorder struct {
+	w     *TraceWriter
+	path  string
+	index string // the index being recorded
+}
+
+// newTraceRecorder creates the trace of the test writing the result file
+// resultFile, e.g. t4_read_heavy.trace, or returns nil if cfg.RecordTrace is
+// empty.
+func newTraceRecorder(cfg Config, resultFile string) (*traceRecorder, error) {
+	if cfg.RecordTrace == "" {
+		return nil, nil
+	}
+	format := cfg.TraceFormat
+	if format == "" {
+		format = TraceBinary
+	}
+	ext := ".trace"
+	if format == TraceNDJSON {
+		ext = ".ndjson"
+	}
+	if err := os.MkdirAll(cfg.RecordTrace, 0755); err != nil {
+		return nil, fmt.Errorf("create trace dir: %w", err)
+	}
+	path := filepath.Join(cfg.RecordTrace, strings.TrimSuffix(resultFile, ".csv")+ext)
+	w, err := NewTraceWriter(path, format)
+	if err != nil {
+		return nil, err
+	}
+	return &traceRecorder{w: w, path: path}, nil
+}
+
+// forIndex returns the recorder if the index called name is the one being
+// recorded, which the first to ask becomes, and nil otherwise.
+func (r *traceRecorder) forIndex(name string) *traceRecorder {
+	if r == nil || r.index != "" && r.index != name {
+		return nil
+	}
+	r.index = name
+	return r
+}
+
+// record appends op to the trace.
+func (r *traceRecorder) record(op TraceOp) {
+	if r == nil {
+		return
+	}
+	_ = r.w.Write(op)
+}
+
+// Close finishes the trace file and reports where it went.
+func (r *traceRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	if err := r.w.Close(); err != nil {
+		fmt.Printf("[Trace] writing %s failed: %v\n", r.path, err)
+		return err
+	}
+	fmt.Printf("[Trace] %d operations on %s written to %s\n", r.w.Count(), r.index, r.path)
+	return nil
+}
@@ -0,0 +1,91 @@
+package bench_test
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/btree-query-bench/bmark/bench"
+)
+
+var traceOps = []bench.TraceOp{
+	{Op: bench.TraceInsert, Key: 1, ValueSize: 128, Load: true},
+	{Op: bench.TraceInsert, Key: -7, ValueSize: 0, Load: true},
+	{Op: bench.TraceGet, Key: 1},
+	{Op: bench.TraceGet, Key: math.MaxInt64},
+	{Op: bench.TraceInsert, Key: 42, ValueSize: 4096},
+	{Op: bench.TraceDelete, Key: -7},
+	{Op: bench.TraceRange, Key: 10, End: math.MaxInt64, Limit: 50},
+	{Op: bench.TraceRange, Key: math.MinInt64, End: 100},
+}
+
+func TestTraceRoundTrip(t *testing.T) {
+	for _, format := range bench.TraceFormats {
+		t.Run(format, func(t *testing.T) {
+			path := "/tmp/trace_test_" + format
+			defer os.RemoveAll(path)
+
+			w, err := bench.NewTraceWriter(path, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, op := range traceOps {
+				if err := w.Write(op); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if w.Count() != len(traceOps) {
+				t.Errorf("Count = %d, want %d", w.Count(), len(traceOps))
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := bench.ReadTrace(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, traceOps) {
+				t.Errorf("ReadTrace = %+v, want %+v", got, traceOps)
+			}
+		})
+	}
+}
+
+func TestTraceTruncated(t *testing.T) {
+	path := "/tmp/trace_test_truncated"
+	defer os.RemoveAll(path)
+
+	w, err := bench.NewTraceWriter(path, bench.TraceBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Write(bench.TraceOp{Op: bench.TraceGet, Key: 1})
+	_ = w.Write(bench.TraceOp{Op: bench.TraceRange, Key: 10, End: math.MaxInt64, Limit: 50})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cut the range record before its limit.
+	if err := os.Truncate(path, info.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := bench.OpenTrace(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if op, err := r.Next(); err != nil || op.Key != 1 {
+		t.Fatalf("Next = %+v, %v, want the get of key 1", op, err)
+	}
+	if _, err := r.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Next of a truncated record = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,59 @@
+package bench
+
+import "strings"
+
+// YCSBWorkload describes one of the YCSB core workloads.
+type YCSBWorkload struct {
+	Name        string // single letter, "A" … "F"
+	Description string
+	Mix         OpMix
+	Dist        string // request distribution (one of KeyDists)
+}
+
+// YCSBWorkloads lists the core workloads with the parameters of the YCSB
+// reference configuration files (workloads/workloada … workloadf). YCSB's
+// "zipfian" request distribution is scrambled, which DistScrambledZipfian
+// mirrors.
+var YCSBWorkloads = []YCSBWorkload{
+	{Name: "A", Description: "Update-Heavy (50/50)", Mix: OpMix{Read: 50, Update: 50}, Dist: DistScrambledZipfian},
+	{Name: "B", Description: "Read-Mostly (95/5)", Mix: OpMix{Read: 95, Update: 5}, Dist: DistScrambledZipfian},
+	{Name: "C", Description: "Read-Only", Mix: OpMix{Read: 100}, Dist: DistScrambledZipfian},
+	{Name: "D", Description: "Read-Latest (95/5)", Mix: OpMix{Read: 95, Insert: 5}, Dist: DistLatest},
+	{Name: "E", Description: "Short Ranges (95/5)", Mix: OpMix{Scan: 95, Insert: 5, MaxScanLen: 100}, Dist: DistScrambledZipfian},
+	{Name: "F", Description: "Read-Modify-Write (50/50)", Mix: OpMix{Read: 50, RMW: 50}, Dist: DistScrambledZipfian},
+}
+
+// FileName returns the CSV file the workload's per-operation log is written to.
+func (wl YCSBWorkload) FileName() string {
+	return "ycsb_" + strings.ToLower(wl.Name) + ".csv"
+}
+
+// LookupYCSBWorkload returns the core workload with the given letter.
+func LookupYCSBWorkload(name string) (YCSBWorkload, bool) {
+	for _, wl := range YCSBWorkloads {
+		if strings.EqualFold(wl.Name, name) {
+			return wl, true
+		}
+	}
+	return YCSBWorkload{}, false
+}
+
+// RunBenchmarkYCSB executes one YCSB core workload. Each index is loaded with
+// the dataset, then MixedOpsTotal operations are issued. Inserted keys
+// continue upwards from the largest key, as in YCSB's ordered inserts.
+// cfg.YCSBDist, if set, replaces the workload's request distribution.
+// Results use the layout of the mixed workloads (T4/T5).
+func RunBenchmarkYCSB(indices []IndexDef, cfg Config, wl YCSBWorkload) error {
+	dist := cfg.keyDist(wl.Dist)
+	if cfg.YCSBDist != "" {
+		dist = cfg.keyDist(cfg.YCSBDist)
+	}
+	return runMixed(indices, cfg, mixedRun{
+		label:          "YCSB-" + wl.Name,
+		fileName:       wl.FileName(),
+		dataName:       "ycsb_" + strings.ToLower(wl.Name),
+		mix:            wl.Mix,
+		dist:           dist,
+		orderedInserts: true,
+	})
+}
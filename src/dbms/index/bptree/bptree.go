@@ -4,6 +4,8 @@
 //
 //	[0-3]   uint32  left child page ID
 //	[4-11]  int64   key
+//	[12-13] uint16  value length  (multi-value mode only)
+//	[14+]   []byte  value         (multi-value mode only)
 //
 // Leaf cell format:
 //
@@ -11,14 +13,22 @@
 //	[8-9]   uint16  value length
 //	[10+]   []byte  value
 //
-// Internal nodes carry no values — only keys and child pointers.
+// Internal nodes carry no values — only keys and child pointers — except in
+// multi-value mode, where a separator is a full (key, value) pair so that
+// equal keys can be split across leaves.
 // Leaf nodes are linked via nextLeaf for O(1) range-scan advancement.
 // Leaf splits use copy-up: the median key is copied into the parent but
 // remains in the right leaf as well.
+//
+// A tree opened with OpenCopyOnWrite never modifies published pages (see
+// shared.Tree.CopyOnWrite) and hands out point-in-time Snapshots. Because a
+// shadowed leaf is not re-linked from its left neighbour, nextLeaf pointers
+// are not trusted in that mode and iterators advance via their root-to-leaf path.
 package bptree
 
 import (
 	"encoding/binary"
+	"fmt"
 
 	"github.com/btree-query-bench/bmark/dbms/index"
 	"github.com/btree-query-bench/bmark/dbms/index/btpage"
@@ -32,16 +42,22 @@ const (
 )
 
 // BPTreeAcc implements the shared.NodeAccessor interface for a B+ tree.
-type BPTreeAcc struct{}
+type BPTreeAcc struct {
+	// SeparatorValues stores values in internal cells (multi-value mode).
+	SeparatorValues bool
+}
 
-func (BPTreeAcc) CellSize(isLeaf bool, value []byte) int {
+func (a BPTreeAcc) CellSize(isLeaf bool, value []byte) int {
 	if isLeaf {
 		return leafCellHeader + len(value)
 	}
+	if a.SeparatorValues {
+		return internalCellSize + 2 + len(value)
+	}
 	return internalCellSize
 }
 
-func (BPTreeAcc) ReadCell(p pager.Page, i int, isLeaf bool) (int64, []byte, uint32) {
+func (a BPTreeAcc) ReadCell(p pager.Page, i int, isLeaf bool) (int64, []byte, uint32) {
 	off := int(btpage.CellPtr(p, i))
 	if isLeaf {
 		key := int64(binary.LittleEndian.Uint64(p[off : off+8]))
@@ -52,6 +68,12 @@ func (BPTreeAcc) ReadCell(p pager.Page, i int, isLeaf bool) (int64, []byte, uint
 	}
 	lc := binary.LittleEndian.Uint32(p[off : off+4])
 	key := int64(binary.LittleEndian.Uint64(p[off+4 : off+12]))
+	if a.SeparatorValues {
+		vl := int(binary.LittleEndian.Uint16(p[off+12 : off+14]))
+		val := make([]byte, vl)
+		copy(val, p[off+14:off+14+vl])
+		return key, val, lc
+	}
 	return key, nil, lc
 }
 
@@ -62,7 +84,7 @@ func readCellZeroCopy(p pager.Page, i int) (int64, []byte) {
 	return key, p[off+10 : off+10+vl] // direct slice into page buffer
 }
 
-func (BPTreeAcc) WriteCell(p pager.Page, off int, key int64, value []byte, leftChild uint32, isLeaf bool) {
+func (a BPTreeAcc) WriteCell(p pager.Page, off int, key int64, value []byte, leftChild uint32, isLeaf bool) {
 	if isLeaf {
 		binary.LittleEndian.PutUint64(p[off:off+8], uint64(key))
 		binary.LittleEndian.PutUint16(p[off+8:off+10], uint16(len(value)))
@@ -71,6 +93,10 @@ func (BPTreeAcc) WriteCell(p pager.Page, off int, key int64, value []byte, leftC
 	}
 	binary.LittleEndian.PutUint32(p[off:off+4], leftChild)
 	binary.LittleEndian.PutUint64(p[off+4:off+12], uint64(key))
+	if a.SeparatorValues {
+		binary.LittleEndian.PutUint16(p[off+12:off+14], uint16(len(value)))
+		copy(p[off+14:], value)
+	}
 }
 
 func (BPTreeAcc) OverwriteValue(p pager.Page, i int, newVal []byte, isLeaf bool) {
@@ -94,13 +120,45 @@ func (BPTreeAcc) LinkLeaves(left, right pager.Page, newRightID uint32, oldNext u
 // BPTree implements a B+ tree by embedding the generic shared.Tree.
 type BPTree struct{ shared.Tree }
 
+var (
+	_ index.MultiIndex      = (*BPTree)(nil)
+	_ index.OrderStatistics = (*BPTree)(nil)
+	_ index.IOReporter      = (*BPTree)(nil)
+	_ index.EventReporter   = (*BPTree)(nil)
+)
+
 // Open opens a B+ tree at the given path, creating it if it does not exist.
+// An existing file created with OpenMulti or OpenCounted keeps its modes.
 func Open(path string, cachePages int, pageSize uint32) (*BPTree, error) {
+	return open(path, cachePages, pageSize, 0)
+}
+
+// OpenMulti opens a B+ tree in multi-value mode, where a key may be stored
+// with several values (see shared.Tree.Duplicates).
+func OpenMulti(path string, cachePages int, pageSize uint32) (*BPTree, error) {
+	return open(path, cachePages, pageSize, shared.FlagDuplicates)
+}
+
+// OpenCounted opens a B+ tree that maintains subtree counts for Count, Rank
+// and Select (see shared.Tree.Counted). Pass multi to also enable
+// multi-value mode. The modes of an existing file are kept.
+func OpenCounted(path string, cachePages int, pageSize uint32, multi bool) (*BPTree, error) {
+	flags := shared.FlagCounts
+	if multi {
+		flags |= shared.FlagDuplicates
+	}
+	return open(path, cachePages, pageSize, flags)
+}
+
+func open(path string, cachePages int, pageSize uint32, flags byte) (*BPTree, error) {
 	pg, err := pager.Open(path+".bpt", cachePages, pageSize)
 	if err != nil {
 		return nil, err
 	}
-	t := &BPTree{shared.Tree{Pg: pg, Acc: BPTreeAcc{}}}
+	t := &BPTree{shared.Tree{Pg: pg, Acc: BPTreeAcc{},
+		Duplicates: flags&shared.FlagDuplicates != 0,
+		Counted:    flags&shared.FlagCounts != 0,
+	}}
 	if pg.PageCount() <= 2 {
 		_, _ = pg.Allocate() // page 1: file header
 		rootID, _ := pg.Allocate()
@@ -112,10 +170,30 @@ func Open(path string, cachePages int, pageSize uint32) (*BPTree, error) {
 	} else {
 		_ = t.ReadHeader()
 	}
+	t.Acc = BPTreeAcc{SeparatorValues: t.Duplicates}
+	return t, nil
+}
+
+// OpenCopyOnWrite opens a B+ tree like Open but in copy-on-write mode (see
+// shared.Tree.CopyOnWrite). Every insert allocates a new root-to-leaf path and
+// superseded pages are never reclaimed, so the file keeps growing; with a sync
+// interval of n, every n-th insert also pays two fsyncs.
+func OpenCopyOnWrite(path string, cachePages int, pageSize uint32) (*BPTree, error) {
+	t, err := Open(path, cachePages, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	t.CopyOnWrite = true
 	return t, nil
 }
 
 func (t *BPTree) Get(key int64) ([]byte, error) {
+	if t.Duplicates {
+		return index.FirstValue(t, key)
+	}
+	if t.CopyOnWrite {
+		return t.GetFrom(t.RootID, key)
+	}
 	leafID, err := t.FindLeaf(key)
 	if err != nil {
 		return nil, err
@@ -142,25 +220,85 @@ func (t *BPTree) Get(key int64) ([]byte, error) {
 }
 
 func (t *BPTree) Insert(key int64, val []byte) error { return t.Tree.Insert(key, val) }
-func (t *BPTree) Delete(_ int64) error               { return nil } // TODO
+
+// Delete removes key. In multi-value mode all values stored under key are removed.
+func (t *BPTree) Delete(key int64) error {
+	if !t.Duplicates {
+		return t.Remove(key, nil)
+	}
+	vals, err := t.GetAll(key)
+	if err != nil {
+		return err
+	}
+	for _, v := range vals {
+		if err := t.Remove(key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAll returns every value stored under key in value order.
+func (t *BPTree) GetAll(key int64) ([][]byte, error) { return index.CollectValues(t, key) }
+
+// DeleteValue removes a single (key, value) pair. Outside multi-value mode it behaves like Delete.
+func (t *BPTree) DeleteValue(key int64, value []byte) error { return t.Remove(key, value) }
+
 func (t *BPTree) Close() error {
 	_ = t.WriteHeader()
 	return t.Pg.Close()
 }
 
+// Snapshot is a read-only view of a copy-on-write B+ tree as of the moment it
+// was taken. Later inserts do not affect it.
+type Snapshot struct {
+	tree   *BPTree
+	rootID uint32
+}
+
+// Snapshot returns a handle on the currently published root.
+// It requires the tree to be opened with OpenCopyOnWrite.
+func (t *BPTree) Snapshot() (*Snapshot, error) {
+	if !t.CopyOnWrite {
+		return nil, fmt.Errorf("bptree: snapshot requires copy-on-write mode")
+	}
+	return &Snapshot{tree: t, rootID: t.RootID}, nil
+}
+
+// Get retrieves the value for key as of the snapshot.
+func (s *Snapshot) Get(key int64) ([]byte, error) { return s.tree.GetFrom(s.rootID, key) }
+
+// Range returns an iterator over [start, end] as of the snapshot.
+func (s *Snapshot) Range(start, end int64) (index.Iterator, error) {
+	return s.tree.rangeFrom(s.rootID, start, end)
+}
+
+// Close releases the snapshot. Superseded pages are never reused, so there is nothing to free.
+func (s *Snapshot) Close() error { return nil }
+
+// pathFrame records the child index taken at an internal page while descending.
+type pathFrame struct {
+	id  uint64
+	idx int
+}
+
 // RangeIterator allows scanning over a range of keys in the B+ tree.
 type RangeIterator struct {
 	tree   *BPTree
 	end    int64
 	leafID uint64
 	idx    int
-	currPg pager.Page // Pin the current page
+	currPg pager.Page  // Pin the current page
+	path   []pathFrame // internal pages above the current leaf (copy-on-write mode only)
 	k      int64
 	v      []byte
 	err    error
 }
 
 func (t *BPTree) Range(start, end int64) (index.Iterator, error) {
+	if t.CopyOnWrite {
+		return t.rangeFrom(t.RootID, start, end)
+	}
 	leafID, err := t.FindLeaf(start)
 	if err != nil {
 		return nil, err
@@ -169,7 +307,7 @@ func (t *BPTree) Range(start, end int64) (index.Iterator, error) {
 	if err != nil {
 		return nil, err
 	}
-	idx := shared.FindIdx(p, start, btpage.NumCells(p), t.Acc, true)
+	idx := t.Search(p, start, nil, true)
 	return &RangeIterator{
 		tree:   t,
 		end:    end,
@@ -179,6 +317,63 @@ func (t *BPTree) Range(start, end int64) (index.Iterator, error) {
 	}, nil
 }
 
+// rangeFrom descends from root while remembering the path, so the iterator
+// can move between leaves without relying on nextLeaf.
+func (t *BPTree) rangeFrom(root uint32, start, end int64) (index.Iterator, error) {
+	it := &RangeIterator{tree: t, end: end, path: []pathFrame{}}
+	curr := uint64(root)
+	for {
+		p, err := t.Pg.Read(curr)
+		if err != nil {
+			return nil, err
+		}
+		n := btpage.NumCells(p)
+		if p[btpage.OffType] == btpage.TypeLeaf {
+			it.leafID, it.currPg = curr, p
+			it.idx = t.Search(p, start, nil, true)
+			return it, nil
+		}
+		idx := t.Search(p, start, nil, false)
+		it.path = append(it.path, pathFrame{curr, idx})
+		curr = uint64(shared.ChildAt(p, idx, n, t.Acc))
+	}
+}
+
+// nextLeaf returns the page ID of the leaf following the current one.
+func (it *RangeIterator) nextLeaf() (uint64, error) {
+	if it.path == nil {
+		return uint64(btpage.NextLeaf(it.currPg)), nil
+	}
+	// Pop exhausted frames, step one child to the right, then descend leftmost.
+	for len(it.path) > 0 {
+		top := len(it.path) - 1
+		f := it.path[top]
+		p, err := it.tree.Pg.Read(f.id)
+		if err != nil {
+			return 0, err
+		}
+		n := btpage.NumCells(p)
+		if f.idx >= n {
+			it.path = it.path[:top]
+			continue
+		}
+		it.path[top].idx++
+		curr := uint64(shared.ChildAt(p, f.idx+1, n, it.tree.Acc))
+		for {
+			p, err := it.tree.Pg.Read(curr)
+			if err != nil {
+				return 0, err
+			}
+			if p[btpage.OffType] == btpage.TypeLeaf {
+				return curr, nil
+			}
+			it.path = append(it.path, pathFrame{curr, 0})
+			curr = uint64(shared.ChildAt(p, 0, btpage.NumCells(p), it.tree.Acc))
+		}
+	}
+	return uint64(btpage.InvalidPage), nil
+}
+
 // Next advances the iterator to the next key-value pair.
 func (it *RangeIterator) Next() bool {
 	for it.leafID != uint64(btpage.InvalidPage) {
@@ -202,7 +397,12 @@ func (it *RangeIterator) Next() bool {
 			return true
 		}
 
-		it.leafID = uint64(btpage.NextLeaf(it.currPg))
+		next, err := it.nextLeaf()
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.leafID = next
 		it.currPg = nil
 		it.idx = 0
 	}
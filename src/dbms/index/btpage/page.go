@@ -5,7 +5,7 @@
 // [1-2]   2 bytes  numCells (number of items on the page)
 // [3-4]   2 bytes  cellContentStart (offset to the top of the cell area)
 // [5-8]   4 bytes  rightmost child page ID (internal pages only)
-// [9-12]  4 bytes  nextLeaf page ID (B+ tree leaf linkage)
+// [9-12]  4 bytes  nextLeaf page ID (B+ tree leaf linkage; rightmost subtree count on counted internal pages)
 // [13+]   cell pointer array (uint16 offsets growing downward)
 package btpage
 
@@ -87,6 +87,18 @@ func SetNextLeaf(p pager.Page, id uint32) {
 	binary.LittleEndian.PutUint32(p[OffNextLeaf:OffNextLeaf+4], id)
 }
 
+// RightmostCount returns the number of entries in the rightmost child's subtree.
+// Only internal pages of trees that maintain subtree counts use this field,
+// which shares its bytes with the nextLeaf pointer of leaf pages.
+func RightmostCount(p pager.Page) uint32 {
+	return binary.LittleEndian.Uint32(p[OffNextLeaf : OffNextLeaf+4])
+}
+
+// SetRightmostCount sets the number of entries in the rightmost child's subtree.
+func SetRightmostCount(p pager.Page, c uint32) {
+	binary.LittleEndian.PutUint32(p[OffNextLeaf:OffNextLeaf+4], c)
+}
+
 // CellPtr returns the offset to the i-th cell.
 func CellPtr(p pager.Page, i int) uint16 {
 	o := OffCellPtrs + i*CellPtrSize
@@ -58,13 +58,45 @@ func (BTreeAcc) LinkLeaves(_, _ pager.Page, _, _ uint32) {}
 // BTree implements a B-tree by embedding the generic shared.Tree.
 type BTree struct{ shared.Tree }
 
+var (
+	_ index.MultiIndex      = (*BTree)(nil)
+	_ index.OrderStatistics = (*BTree)(nil)
+	_ index.IOReporter      = (*BTree)(nil)
+	_ index.EventReporter   = (*BTree)(nil)
+)
+
 // Open opens a B-tree at the given path, creating it if it does not exist.
+// An existing file created with OpenMulti or OpenCounted keeps its modes.
 func Open(path string, cachePages int, pageSize uint32) (*BTree, error) {
+	return open(path, cachePages, pageSize, 0)
+}
+
+// OpenMulti opens a B-tree in multi-value mode, where a key may be stored
+// with several values (see shared.Tree.Duplicates).
+func OpenMulti(path string, cachePages int, pageSize uint32) (*BTree, error) {
+	return open(path, cachePages, pageSize, shared.FlagDuplicates)
+}
+
+// OpenCounted opens a B-tree that maintains subtree counts for Count, Rank
+// and Select (see shared.Tree.Counted). Pass multi to also enable
+// multi-value mode. The modes of an existing file are kept.
+func OpenCounted(path string, cachePages int, pageSize uint32, multi bool) (*BTree, error) {
+	flags := shared.FlagCounts
+	if multi {
+		flags |= shared.FlagDuplicates
+	}
+	return open(path, cachePages, pageSize, flags)
+}
+
+func open(path string, cachePages int, pageSize uint32, flags byte) (*BTree, error) {
 	pg, err := pager.Open(path+".bt", cachePages, pageSize)
 	if err != nil {
 		return nil, err
 	}
-	t := &BTree{shared.Tree{Pg: pg, Acc: BTreeAcc{}}}
+	t := &BTree{shared.Tree{Pg: pg, Acc: BTreeAcc{},
+		Duplicates: flags&shared.FlagDuplicates != 0,
+		Counted:    flags&shared.FlagCounts != 0,
+	}}
 	if pg.PageCount() <= 2 {
 		_, _ = pg.Allocate() // page 1: file header
 		rootID, _ := pg.Allocate()
@@ -79,9 +111,37 @@ func Open(path string, cachePages int, pageSize uint32) (*BTree, error) {
 	return t, nil
 }
 
-func (t *BTree) Get(key int64) ([]byte, error)      { return t.Tree.Get(key) }
+func (t *BTree) Get(key int64) ([]byte, error) {
+	if t.Duplicates {
+		return index.FirstValue(t, key)
+	}
+	return t.Tree.Get(key)
+}
+
 func (t *BTree) Insert(key int64, val []byte) error { return t.Tree.Insert(key, val) }
-func (t *BTree) Delete(_ int64) error               { return nil } // TODO
+
+// Delete removes key. In multi-value mode all values stored under key are removed.
+func (t *BTree) Delete(key int64) error {
+	if !t.Duplicates {
+		return t.Remove(key, nil)
+	}
+	vals, err := t.GetAll(key)
+	if err != nil {
+		return err
+	}
+	for _, v := range vals {
+		if err := t.Remove(key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAll returns every value stored under key in value order.
+func (t *BTree) GetAll(key int64) ([][]byte, error) { return index.CollectValues(t, key) }
+
+// DeleteValue removes a single (key, value) pair. Outside multi-value mode it behaves like Delete.
+func (t *BTree) DeleteValue(key int64, value []byte) error { return t.Remove(key, value) }
 func (t *BTree) Close() error {
 	_ = t.WriteHeader()
 	return t.Pg.Close()
@@ -113,7 +173,7 @@ func (t *BTree) Range(start, end int64) (index.Iterator, error) {
 		}
 		n := btpage.NumCells(p)
 		leaf := p[btpage.OffType] == btpage.TypeLeaf
-		idx := shared.FindIdx(p, start, n, t.Acc, leaf)
+		idx := t.Search(p, start, nil, leaf)
 		it.stack = append(it.stack, frame{curr, idx, false})
 		if leaf {
 			break
@@ -23,6 +23,123 @@ type Index interface {
 	Close() error
 }
 
+// MultiIndex is implemented by indexes that can map one key to several
+// values, as needed for secondary indexes over non-unique columns. In
+// multi-value mode Insert adds a (key, value) pair instead of overwriting,
+// Get returns one of the values and Delete removes all of them.
+type MultiIndex interface {
+	Index
+
+	// GetAll returns every value stored under key, or nil if there is none.
+	GetAll(key int64) ([][]byte, error)
+
+	// DeleteValue removes the single pair (key, value), if present.
+	DeleteValue(key int64, value []byte) error
+}
+
+// OrderStatistics is implemented by indexes that can answer positional
+// queries. Counts and positions refer to entries, so in multi-value mode a
+// key contributes one entry per stored value.
+type OrderStatistics interface {
+	// Count returns the number of entries with start <= key <= end.
+	Count(start, end int64) (int, error)
+
+	// Rank returns the number of entries whose key is less than key.
+	Rank(key int64) (int, error)
+
+	// Select returns the entry at zero-based position k in key order.
+	Select(k int) (int64, []byte, error)
+}
+
+// IOStats counts the storage activity of an index. Counters an index cannot
+// observe stay zero.
+type IOStats struct {
+	CacheHits    uint64 // page or block requests served from the cache
+	CacheMisses  uint64 // page or block requests that went to disk
+	Evictions    uint64 // pages dropped from the cache
+	PageReads    uint64 // pages or blocks read from disk
+	PageWrites   uint64 // pages or files written to disk
+	Syncs        uint64 // fsync calls
+	BytesRead    uint64 // bytes read from disk
+	BytesWritten uint64 // bytes written to disk
+}
+
+// Sub returns the activity between the snapshot earlier and s.
+func (s IOStats) Sub(earlier IOStats) IOStats {
+	return IOStats{
+		CacheHits:    s.CacheHits - earlier.CacheHits,
+		CacheMisses:  s.CacheMisses - earlier.CacheMisses,
+		Evictions:    s.Evictions - earlier.Evictions,
+		PageReads:    s.PageReads - earlier.PageReads,
+		PageWrites:   s.PageWrites - earlier.PageWrites,
+		Syncs:        s.Syncs - earlier.Syncs,
+		BytesRead:    s.BytesRead - earlier.BytesRead,
+		BytesWritten: s.BytesWritten - earlier.BytesWritten,
+	}
+}
+
+// IOReporter is implemented by indexes that account for their I/O.
+type IOReporter interface {
+	// IOStats returns the activity since the index was opened.
+	IOStats() IOStats
+}
+
+// Events counts the structural changes of an index: work that stalls the
+// operation triggering it or runs in the background. Counters an index
+// cannot observe stay zero.
+type Events struct {
+	Splits      uint64 // B-tree and B+ tree: page splits
+	RootSplits  uint64 // B-tree and B+ tree: root splits, each adding a level
+	Flushes     uint64 // LSM: memtable flushes
+	Compactions uint64 // LSM: compactions
+	WriteStalls uint64 // LSM: writes stopped until a flush or compaction caught up
+}
+
+// Sub returns the events between the snapshot earlier and e.
+func (e Events) Sub(earlier Events) Events {
+	return Events{
+		Splits:      e.Splits - earlier.Splits,
+		RootSplits:  e.RootSplits - earlier.RootSplits,
+		Flushes:     e.Flushes - earlier.Flushes,
+		Compactions: e.Compactions - earlier.Compactions,
+		WriteStalls: e.WriteStalls - earlier.WriteStalls,
+	}
+}
+
+// EventReporter is implemented by indexes that count their structural events.
+type EventReporter interface {
+	// Events returns the events since the index was opened.
+	Events() Events
+}
+
+// CollectValues returns copies of the values of all entries with the given
+// key by scanning Range(key, key).
+func CollectValues(idx Index, key int64) ([][]byte, error) {
+	it, err := idx.Range(key, key)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	var out [][]byte
+	for it.Next() {
+		out = append(out, append([]byte(nil), it.Value()...))
+	}
+	return out, it.Error()
+}
+
+// FirstValue returns the first value of Range(key, key), or nil if there is none.
+func FirstValue(idx Index, key int64) ([]byte, error) {
+	it, err := idx.Range(key, key)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	if it.Next() {
+		return append([]byte(nil), it.Value()...), nil
+	}
+	return nil, it.Error()
+}
+
 // Iterator allows scanning over a range of key-value pairs in the index.
 type Iterator interface {
 	// Next advances the iterator to the next key-value pair.
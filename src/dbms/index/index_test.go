@@ -2,14 +2,22 @@ package index_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"os"
+	"sort"
 	"testing"
 
 	"github.com/btree-query-bench/bmark/dbms/index"
 	"github.com/btree-query-bench/bmark/dbms/index/bptree"
+	"github.com/btree-query-bench/bmark/dbms/index/btpage"
 	"github.com/btree-query-bench/bmark/dbms/index/btree"
 	"github.com/btree-query-bench/bmark/dbms/index/lsm"
+	"github.com/btree-query-bench/bmark/dbms/index/membtree"
+	"github.com/btree-query-bench/bmark/dbms/index/shared"
+	"github.com/btree-query-bench/bmark/dbms/index/sortedarray"
 )
 
 func runIndexTests(t *testing.T, newIdx func(path string) (index.Index, error), name string) {
@@ -188,6 +196,64 @@ func runIndexTests(t *testing.T, newIdx func(path string) (index.Index, error),
 		}
 		it.Close()
 	})
+
+	t.Run(name+"/Delete", func(t *testing.T) {
+		path := fmt.Sprintf("/tmp/idx_test_%s_del", name)
+		defer os.RemoveAll(path)
+		defer os.RemoveAll(path + ".bt")
+		defer os.RemoveAll(path + ".bpt")
+
+		idx, err := newIdx(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer idx.Close()
+
+		if s, ok := idx.(interface{ SetSyncInterval(int) }); ok {
+			s.SetSyncInterval(0)
+		}
+
+		n := 500
+		for i := 1; i <= n; i++ {
+			v := bytes.Repeat([]byte{byte(i % 256)}, 100)
+			if err := idx.Insert(int64(i), v); err != nil {
+				t.Fatalf("Insert %d failed: %v", i, err)
+			}
+		}
+
+		// Delete every odd key.
+		for i := 1; i <= n; i += 2 {
+			if err := idx.Delete(int64(i)); err != nil {
+				t.Fatalf("Delete %d failed: %v", i, err)
+			}
+		}
+
+		for i := 1; i <= n; i++ {
+			got, err := idx.Get(int64(i))
+			if err != nil {
+				t.Errorf("Get %d failed: %v", i, err)
+			}
+			if deleted := i%2 == 1; deleted != (got == nil) {
+				t.Errorf("Get %d after delete: got %v, deleted=%v", i, got != nil, deleted)
+			}
+		}
+
+		it, err := idx.Range(1, int64(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for it.Next() {
+			if it.Key()%2 == 1 {
+				t.Errorf("Range returned deleted key %d", it.Key())
+			}
+			count++
+		}
+		it.Close()
+		if count != n/2 {
+			t.Errorf("Range after delete got %d, want %d", count, n/2)
+		}
+	})
 }
 
 func TestBTree(t *testing.T) {
@@ -207,3 +273,644 @@ func TestLSM(t *testing.T) {
 		return lsm.Open(path, 64)
 	}, "LSM")
 }
+
+func TestBPTreeCopyOnWrite(t *testing.T) {
+	runIndexTests(t, func(path string) (index.Index, error) {
+		return bptree.OpenCopyOnWrite(path, 10, 4096)
+	}, "BPTreeCOW")
+}
+
+func TestBPTreeSnapshot(t *testing.T) {
+	path := "/tmp/idx_test_BPTreeCOW_snap"
+	defer os.RemoveAll(path + ".bpt")
+
+	tree, err := bptree.OpenCopyOnWrite(path, 10, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.SetSyncInterval(0)
+
+	n := 500
+	for i := 1; i <= n; i++ {
+		if err := tree.Insert(int64(i), []byte(fmt.Sprintf("old%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snap, err := tree.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Close()
+
+	// Overwrite every key and add more keys after the snapshot was taken.
+	for i := 1; i <= 2*n; i++ {
+		if err := tree.Insert(int64(i), []byte(fmt.Sprintf("new%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, _ := snap.Get(42)
+	if string(got) != "old42" {
+		t.Errorf("Snapshot Get(42) = %s, want old42", got)
+	}
+	got, _ = snap.Get(int64(n + 1))
+	if got != nil {
+		t.Errorf("Snapshot Get(%d) = %s, want nil", n+1, got)
+	}
+	got, _ = tree.Get(42)
+	if string(got) != "new42" {
+		t.Errorf("Tree Get(42) = %s, want new42", got)
+	}
+
+	it, err := snap.Range(1, int64(2*n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for it.Next() {
+		count++
+		if want := fmt.Sprintf("old%d", it.Key()); string(it.Value()) != want {
+			t.Errorf("Snapshot Range key %d = %s, want %s", it.Key(), it.Value(), want)
+			break
+		}
+	}
+	it.Close()
+	if count != n {
+		t.Errorf("Snapshot Range returned %d items, want %d", count, n)
+	}
+
+	it, err = tree.Range(1, int64(2*n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	count = 0
+	for it.Next() {
+		count++
+	}
+	it.Close()
+	if count != 2*n {
+		t.Errorf("Tree Range returned %d items, want %d", count, 2*n)
+	}
+}
+
+func TestBPTreeCopyOnWriteReopen(t *testing.T) {
+	path := "/tmp/idx_test_BPTreeCOW_reopen"
+	defer os.RemoveAll(path + ".bpt")
+
+	tree, err := bptree.OpenCopyOnWrite(path, 10, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	tree.SetSyncInterval(100)
+
+	n := 500
+	for i := 1; i <= n; i++ {
+		if err := tree.Insert(int64(i), []byte(fmt.Sprintf("old%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Reopen without closing, as after a crash: the page count on page 0 is
+	// stale, and new pages must not overwrite the committed tree.
+	reopened, err := bptree.OpenCopyOnWrite(path, 10, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	for i := n + 1; i <= 2*n; i++ {
+		if err := reopened.Insert(int64(i), []byte(fmt.Sprintf("new%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 1; i <= 2*n; i++ {
+		want := fmt.Sprintf("old%d", i)
+		if i > n {
+			want = fmt.Sprintf("new%d", i)
+		}
+		if got, _ := reopened.Get(int64(i)); string(got) != want {
+			t.Fatalf("Get(%d) after reopen = %s, want %s", i, got, want)
+		}
+	}
+	if got, err := reopened.Verify(); err != nil || got != 2*n {
+		t.Errorf("Verify after reopen = %d, %v, want %d entries", got, err, 2*n)
+	}
+}
+
+func TestSortedArray(t *testing.T) {
+	runIndexTests(t, func(_ string) (index.Index, error) {
+		return sortedarray.NewSortedArray(), nil
+	}, "SortedArray")
+}
+
+func TestMemBTree(t *testing.T) {
+	runIndexTests(t, func(_ string) (index.Index, error) {
+		return membtree.NewMemBTree(3), nil
+	}, "MemBTree")
+}
+
+func TestInMemoryDelete(t *testing.T) {
+	indices := map[string]index.Index{
+		"SortedArray": sortedarray.NewSortedArray(),
+		"MemBTree":    membtree.NewMemBTree(3),
+	}
+	for name, idx := range indices {
+		rng := rand.New(rand.NewSource(1))
+		want := make(map[int64]bool)
+		for i := 0; i < 5000; i++ {
+			k := int64(rng.Intn(1000))
+			if rng.Intn(3) == 0 {
+				_ = idx.Delete(k)
+				delete(want, k)
+			} else {
+				_ = idx.Insert(k, []byte{byte(k)})
+				want[k] = true
+			}
+		}
+
+		for k := int64(0); k < 1000; k++ {
+			got, _ := idx.Get(k)
+			if (got != nil) != want[k] {
+				t.Errorf("%s: Get(%d) present=%v, want %v", name, k, got != nil, want[k])
+			}
+		}
+
+		it, _ := idx.Range(0, 1000)
+		count, prev := 0, int64(-1)
+		for it.Next() {
+			if it.Key() <= prev {
+				t.Errorf("%s: Range out of order: %d after %d", name, it.Key(), prev)
+			}
+			prev = it.Key()
+			count++
+		}
+		it.Close()
+		if count != len(want) {
+			t.Errorf("%s: Range returned %d items, want %d", name, count, len(want))
+		}
+	}
+}
+
+func TestMultiValue(t *testing.T) {
+	variants := map[string]func(path string) (index.MultiIndex, error){
+		"BTree": func(path string) (index.MultiIndex, error) {
+			return btree.OpenMulti(path, 10, 512)
+		},
+		"BPTree": func(path string) (index.MultiIndex, error) {
+			return bptree.OpenMulti(path, 10, 512)
+		},
+	}
+	for name, open := range variants {
+		t.Run(name, func(t *testing.T) {
+			path := fmt.Sprintf("/tmp/idx_test_%s_multi", name)
+			defer os.RemoveAll(path + ".bt")
+			defer os.RemoveAll(path + ".bpt")
+
+			idx, err := open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer idx.Close()
+
+			// Few distinct keys with many values each, so runs of equal keys span leaves.
+			rng := rand.New(rand.NewSource(7))
+			want := make(map[int64]map[string]bool)
+			for i := 0; i < 4000; i++ {
+				k := int64(rng.Intn(10))
+				v := []byte(fmt.Sprintf("row%05d", rng.Intn(1000)))
+				if want[k] == nil {
+					want[k] = make(map[string]bool)
+				}
+				if rng.Intn(4) == 0 {
+					if err := idx.DeleteValue(k, v); err != nil {
+						t.Fatalf("DeleteValue(%d, %s) failed: %v", k, v, err)
+					}
+					delete(want[k], string(v))
+				} else {
+					if err := idx.Insert(k, v); err != nil {
+						t.Fatalf("Insert(%d, %s) failed: %v", k, v, err)
+					}
+					want[k][string(v)] = true
+				}
+			}
+
+			for k, vals := range want {
+				got, err := idx.GetAll(k)
+				if err != nil {
+					t.Fatalf("GetAll(%d) failed: %v", k, err)
+				}
+				if len(got) != len(vals) {
+					t.Errorf("GetAll(%d) returned %d values, want %d", k, len(got), len(vals))
+				}
+				for i, v := range got {
+					if !vals[string(v)] {
+						t.Errorf("GetAll(%d) returned unexpected value %s", k, v)
+					}
+					if i > 0 && bytes.Compare(got[i-1], v) >= 0 {
+						t.Errorf("GetAll(%d) values out of order at %d", k, i)
+					}
+				}
+			}
+
+			if err := idx.Delete(3); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := idx.GetAll(3); len(got) != 0 {
+				t.Errorf("GetAll(3) after Delete returned %d values, want 0", len(got))
+			}
+			if got, _ := idx.Get(4); len(want[4]) > 0 && got == nil {
+				t.Errorf("Get(4) returned nil, want a value")
+			}
+		})
+	}
+}
+
+func TestOrderStatistics(t *testing.T) {
+	type orderedIndex interface {
+		index.MultiIndex
+		index.OrderStatistics
+	}
+	variants := []struct {
+		name  string
+		multi bool
+		open  func(path string) (orderedIndex, error)
+	}{
+		{"BTree", false, func(path string) (orderedIndex, error) {
+			return btree.OpenCounted(path, 10, 512, false)
+		}},
+		{"BPTree", false, func(path string) (orderedIndex, error) {
+			return bptree.OpenCounted(path, 10, 512, false)
+		}},
+		{"BTreeMulti", true, func(path string) (orderedIndex, error) {
+			return btree.OpenCounted(path, 10, 512, true)
+		}},
+		{"BPTreeMulti", true, func(path string) (orderedIndex, error) {
+			return bptree.OpenCounted(path, 10, 512, true)
+		}},
+	}
+	for _, vt := range variants {
+		t.Run(vt.name, func(t *testing.T) {
+			path := fmt.Sprintf("/tmp/idx_test_%s_counted", vt.name)
+			defer os.RemoveAll(path + ".bt")
+			defer os.RemoveAll(path + ".bpt")
+
+			idx, err := vt.open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer idx.Close()
+
+			// Model: the set of stored (key, value) entries. Outside
+			// multi-value mode a key holds a single value.
+			type entry struct {
+				k int64
+				v string
+			}
+			model := make(map[entry]bool)
+			byKey := make(map[int64]string)
+			rng := rand.New(rand.NewSource(11))
+			for i := 0; i < 3000; i++ {
+				k := int64(rng.Intn(400))
+				v := fmt.Sprintf("v%0*d", 1+rng.Intn(20), rng.Intn(50))
+				if rng.Intn(3) == 0 {
+					if vt.multi {
+						err = idx.DeleteValue(k, []byte(v))
+						delete(model, entry{k, v})
+					} else {
+						err = idx.Delete(k)
+						delete(model, entry{k, byKey[k]})
+						delete(byKey, k)
+					}
+				} else {
+					err = idx.Insert(k, []byte(v))
+					if !vt.multi {
+						delete(model, entry{k, byKey[k]})
+						byKey[k] = v
+					}
+					model[entry{k, v}] = true
+				}
+				if err != nil {
+					t.Fatalf("op %d on key %d failed: %v", i, k, err)
+				}
+			}
+
+			sorted := make([]entry, 0, len(model))
+			for e := range model {
+				sorted = append(sorted, e)
+			}
+			sort.Slice(sorted, func(i, j int) bool {
+				if sorted[i].k != sorted[j].k {
+					return sorted[i].k < sorted[j].k
+				}
+				return sorted[i].v < sorted[j].v
+			})
+
+			for i, e := range sorted {
+				k, v, err := idx.Select(i)
+				if err != nil {
+					t.Fatalf("Select(%d) failed: %v", i, err)
+				}
+				if k != e.k || string(v) != e.v {
+					t.Fatalf("Select(%d) = (%d, %s), want (%d, %s)", i, k, v, e.k, e.v)
+				}
+			}
+			if _, _, err := idx.Select(len(sorted)); err == nil {
+				t.Errorf("Select(%d) past the end succeeded", len(sorted))
+			}
+
+			for key := int64(-1); key <= 401; key++ {
+				want := sort.Search(len(sorted), func(i int) bool { return sorted[i].k >= key })
+				if got, err := idx.Rank(key); err != nil || got != want {
+					t.Fatalf("Rank(%d) = %d, %v; want %d", key, got, err, want)
+				}
+			}
+
+			for i := 0; i < 200; i++ {
+				start, end := int64(rng.Intn(420)-10), int64(rng.Intn(420)-10)
+				want := 0
+				for _, e := range sorted {
+					if e.k >= start && e.k <= end {
+						want++
+					}
+				}
+				if got, err := idx.Count(start, end); err != nil || got != want {
+					t.Fatalf("Count(%d, %d) = %d, %v; want %d", start, end, got, err, want)
+				}
+			}
+			if got, err := idx.Count(0, math.MaxInt64); err != nil || got != len(sorted) {
+				t.Errorf("Count(0, MaxInt64) = %d, %v; want %d", got, err, len(sorted))
+			}
+		})
+	}
+
+	t.Run("LSM", func(t *testing.T) {
+		path := "/tmp/idx_test_LSM_counted"
+		defer os.RemoveAll(path)
+		idx, err := lsm.Open(path, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer idx.Close()
+		// Keys -100, -98, ..., 98: negative keys must sort before positive ones.
+		for i := int64(-50); i < 50; i++ {
+			if err := idx.Insert(i*2, []byte(fmt.Sprint(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got, err := idx.Count(10, 20); err != nil || got != 6 {
+			t.Errorf("Count(10, 20) = %d, %v; want 6", got, err)
+		}
+		if got, err := idx.Count(-10, 10); err != nil || got != 11 {
+			t.Errorf("Count(-10, 10) = %d, %v; want 11", got, err)
+		}
+		if got, err := idx.Rank(-99); err != nil || got != 1 {
+			t.Errorf("Rank(-99) = %d, %v; want 1", got, err)
+		}
+		if got, err := idx.Rank(11); err != nil || got != 56 {
+			t.Errorf("Rank(11) = %d, %v; want 56", got, err)
+		}
+		if k, v, err := idx.Select(0); err != nil || k != -100 || string(v) != "-50" {
+			t.Errorf("Select(0) = (%d, %s), %v; want (-100, -50)", k, v, err)
+		}
+		if k, v, err := idx.Select(57); err != nil || k != 14 || string(v) != "7" {
+			t.Errorf("Select(57) = (%d, %s), %v; want (14, 7)", k, v, err)
+		}
+		if _, _, err := idx.Select(100); err == nil {
+			t.Error("Select(100) past the end succeeded")
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	type verifiable interface {
+		index.Index
+		Verify() (int, error)
+		FindLeaf(key int64) (uint64, error)
+	}
+	variants := []struct {
+		name  string
+		multi bool
+		open  func(path string) (verifiable, error)
+	}{
+		{"BTree", false, func(path string) (verifiable, error) { return btree.Open(path, 10, 512) }},
+		{"BPTree", false, func(path string) (verifiable, error) { return bptree.Open(path, 10, 512) }},
+		{"BPTreeCOW", false, func(path string) (verifiable, error) { return bptree.OpenCopyOnWrite(path, 10, 512) }},
+		{"BTreeCountedMulti", true, func(path string) (verifiable, error) { return btree.OpenCounted(path, 10, 512, true) }},
+		{"BPTreeCountedMulti", true, func(path string) (verifiable, error) { return bptree.OpenCounted(path, 10, 512, true) }},
+	}
+	for _, vt := range variants {
+		t.Run(vt.name, func(t *testing.T) {
+			path := fmt.Sprintf("/tmp/idx_test_%s_verify", vt.name)
+			defer os.RemoveAll(path + ".bt")
+			defer os.RemoveAll(path + ".bpt")
+
+			idx, err := vt.open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer idx.Close()
+
+			entries := make(map[string]bool)
+			rng := rand.New(rand.NewSource(5))
+			for i := 0; i < 3000; i++ {
+				k := int64(rng.Intn(500))
+				v := fmt.Sprintf("v%d", rng.Intn(3))
+				if rng.Intn(3) == 0 {
+					if vt.multi {
+						err = idx.(index.MultiIndex).DeleteValue(k, []byte(v))
+						delete(entries, fmt.Sprintf("%d|%s", k, v))
+					} else {
+						err = idx.Delete(k)
+						delete(entries, fmt.Sprint(k))
+					}
+				} else {
+					err = idx.Insert(k, []byte(v))
+					if vt.multi {
+						entries[fmt.Sprintf("%d|%s", k, v)] = true
+					} else {
+						entries[fmt.Sprint(k)] = true
+					}
+				}
+				if err != nil {
+					t.Fatalf("op %d on key %d failed: %v", i, k, err)
+				}
+			}
+			if n, err := idx.Verify(); err != nil || n != len(entries) {
+				t.Fatalf("Verify() = %d, %v; want %d, nil", n, err, len(entries))
+			}
+
+			// Swapping two cells of a leaf breaks the key order.
+			tree := treeOf(idx)
+			leafID, err := idx.FindLeaf(250)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := tree.Pg.Read(leafID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if btpage.NumCells(p) < 2 {
+				t.Skip("leaf has fewer than two cells")
+			}
+			c0, c1 := btpage.CellPtr(p, 0), btpage.CellPtr(p, 1)
+			btpage.SetCellPtr(p, 0, c1)
+			btpage.SetCellPtr(p, 1, c0)
+			if err := tree.Pg.Write(leafID, p); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := idx.Verify(); !errors.Is(err, shared.ErrCorrupt) {
+				t.Errorf("Verify() after swapping cells = %v, want ErrCorrupt", err)
+			}
+		})
+	}
+}
+
+// treeOf returns the shared engine embedded in a disk-based tree.
+func treeOf(idx index.Index) *shared.Tree {
+	switch t := idx.(type) {
+	case *btree.BTree:
+		return &t.Tree
+	case *bptree.BPTree:
+		return &t.Tree
+	}
+	return nil
+}
+
+func TestTreeStats(t *testing.T) {
+	for _, bplus := range []bool{false, true} {
+		name := "BTree"
+		if bplus {
+			name = "BPTree"
+		}
+		t.Run(name, func(t *testing.T) {
+			path := fmt.Sprintf("/tmp/idx_test_%s_stats", name)
+			defer os.RemoveAll(path + ".bt")
+			defer os.RemoveAll(path + ".bpt")
+
+			var idx index.Index
+			var err error
+			if bplus {
+				idx, err = bptree.Open(path, 10, 512)
+			} else {
+				idx, err = btree.Open(path, 10, 512)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer idx.Close()
+
+			n := 2000
+			for i := 1; i <= n; i++ {
+				if err := idx.Insert(int64(i), []byte(fmt.Sprintf("v%d", i))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 1; i <= n; i += 3 {
+				if err := idx.Delete(int64(i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			live := n - (n+2)/3
+
+			tree := treeOf(idx)
+			before := tree.Pg.Stats()
+			s, err := tree.Stats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after := tree.Pg.Stats(); after != before {
+				t.Errorf("Stats changed the pager statistics from %+v to %+v", before, after)
+			}
+			if s.Entries != live {
+				t.Errorf("Entries = %d, want %d", s.Entries, live)
+			}
+			if s.Height() != tree.Height() {
+				t.Errorf("Height() = %d, want %d", s.Height(), tree.Height())
+			}
+			if s.Levels[0].Pages != 1 {
+				t.Errorf("root level has %d pages", s.Levels[0].Pages)
+			}
+			leaves := s.Levels[len(s.Levels)-1].Pages
+			if leaves != tree.CountLeaves() {
+				t.Errorf("leaf level has %d pages, CountLeaves() = %d", leaves, tree.CountLeaves())
+			}
+			hist := 0
+			for _, c := range s.FillHistogram {
+				hist += c
+			}
+			if hist != s.Pages() {
+				t.Errorf("fill histogram holds %d pages, want %d", hist, s.Pages())
+			}
+			// Every page but the root is a child of exactly one internal page.
+			internal := s.Pages() - leaves
+			if want := float64(s.Pages()-1) / float64(internal); math.Abs(s.AvgFanout-want) > 1e-9 {
+				t.Errorf("AvgFanout = %f, want %f", s.AvgFanout, want)
+			}
+			if s.AvgFill <= 0 || s.AvgFill > 1 {
+				t.Errorf("AvgFill = %f", s.AvgFill)
+			}
+			if s.FragmentedBytes <= 0 {
+				t.Errorf("FragmentedBytes = %d after deletes, want > 0", s.FragmentedBytes)
+			}
+			wantChain := 0
+			if bplus {
+				wantChain = leaves
+			}
+			if s.LeafChainLength != wantChain {
+				t.Errorf("LeafChainLength = %d, want %d", s.LeafChainLength, wantChain)
+			}
+		})
+	}
+}
+
+func TestTreeEvents(t *testing.T) {
+	for _, bplus := range []bool{false, true} {
+		name := "BTree"
+		if bplus {
+			name = "BPTree"
+		}
+		t.Run(name, func(t *testing.T) {
+			path := fmt.Sprintf("/tmp/idx_test_%s_events", name)
+			defer os.RemoveAll(path + ".bt")
+			defer os.RemoveAll(path + ".bpt")
+
+			var idx index.Index
+			var err error
+			if bplus {
+				idx, err = bptree.Open(path, 10, 512)
+			} else {
+				idx, err = btree.Open(path, 10, 512)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer idx.Close()
+
+			for i := 1; i <= 2000; i++ {
+				if err := idx.Insert(int64(i), []byte(fmt.Sprintf("v%d", i))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			e := idx.(index.EventReporter).Events()
+			tree := treeOf(idx)
+			if want := uint64(tree.Height() - 1); e.RootSplits != want {
+				t.Errorf("RootSplits = %d, want %d", e.RootSplits, want)
+			}
+			// Without deletes every page but the first comes from a split or
+			// is a new root.
+			s, err := tree.Stats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := uint64(s.Pages() - 1); e.Splits+e.RootSplits != want {
+				t.Errorf("Splits + RootSplits = %d, want %d", e.Splits+e.RootSplits, want)
+			}
+			if d := e.Sub(e); d != (index.Events{}) {
+				t.Errorf("Sub of itself = %+v, want zero", d)
+			}
+		})
+	}
+}
@@ -5,32 +5,52 @@ package lsm
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"sync/atomic"
 
 	"github.com/btree-query-bench/bmark/dbms/index"
 	"github.com/cockroachdb/pebble"
 )
 
+// ErrOutOfRange is returned by Select for a position beyond the last key.
+var ErrOutOfRange = errors.New("lsm: position out of range")
+
 // LSM wraps the Pebble storage engine to implement the Index interface.
 type LSM struct {
-	db *pebble.DB
+	db        *pebble.DB
+	blockSize int           // data block size of the sstables
+	stalls    atomic.Uint64 // write stalls reported by Pebble's event listener
 }
 
+var (
+	_ index.OrderStatistics = (*LSM)(nil)
+	_ index.IOReporter      = (*LSM)(nil)
+	_ index.EventReporter   = (*LSM)(nil)
+)
+
 // Open opens (or creates) a Pebble database at the given directory path.
 func Open(dir string, memSize int64) (*LSM, error) {
 	targetSize := memSize * 1024 * 1024
 
+	l := &LSM{}
 	opts := &pebble.Options{
 		DisableWAL:   true, // Disable for fairness with B-tree (which has no WAL)
 		MemTableSize: uint64(targetSize),
+		EventListener: &pebble.EventListener{
+			WriteStallBegin: func(pebble.WriteStallBeginInfo) { l.stalls.Add(1) },
+		},
 	}
+	opts.EnsureDefaults()
 
 	db, err := pebble.Open(dir, opts)
 	if err != nil {
 		return nil, fmt.Errorf("lsm: open: %w", err)
 	}
-	return &LSM{db: db}, nil
+	l.db, l.blockSize = db, opts.Levels[0].BlockSize
+	return l, nil
 }
 
 // Close cleanly shuts down Pebble, flushing any in-memory state.
@@ -71,7 +91,7 @@ func (l *LSM) Delete(key int64) error {
 func (l *LSM) Range(start, end int64) (index.Iterator, error) {
 	iterOpts := &pebble.IterOptions{
 		LowerBound: encodeKey(start),
-		UpperBound: encodeKeyExclusive(end),
+		UpperBound: upperBound(end),
 	}
 	iter, err := l.db.NewIter(iterOpts)
 	if err != nil {
@@ -81,34 +101,163 @@ func (l *LSM) Range(start, end int64) (index.Iterator, error) {
 	return &rangeIterator{iter: iter, first: true}, nil
 }
 
-// Levels returns a string describing the current state of the LSM levels.
-func (l *LSM) Levels() string {
+// Count returns the number of keys in [start, end]. Pebble keeps no counts,
+// so this scans the range.
+func (l *LSM) Count(start, end int64) (int, error) {
+	if start > end {
+		return 0, nil
+	}
+	return l.countBetween(encodeKey(start), upperBound(end))
+}
+
+// Rank returns the number of keys less than key by scanning up to it.
+func (l *LSM) Rank(key int64) (int, error) {
+	return l.countBetween(nil, encodeKey(key))
+}
+
+// Select returns the k-th smallest key and its value by scanning from the start.
+func (l *LSM) Select(k int) (int64, []byte, error) {
+	if k < 0 {
+		return 0, nil, ErrOutOfRange
+	}
+	iter, err := l.db.NewIter(nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("lsm: select: %w", err)
+	}
+	defer iter.Close()
+	valid := iter.First()
+	for ; valid && k > 0; k-- {
+		valid = iter.Next()
+	}
+	if !valid {
+		if err := iter.Error(); err != nil {
+			return 0, nil, fmt.Errorf("lsm: select: %w", err)
+		}
+		return 0, nil, ErrOutOfRange
+	}
+	val := make([]byte, len(iter.Value()))
+	copy(val, iter.Value())
+	return decodeKey(iter.Key()), val, nil
+}
+
+func (l *LSM) countBetween(lower, upper []byte) (int, error) {
+	iter, err := l.db.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+	if err != nil {
+		return 0, fmt.Errorf("lsm: count: %w", err)
+	}
+	defer iter.Close()
+	n := 0
+	for valid := iter.First(); valid; valid = iter.Next() {
+		n++
+	}
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("lsm: count: %w", err)
+	}
+	return n, nil
+}
+
+// Metrics returns Pebble's current metrics.
+func (l *LSM) Metrics() *pebble.Metrics {
+	return l.db.Metrics()
+}
+
+// IOStats maps Pebble's metrics onto index.IOStats. Pebble does not count the
+// bytes that reads fetch from disk, so every block cache miss is taken as one
+// block read and added to the bytes read by compactions. Page writes are the
+// sstables written by flushes and compactions. Syncs and evictions are not
+// reported.
+func (l *LSM) IOStats() index.IOStats {
 	m := l.db.Metrics()
-	var sb strings.Builder
+	total := m.Total()
+	misses := uint64(m.BlockCache.Misses)
+	return index.IOStats{
+		CacheHits:    uint64(m.BlockCache.Hits),
+		CacheMisses:  misses,
+		PageReads:    misses,
+		PageWrites:   total.TablesFlushed + total.TablesCompacted,
+		BytesRead:    total.BytesRead + misses*uint64(l.blockSize),
+		BytesWritten: m.WAL.BytesWritten + total.BytesFlushed + total.BytesCompacted,
+	}
+}
+
+// Events returns the flushes, compactions and write stalls since the
+// database was opened.
+func (l *LSM) Events() index.Events {
+	m := l.db.Metrics()
+	return index.Events{
+		Flushes:     uint64(m.Flush.Count),
+		Compactions: uint64(m.Compact.Count),
+		WriteStalls: l.stalls.Load(),
+	}
+}
+
+// UnflushedBytes returns the size of the memtables, which are not on disk yet.
+func (l *LSM) UnflushedBytes() int64 {
+	return int64(l.db.Metrics().MemTable.Size)
+}
+
+// LevelStats describes the sstables of one non-empty LSM level.
+type LevelStats struct {
+	Level     int
+	Sublevels int   // each adds to read amplification; only L0 has more than one
+	Files     int64 // sstables
+	Bytes     int64 // their total size
+}
+
+// LevelStats returns the non-empty levels, from L0 down.
+func (l *LSM) LevelStats() []LevelStats {
+	m := l.db.Metrics()
+	var out []LevelStats
 	for i, level := range m.Levels {
 		if level.NumFiles > 0 {
-			fmt.Fprintf(&sb, "L%d: %d files", i, level.NumFiles)
+			out = append(out, LevelStats{Level: i, Sublevels: int(level.Sublevels), Files: level.NumFiles, Bytes: level.Size})
 		}
 	}
+	return out
+}
+
+// Levels returns a string describing the current state of the LSM levels.
+func (l *LSM) Levels() string {
+	var sb strings.Builder
+	for _, level := range l.LevelStats() {
+		fmt.Fprintf(&sb, "L%d: %d files", level.Level, level.Files)
+	}
 	return sb.String()
 }
 
 // ─── Key encoding ─────────────────────────────────────────────────────────────
 
-// encodeKey encodes an int64 as a big-endian 8-byte slice.
-// Big-endian preserves sort order, which Pebble (and all LSM trees) rely on.
+// encodeKey encodes an int64 as a big-endian 8-byte slice with the sign bit
+// flipped, so that byte order matches signed key order, which Pebble (and all
+// LSM trees) rely on: negative keys sort before positive ones.
 func encodeKey(k int64) []byte {
 	b := make([]byte, 8)
-	binary.BigEndian.PutUint64(b, uint64(k))
+	binary.BigEndian.PutUint64(b, uint64(k)^signBit)
 	return b
 }
 
+// decodeKey reverses encodeKey.
+func decodeKey(b []byte) int64 {
+	return int64(binary.BigEndian.Uint64(b) ^ signBit)
+}
+
+const signBit = 1 << 63
+
 // encodeKeyExclusive returns the exclusive upper bound for use with Pebble's
 // UpperBound option (which is exclusive, unlike our interface which is inclusive).
 func encodeKeyExclusive(k int64) []byte {
 	return encodeKey(k + 1)
 }
 
+// upperBound returns the exclusive Pebble bound for an inclusive end key,
+// or nil (unbounded) when end is the largest key.
+func upperBound(end int64) []byte {
+	if end == math.MaxInt64 {
+		return nil
+	}
+	return encodeKeyExclusive(end)
+}
+
 // ─── Range Iterator ───────────────────────────────────────────────────────────
 
 type rangeIterator struct {
@@ -136,7 +285,7 @@ func (it *rangeIterator) Next() bool {
 		it.err = fmt.Errorf("lsm: unexpected key length %d", len(k))
 		return false
 	}
-	it.key = int64(binary.BigEndian.Uint64(k))
+	it.key = decodeKey(k)
 	// Copy value — Pebble reuses the buffer on Next().
 	v := it.iter.Value()
 	it.val = make([]byte, len(v))
@@ -0,0 +1,314 @@
+// Package membtree implements an in-memory, pointer-based B-tree.
+//
+// Nodes are ordinary Go structs linked by pointers, so there is no page
+// encoding, no pager cache and no disk I/O. It follows the same algorithm as
+// the btree package (values stored in internal nodes, in-order traversal for
+// range scans) and serves as a baseline for the cost of the algorithm alone.
+package membtree
+
+import (
+	"sort"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+)
+
+var _ index.Index = (*MemBTree)(nil)
+
+// DefaultDegree is the minimum degree used when NewMemBTree is given a value < 2.
+const DefaultDegree = 64
+
+type node struct {
+	keys     []int64
+	vals     [][]byte
+	children []*node // nil for leaves
+}
+
+func (n *node) leaf() bool { return n.children == nil }
+
+// search returns the position of the first key >= key.
+func (n *node) search(key int64) int {
+	return sort.Search(len(n.keys), func(i int) bool { return n.keys[i] >= key })
+}
+
+// MemBTree is a classic B-tree of minimum degree t: every node except the
+// root holds between t-1 and 2t-1 keys.
+type MemBTree struct {
+	root *node
+	t    int
+}
+
+// NewMemBTree creates an empty tree with the given minimum degree.
+func NewMemBTree(degree int) *MemBTree {
+	if degree < 2 {
+		degree = DefaultDegree
+	}
+	return &MemBTree{root: &node{}, t: degree}
+}
+
+func (b *MemBTree) full(n *node) bool { return len(n.keys) == 2*b.t-1 }
+
+func (b *MemBTree) Get(key int64) ([]byte, error) {
+	n := b.root
+	for {
+		i := n.search(key)
+		if i < len(n.keys) && n.keys[i] == key {
+			return n.vals[i], nil
+		}
+		if n.leaf() {
+			return nil, nil
+		}
+		n = n.children[i]
+	}
+}
+
+// Insert adds or updates key. Full nodes are split on the way down, so the
+// insert never has to walk back up.
+func (b *MemBTree) Insert(key int64, value []byte) error {
+	if b.full(b.root) {
+		b.root = &node{children: []*node{b.root}}
+		b.splitChild(b.root, 0)
+	}
+	n := b.root
+	for {
+		i := n.search(key)
+		if i < len(n.keys) && n.keys[i] == key {
+			n.vals[i] = value
+			return nil
+		}
+		if n.leaf() {
+			n.keys = insertAt(n.keys, i, key)
+			n.vals = insertAt(n.vals, i, value)
+			return nil
+		}
+		if b.full(n.children[i]) {
+			b.splitChild(n, i)
+			if n.keys[i] == key {
+				n.vals[i] = value
+				return nil
+			}
+			if key > n.keys[i] {
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// splitChild splits the full child i of parent around its median key.
+func (b *MemBTree) splitChild(parent *node, i int) {
+	child := parent.children[i]
+	mid := b.t - 1
+	right := &node{
+		keys: append([]int64(nil), child.keys[mid+1:]...),
+		vals: append([][]byte(nil), child.vals[mid+1:]...),
+	}
+	if !child.leaf() {
+		right.children = append([]*node(nil), child.children[mid+1:]...)
+		child.children = child.children[:mid+1]
+	}
+	parent.keys = insertAt(parent.keys, i, child.keys[mid])
+	parent.vals = insertAt(parent.vals, i, child.vals[mid])
+	parent.children = insertAt(parent.children, i+1, right)
+	child.keys = child.keys[:mid]
+	child.vals = child.vals[:mid]
+}
+
+// Delete removes key. Every node entered on the way down is first topped up
+// to at least t keys, so removing from a leaf never underflows it.
+func (b *MemBTree) Delete(key int64) error {
+	b.delete(b.root, key)
+	if len(b.root.keys) == 0 && !b.root.leaf() {
+		b.root = b.root.children[0]
+	}
+	return nil
+}
+
+func (b *MemBTree) delete(n *node, key int64) {
+	for {
+		i := n.search(key)
+		found := i < len(n.keys) && n.keys[i] == key
+		if n.leaf() {
+			if found {
+				n.keys = removeAt(n.keys, i)
+				n.vals = removeAt(n.vals, i)
+			}
+			return
+		}
+		if found {
+			switch {
+			case len(n.children[i].keys) >= b.t:
+				// Replace with the predecessor and delete it from the left subtree.
+				p := n.children[i]
+				for !p.leaf() {
+					p = p.children[len(p.children)-1]
+				}
+				last := len(p.keys) - 1
+				n.keys[i], n.vals[i] = p.keys[last], p.vals[last]
+				key, n = p.keys[last], n.children[i]
+			case len(n.children[i+1].keys) >= b.t:
+				// Replace with the successor and delete it from the right subtree.
+				s := n.children[i+1]
+				for !s.leaf() {
+					s = s.children[0]
+				}
+				n.keys[i], n.vals[i] = s.keys[0], s.vals[0]
+				key, n = s.keys[0], n.children[i+1]
+			default:
+				b.merge(n, i)
+				n = n.children[i]
+			}
+			continue
+		}
+		if len(n.children[i].keys) < b.t {
+			i = b.fill(n, i)
+		}
+		n = n.children[i]
+	}
+}
+
+// fill makes sure child i of n has at least t keys by borrowing from a
+// sibling or merging with one. It returns the index of the child to descend into.
+func (b *MemBTree) fill(n *node, i int) int {
+	switch {
+	case i > 0 && len(n.children[i-1].keys) >= b.t:
+		child, left := n.children[i], n.children[i-1]
+		last := len(left.keys) - 1
+		child.keys = insertAt(child.keys, 0, n.keys[i-1])
+		child.vals = insertAt(child.vals, 0, n.vals[i-1])
+		n.keys[i-1], n.vals[i-1] = left.keys[last], left.vals[last]
+		left.keys, left.vals = left.keys[:last], left.vals[:last]
+		if !left.leaf() {
+			child.children = insertAt(child.children, 0, left.children[last+1])
+			left.children = left.children[:last+1]
+		}
+		return i
+	case i < len(n.keys) && len(n.children[i+1].keys) >= b.t:
+		child, right := n.children[i], n.children[i+1]
+		child.keys = append(child.keys, n.keys[i])
+		child.vals = append(child.vals, n.vals[i])
+		n.keys[i], n.vals[i] = right.keys[0], right.vals[0]
+		right.keys, right.vals = removeAt(right.keys, 0), removeAt(right.vals, 0)
+		if !right.leaf() {
+			child.children = append(child.children, right.children[0])
+			right.children = removeAt(right.children, 0)
+		}
+		return i
+	case i < len(n.keys):
+		b.merge(n, i)
+		return i
+	default:
+		b.merge(n, i-1)
+		return i - 1
+	}
+}
+
+// merge folds key i of n and its right child into its left child.
+func (b *MemBTree) merge(n *node, i int) {
+	left, right := n.children[i], n.children[i+1]
+	left.keys = append(append(left.keys, n.keys[i]), right.keys...)
+	left.vals = append(append(left.vals, n.vals[i]), right.vals...)
+	if !left.leaf() {
+		left.children = append(left.children, right.children...)
+	}
+	n.keys = removeAt(n.keys, i)
+	n.vals = removeAt(n.vals, i)
+	n.children = removeAt(n.children, i+1)
+}
+
+func (b *MemBTree) Close() error { return nil }
+
+// Height returns the current height of the tree.
+func (b *MemBTree) Height() int {
+	h := 1
+	for n := b.root; !n.leaf(); n = n.children[0] {
+		h++
+	}
+	return h
+}
+
+// ─── Range Iterator ───────────────────────────────────────────────────────────
+
+type frame struct {
+	n   *node
+	idx int // next key of n to emit
+}
+
+// RangeIterator performs an in-order traversal using an explicit stack.
+type RangeIterator struct {
+	stack []frame
+	end   int64
+	k     int64
+	v     []byte
+}
+
+func (b *MemBTree) Range(start, end int64) (index.Iterator, error) {
+	it := &RangeIterator{end: end}
+	n := b.root
+	for {
+		i := n.search(start)
+		it.stack = append(it.stack, frame{n, i})
+		if n.leaf() {
+			return it, nil
+		}
+		n = n.children[i]
+	}
+}
+
+// Next advances the iterator to the next key-value pair.
+func (it *RangeIterator) Next() bool {
+	for len(it.stack) > 0 {
+		top := len(it.stack) - 1
+		f := it.stack[top]
+		if f.idx >= len(f.n.keys) {
+			it.stack = it.stack[:top]
+			continue
+		}
+		if f.n.keys[f.idx] > it.end {
+			it.stack = nil
+			return false
+		}
+		it.k, it.v = f.n.keys[f.idx], f.n.vals[f.idx]
+		it.stack[top].idx++
+		// The right subtree of the emitted key comes before the next key.
+		if !f.n.leaf() {
+			for c := f.n.children[f.idx+1]; c != nil; {
+				it.stack = append(it.stack, frame{c, 0})
+				if c.leaf() {
+					break
+				}
+				c = c.children[0]
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Key returns the key of the current key-value pair.
+func (it *RangeIterator) Key() int64 { return it.k }
+
+// Value returns the value of the current key-value pair.
+func (it *RangeIterator) Value() []byte { return it.v }
+
+// Error returns the first error encountered by the iterator, if any.
+func (it *RangeIterator) Error() error { return nil }
+
+// Close releases resources associated with the iterator.
+func (it *RangeIterator) Close() error { return nil }
+
+// ─── helpers ───────────────────────────────────
+
+func insertAt[T any](s []T, i int, v T) []T {
+	var zero T
+	s = append(s, zero)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+func removeAt[T any](s []T, i int) []T {
+	copy(s[i:], s[i+1:])
+	var zero T
+	s[len(s)-1] = zero
+	return s[:len(s)-1]
+}
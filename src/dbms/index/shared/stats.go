@@ -0,0 +1,136 @@
+package shared
+
+import (
+	"github.com/btree-query-bench/bmark/dbms/index/btpage"
+)
+
+// FillBuckets is the number of buckets of Stats.FillHistogram.
+const FillBuckets = 10
+
+// Stats describes the shape and occupancy of a tree.
+type Stats struct {
+	// Levels holds one entry per level, from the root down to the leaves.
+	Levels []LevelStats
+
+	// FillHistogram counts pages by fill (see LevelStats.AvgFill) in
+	// FillBuckets equal buckets; bucket i covers [i/10, (i+1)/10).
+	FillHistogram [FillBuckets]int
+
+	Entries         int     // entries in the tree
+	AvgFill         float64 // average fill over all pages
+	AvgFanout       float64 // average number of children of an internal page (0 for a single leaf)
+	FragmentedBytes int     // bytes left behind by deleted cells, over all pages (see Tree.FragmentedBytes)
+
+	// LeafChainLength is the number of leaves reached by following the
+	// nextLeaf links from the leftmost leaf. B+ trees link all their leaves
+	// (unless written in copy-on-write mode); B-trees have no chain.
+	LeafChainLength int
+}
+
+// LevelStats describes the pages of one tree level.
+type LevelStats struct {
+	Pages   int
+	Keys    int     // cells: entries on leaves and B-tree internal pages, separators on B+ tree internal pages
+	AvgFill float64 // average fraction of a page that btpage.FreeSpace does not report as free
+}
+
+// Height returns the number of levels.
+func (s Stats) Height() int { return len(s.Levels) }
+
+// Pages returns the number of pages over all levels.
+func (s Stats) Pages() int {
+	n := 0
+	for _, l := range s.Levels {
+		n += l.Pages
+	}
+	return n
+}
+
+// Stats walks the pages reachable from the root and collects their
+// occupancy. Pages superseded in copy-on-write mode are not included.
+// The walk reads through Pager.Peek, so the page cache and the I/O
+// statistics stay as they were.
+func (t *Tree) Stats() (Stats, error) {
+	var s Stats
+	var fillSums []float64
+	var children, internal int
+	leftmostLeaf := uint64(btpage.InvalidPage)
+
+	var walk func(id uint64, depth int) error
+	walk = func(id uint64, depth int) error {
+		p, err := t.Pg.Peek(id)
+		if err != nil {
+			return err
+		}
+		n := btpage.NumCells(p)
+		leaf := isLeaf(p)
+		fill := 1 - float64(btpage.FreeSpace(p, n))/float64(len(p))
+
+		if depth == len(s.Levels) {
+			s.Levels = append(s.Levels, LevelStats{})
+			fillSums = append(fillSums, 0)
+		}
+		s.Levels[depth].Pages++
+		s.Levels[depth].Keys += n
+		fillSums[depth] += fill
+		s.FillHistogram[min(FillBuckets-1, int(fill*FillBuckets))]++
+		s.FragmentedBytes += t.FragmentedBytes(p)
+
+		if leaf {
+			s.Entries += n
+			if leftmostLeaf == uint64(btpage.InvalidPage) {
+				leftmostLeaf = id
+			}
+			return nil
+		}
+		if !t.Acc.CopyUpLeaves() {
+			s.Entries += n
+		}
+		internal++
+		children += n + 1
+		for i := 0; i <= n; i++ {
+			if err := walk(uint64(ChildAt(p, i, n, t.Acc)), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(uint64(t.RootID), 0); err != nil {
+		return Stats{}, err
+	}
+
+	pages := 0
+	for i := range s.Levels {
+		s.Levels[i].AvgFill = fillSums[i] / float64(s.Levels[i].Pages)
+		s.AvgFill += fillSums[i]
+		pages += s.Levels[i].Pages
+	}
+	s.AvgFill /= float64(pages)
+	if internal > 0 {
+		s.AvgFanout = float64(children) / float64(internal)
+	}
+
+	if t.Acc.CopyUpLeaves() {
+		// Stop after as many leaves as the tree has, in case the chain loops.
+		leaves := s.Levels[len(s.Levels)-1].Pages
+		for id := leftmostLeaf; id != uint64(btpage.InvalidPage) && s.LeafChainLength <= leaves; {
+			p, err := t.Pg.Peek(id)
+			if err != nil {
+				return Stats{}, err
+			}
+			s.LeafChainLength++
+			id = uint64(btpage.NextLeaf(p))
+		}
+	}
+	return s, nil
+}
+
+// PageFill returns the number of pages reachable from the root and their
+// average fill (see Stats).
+func (t *Tree) PageFill() (pages int, avgFill float64, err error) {
+	s, err := t.Stats()
+	if err != nil {
+		return 0, 0, err
+	}
+	return s.Pages(), s.AvgFill, nil
+}
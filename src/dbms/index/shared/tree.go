@@ -3,11 +3,15 @@
 package shared
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
 
+	"github.com/btree-query-bench/bmark/dbms/index"
 	"github.com/btree-query-bench/bmark/dbms/index/btpage"
 	"github.com/btree-query-bench/bmark/dbms/pager"
 )
@@ -42,8 +46,56 @@ type Tree struct {
 	Pg     *pager.Pager
 	RootID uint32
 	Acc    NodeAccessor
+
+	// CopyOnWrite enables LMDB-style shadow paging: an insert never modifies a
+	// published page but copies the root-to-leaf path into freshly allocated
+	// pages and then swaps the root pointer in the header page. Pages reachable
+	// from an older root stay untouched, so snapshots remain consistent. With
+	// a sync interval of n, every n-th insert is a durable commit: it syncs the
+	// new pages, rewrites the header and syncs again. Reopening after a crash
+	// finds the last durable tree and allocates past every page of it.
+	// Superseded pages are not reclaimed, so the file grows with every insert.
+	CopyOnWrite bool
+
+	// Duplicates enables multi-value mode: cells are ordered by (key, value)
+	// so the same key can be stored with several values, as in a secondary
+	// index over a non-unique column. Insert adds a pair instead of
+	// overwriting, and B+ tree separators keep their value so that runs of
+	// equal keys can span several leaves. The mode is persisted in the header.
+	Duplicates bool
+
+	// Counted makes the tree maintain, for every child pointer of an internal
+	// page, the number of entries in that child's subtree. The count is stored
+	// in 4 bytes behind each internal cell (and in the header for the rightmost
+	// child), which enables Count, Rank and Select in O(height) page reads.
+	// Inserts and removals pay an extra lookup to learn whether they change
+	// the number of entries. The mode is persisted in the header.
+	Counted bool
+
+	splits, rootSplits uint64 // see Events
+	commits            int    // copy-on-write commits since the last durable one
 }
 
+// Header page (page 1) layout.
+const (
+	offHeaderRoot  = 0 // uint32 root page ID
+	offHeaderFlags = 4 // byte of Flag* bits
+
+	// FlagDuplicates marks a tree created in multi-value mode.
+	FlagDuplicates = byte(1 << 0)
+	// FlagCounts marks a tree that maintains subtree counts.
+	FlagCounts = byte(1 << 1)
+
+	countSize = 4 // uint32 subtree count behind each internal cell
+)
+
+var (
+	// ErrNoCounts is returned by order-statistic queries on a tree without subtree counts.
+	ErrNoCounts = errors.New("shared: tree does not maintain subtree counts")
+	// ErrOutOfRange is returned by Select for a position beyond the last entry.
+	ErrOutOfRange = errors.New("shared: position out of range")
+)
+
 // SetSyncInterval sets the number of writes after which the pager should sync to disk.
 func (t *Tree) SetSyncInterval(n int) {
 	t.Pg.SetSyncInterval(n)
@@ -51,23 +103,201 @@ func (t *Tree) SetSyncInterval(n int) {
 
 // ─── helpers ───────────────────────────────────
 
+// IOStats returns the pager's activity since the tree was opened.
+func (t *Tree) IOStats() index.IOStats {
+	s := t.Pg.Stats()
+	return index.IOStats{
+		CacheHits:    s.CacheHits,
+		CacheMisses:  s.CacheMisses,
+		Evictions:    s.Evictions,
+		PageReads:    s.PageReads,
+		PageWrites:   s.PageWrites,
+		Syncs:        s.Syncs,
+		BytesRead:    s.BytesRead,
+		BytesWritten: s.BytesWritten,
+	}
+}
+
+// MultiValue reports whether the tree is in multi-value mode (see
+// Duplicates). The B-tree and B+ tree always implement index.MultiIndex, so
+// callers use it to tell whether duplicate keys are allowed.
+func (t *Tree) MultiValue() bool { return t.Duplicates }
+
+// Events returns the page and root splits since the tree was opened.
+func (t *Tree) Events() index.Events {
+	return index.Events{Splits: t.splits, RootSplits: t.rootSplits}
+}
+
 func isLeaf(p pager.Page) bool { return p[btpage.OffType] == btpage.TypeLeaf }
 
 func (t *Tree) readCell(p pager.Page, i int) (int64, []byte, uint32) {
 	return t.Acc.ReadCell(p, i, isLeaf(p))
 }
 
+// cellSize returns the space a cell occupies on p, including the subtree count of counted internal cells.
 func (t *Tree) cellSize(p pager.Page, value []byte) int {
-	return t.Acc.CellSize(isLeaf(p), value)
+	return t.sizeOf(isLeaf(p), value)
+}
+
+func (t *Tree) sizeOf(leaf bool, value []byte) int {
+	size := t.Acc.CellSize(leaf, value)
+	if t.Counted && !leaf {
+		size += countSize
+	}
+	return size
 }
 
 // AppendCell adds a new cell to the end of the specified page.
+// A counted internal cell starts with a subtree count of zero.
 func (t *Tree) AppendCell(p pager.Page, key int64, value []byte, leftChild uint32) {
+	t.appendCell(p, CellData{key, value, leftChild, 0})
+}
+
+func (t *Tree) appendCell(p pager.Page, c CellData) {
 	n := btpage.NumCells(p)
-	off := btpage.AllocCell(p, t.Acc.CellSize(isLeaf(p), value))
-	t.Acc.WriteCell(p, off, key, value, leftChild, isLeaf(p))
+	off := btpage.AllocCell(p, t.cellSize(p, c.Value))
+	t.Acc.WriteCell(p, off, c.Key, c.Value, c.LeftChild, isLeaf(p))
 	btpage.SetCellPtr(p, n, uint16(off))
 	btpage.SetNumCells(p, n+1)
+	if t.Counted && !isLeaf(p) {
+		t.setCellCount(p, n, c.Count)
+	}
+}
+
+// ─── subtree counts ───────────────────────────────────
+
+// countOff returns the offset of the subtree count stored behind internal cell i.
+func (t *Tree) countOff(p pager.Page, i int) int {
+	_, v, _ := t.Acc.ReadCell(p, i, false)
+	return int(btpage.CellPtr(p, i)) + t.Acc.CellSize(false, v)
+}
+
+func (t *Tree) cellCount(p pager.Page, i int) uint32 {
+	off := t.countOff(p, i)
+	return binary.LittleEndian.Uint32(p[off : off+countSize])
+}
+
+func (t *Tree) setCellCount(p pager.Page, i int, c uint32) {
+	off := t.countOff(p, i)
+	binary.LittleEndian.PutUint32(p[off:off+countSize], c)
+}
+
+// ChildCount returns the number of entries below child idx of an internal page
+// with n cells, where idx == n denotes the rightmost child.
+func (t *Tree) ChildCount(p pager.Page, idx, n int) uint32 {
+	if idx == n {
+		return btpage.RightmostCount(p)
+	}
+	return t.cellCount(p, idx)
+}
+
+func (t *Tree) setChildCount(p pager.Page, idx, n int, c uint32) {
+	if idx == n {
+		btpage.SetRightmostCount(p, c)
+		return
+	}
+	t.setCellCount(p, idx, c)
+}
+
+// SubtreeCount returns the number of entries stored in p and below it.
+func (t *Tree) SubtreeCount(p pager.Page) uint32 {
+	n := btpage.NumCells(p)
+	if isLeaf(p) {
+		return uint32(n)
+	}
+	total := uint32(0)
+	if !t.Acc.CopyUpLeaves() {
+		total += uint32(n) // B-tree internal cells are entries too
+	}
+	for i := 0; i <= n; i++ {
+		total += t.ChildCount(p, i, n)
+	}
+	return total
+}
+
+func (t *Tree) pageCount(id uint64) (uint32, error) {
+	p, err := t.Pg.Read(id)
+	if err != nil {
+		return 0, err
+	}
+	return t.SubtreeCount(p), nil
+}
+
+// childCounts carries the subtree counts of the two children around a cell
+// inserted into an internal page. It is ignored unless the tree is Counted.
+type childCounts struct{ left, right uint32 }
+
+// splitCounts returns the subtree counts of the two pages a child split into.
+func (t *Tree) splitCounts(leftID, rightID uint64) (childCounts, error) {
+	if !t.Counted {
+		return childCounts{}, nil
+	}
+	l, err := t.pageCount(leftID)
+	if err != nil {
+		return childCounts{}, err
+	}
+	r, err := t.pageCount(rightID)
+	return childCounts{l, r}, err
+}
+
+// contains reports whether the entry addressed by (key, value) is stored.
+func (t *Tree) contains(key int64, value []byte) (bool, error) {
+	curr := uint64(t.RootID)
+	for {
+		p, err := t.Pg.Read(curr)
+		if err != nil {
+			return false, err
+		}
+		n := btpage.NumCells(p)
+		leaf := isLeaf(p)
+		idx := t.Search(p, key, value, leaf)
+		if idx < n && t.matches(p, idx, leaf, key, value) {
+			return true, nil
+		}
+		if leaf {
+			return false, nil
+		}
+		curr = uint64(ChildAt(p, idx, n, t.Acc))
+	}
+}
+
+// countDelta returns how an insert (+1) or removal (-1) of (key, value)
+// changes the number of entries: 0 if it only overwrites or misses.
+func (t *Tree) countDelta(key int64, value []byte, insert bool) (int, error) {
+	if !t.Counted {
+		return 0, nil
+	}
+	found, err := t.contains(key, value)
+	switch {
+	case err != nil:
+		return 0, err
+	case insert && !found:
+		return 1, nil
+	case !insert && found:
+		return -1, nil
+	}
+	return 0, nil
+}
+
+// descend prepares internal page p for recursing into child idx: it shadows
+// the child in copy-on-write mode and applies delta to the child's count.
+// It returns the (possibly new) child page ID.
+func (t *Tree) descend(id uint64, p pager.Page, idx, n, delta int) (uint64, error) {
+	childID := uint64(ChildAt(p, idx, n, t.Acc))
+	if !t.CopyOnWrite && delta == 0 {
+		return childID, nil
+	}
+	if t.CopyOnWrite {
+		var err error
+		if childID, err = t.shadow(childID); err != nil {
+			return 0, err
+		}
+		setChildAt(p, idx, n, uint32(childID))
+	}
+	if delta != 0 {
+		t.setChildCount(p, idx, n, uint32(int(t.ChildCount(p, idx, n))+delta))
+	}
+	return childID, t.Pg.Write(id, p)
 }
 
 func DeleteCell(p pager.Page, i int) {
@@ -86,6 +316,17 @@ func ChildAt(p pager.Page, idx, n int, acc NodeAccessor) uint32 {
 	return lc
 }
 
+// setChildAt replaces the child pointer at idx of an internal page.
+// Both accessors store the left child in the first 4 bytes of a cell.
+func setChildAt(p pager.Page, idx, n int, id uint32) {
+	if idx == n {
+		btpage.SetRightmost(p, id)
+		return
+	}
+	off := int(btpage.CellPtr(p, idx))
+	binary.LittleEndian.PutUint32(p[off:off+4], id)
+}
+
 // FindIdx does a binary search within a database page to locate the index of a key.
 // It uses the NodeAccessor to read keys based on B-Tree type.
 func FindIdx(p pager.Page, key int64, n int, acc NodeAccessor, leaf bool) int {
@@ -102,9 +343,58 @@ func FindIdx(p pager.Page, key int64, n int, acc NodeAccessor, leaf bool) int {
 	return lo
 }
 
+// Search returns the index of the first cell on p that is not less than
+// (key, value). Outside duplicate mode only the key is compared and the
+// result matches FindIdx. A nil value sorts before every stored value, so
+// Search(p, key, nil, leaf) finds the first cell of a run of equal keys.
+func (t *Tree) Search(p pager.Page, key int64, value []byte, leaf bool) int {
+	n := btpage.NumCells(p)
+	if !t.Duplicates {
+		return FindIdx(p, key, n, t.Acc, leaf)
+	}
+	lo, hi := 0, n
+	for lo < hi {
+		m := (lo + hi) / 2
+		k, v, _ := t.Acc.ReadCell(p, m, leaf)
+		c := compareCells(k, v, key, value)
+		if c < 0 || (!leaf && c == 0 && t.Acc.CopyUpLeaves()) {
+			lo = m + 1
+		} else {
+			hi = m
+		}
+	}
+	return lo
+}
+
+// compareCells orders cells by key, then by value bytes.
+func compareCells(k1 int64, v1 []byte, k2 int64, v2 []byte) int {
+	switch {
+	case k1 < k2:
+		return -1
+	case k1 > k2:
+		return 1
+	}
+	return bytes.Compare(v1, v2)
+}
+
+// matches reports whether cell i of p is the entry addressed by (key, value).
+func (t *Tree) matches(p pager.Page, i int, leaf bool, key int64, value []byte) bool {
+	k, v, _ := t.Acc.ReadCell(p, i, leaf)
+	if k != key {
+		return false
+	}
+	return !t.Duplicates || bytes.Equal(v, value)
+}
+
 // Get retrieves the value associated with the specified key from the tree.
 func (t *Tree) Get(key int64) ([]byte, error) {
-	curr := uint64(t.RootID)
+	return t.GetFrom(t.RootID, key)
+}
+
+// GetFrom looks up key in the subtree rooted at root. It is used to read
+// from a snapshot root of a copy-on-write tree.
+func (t *Tree) GetFrom(root uint32, key int64) ([]byte, error) {
+	curr := uint64(root)
 	for {
 		p, err := t.Pg.Read(curr)
 		if err != nil {
@@ -129,76 +419,379 @@ func (t *Tree) Get(key int64) ([]byte, error) {
 // Insert adds a key-value pair to the tree. If the key already exists,
 // its value is updated.
 func (t *Tree) Insert(key int64, value []byte) error {
-	mk, mv, rightID, split, err := t.insertRec(uint64(t.RootID), key, value)
+	delta, err := t.countDelta(key, value, true)
+	if err != nil {
+		return err
+	}
+	root := uint64(t.RootID)
+	if t.CopyOnWrite {
+		if root, err = t.shadow(root); err != nil {
+			return err
+		}
+	}
+	mk, mv, rightID, split, err := t.insertRec(root, key, value, delta)
 	if err != nil {
 		return err
 	}
-	if !split {
+	return t.finish(root, mk, mv, rightID, split)
+}
+
+// finish grows a new root if the old one split and publishes the result.
+func (t *Tree) finish(root uint64, mk int64, mv []byte, rightID uint64, split bool) error {
+	if split {
+		t.rootSplits++
+		c, err := t.splitCounts(root, rightID)
+		if err != nil {
+			return err
+		}
+		newRoot, _ := t.Pg.Allocate()
+		p, _ := t.Pg.Read(newRoot)
+		btpage.InitPage(p, btpage.TypeInternal)
+		btpage.SetRightmost(p, uint32(rightID))
+		t.appendCell(p, CellData{mk, mv, uint32(root), c.left})
+		if t.Counted {
+			btpage.SetRightmostCount(p, c.right)
+		}
+		_ = t.Pg.Write(newRoot, p)
+		root = newRoot
+	}
+	if !t.CopyOnWrite && !split {
 		return nil
 	}
-	newRoot, _ := t.Pg.Allocate()
-	p, _ := t.Pg.Read(newRoot)
-	btpage.InitPage(p, btpage.TypeInternal)
-	btpage.SetRightmost(p, uint32(rightID))
-	t.AppendCell(p, mk, mv, t.RootID)
-	_ = t.Pg.Write(newRoot, p)
-	t.RootID = uint32(newRoot)
-	return t.WriteHeader()
+	return t.commitRoot(uint32(root))
 }
 
-func (t *Tree) insertRec(id uint64, key int64, value []byte) (int64, []byte, uint64, bool, error) {
+// shadow copies the page id into a freshly allocated page and returns the new
+// page ID. The copy belongs to the running insert and may be modified in place.
+func (t *Tree) shadow(id uint64) (uint64, error) {
+	p, err := t.Pg.Read(id)
+	if err != nil {
+		return 0, err
+	}
+	newID, err := t.Pg.Allocate()
+	if err != nil {
+		return 0, err
+	}
+	cp := make(pager.Page, len(p))
+	copy(cp, p)
+	return newID, t.Pg.Write(newID, cp)
+}
+
+// commitRoot publishes root as the new tree root. In copy-on-write mode with
+// a sync interval of n, only every n-th commit rewrites the header, after
+// syncing the new pages, so the header never points at pages that did not
+// reach the disk. The commits in between are published in memory and written
+// by the next durable commit or Close.
+func (t *Tree) commitRoot(root uint32) error {
+	t.RootID = root
+	if !t.CopyOnWrite || t.Pg.SyncInterval <= 0 {
+		return t.WriteHeader()
+	}
+	if t.commits++; t.commits < t.Pg.SyncInterval {
+		return nil
+	}
+	t.commits = 0
+	if err := t.Pg.Sync(); err != nil {
+		return err
+	}
+	if err := t.WriteHeader(); err != nil {
+		return err
+	}
+	return t.Pg.Sync()
+}
+
+// insertRec inserts into the subtree at id. delta is the change in the number
+// of entries (1 for a new entry, 0 for an overwrite) applied to child counts
+// on the way down.
+func (t *Tree) insertRec(id uint64, key int64, value []byte, delta int) (int64, []byte, uint64, bool, error) {
 	p, err := t.Pg.Read(id)
 	if err != nil {
 		return 0, nil, 0, false, err
 	}
 	n := btpage.NumCells(p)
 	leaf := isLeaf(p)
-	idx := FindIdx(p, key, n, t.Acc, leaf)
+	idx := t.Search(p, key, value, leaf)
 
 	// Handle existing key: overwrite in-place if value fits, else delete+reinsert.
-	if idx < n {
-		if k, oldVal, _ := t.Acc.ReadCell(p, idx, leaf); k == key {
+	// In duplicate mode an equal cell means the pair is already present.
+	if idx < n && t.Duplicates {
+		if t.matches(p, idx, leaf, key, value) {
+			return 0, nil, 0, false, nil
+		}
+	} else if idx < n {
+		if k, oldVal, lc := t.Acc.ReadCell(p, idx, leaf); k == key {
+			var c childCounts
+			if t.Counted && !leaf {
+				c = childCounts{t.ChildCount(p, idx, n), t.ChildCount(p, idx+1, n)}
+			}
 			if len(value) <= len(oldVal) {
 				t.Acc.OverwriteValue(p, idx, value, leaf)
+				if t.Counted && !leaf {
+					t.setCellCount(p, idx, c.left) // the count moves with the shorter value
+				}
 				return 0, nil, 0, false, t.Pg.Write(id, p)
 			}
 			DeleteCell(p, idx)
 			n--
+			if !leaf {
+				// Re-insert between the cell's original children so its left subtree stays attached.
+				right := ChildAt(p, idx, n, t.Acc)
+				setChildAt(p, idx, n, lc)
+				return t.doInsert(id, p, n, idx, key, value, uint64(right), c)
+			}
 		}
 	}
 
 	if leaf {
-		return t.doInsert(id, p, n, idx, key, value, 0)
+		return t.doInsert(id, p, n, idx, key, value, 0, childCounts{})
 	}
 
-	// Recurse into child.
-	childID := uint64(ChildAt(p, idx, n, t.Acc))
-	mk, mv, rc, split, err := t.insertRec(childID, key, value)
+	// Recurse into child. In copy-on-write mode the child is shadowed first and
+	// this (already shadowed) page is repointed at the copy.
+	childID, err := t.descend(id, p, idx, n, delta)
+	if err != nil {
+		return 0, nil, 0, false, err
+	}
+	mk, mv, rc, split, err := t.insertRec(childID, key, value, delta)
 	if err != nil || !split {
 		return 0, nil, 0, false, err
 	}
+	return t.absorbSplit(id, mk, mv, rc)
+}
 
+// absorbSplit inserts the separator of a child split into the internal page id.
+func (t *Tree) absorbSplit(id uint64, mk int64, mv []byte, rc uint64) (int64, []byte, uint64, bool, error) {
 	// Re-read after child write (page may have been evicted from cache).
+	p, err := t.Pg.Read(id)
+	if err != nil {
+		return 0, nil, 0, false, err
+	}
+	n := btpage.NumCells(p)
+	idx := t.Search(p, mk, mv, false)
+	c, err := t.splitCounts(uint64(ChildAt(p, idx, n, t.Acc)), rc)
+	if err != nil {
+		return 0, nil, 0, false, err
+	}
+	return t.doInsert(id, p, n, idx, mk, mv, rc, c)
+}
+
+// Remove deletes the entry for key. In duplicate mode only the cell holding
+// exactly (key, value) is removed; otherwise value is ignored.
+//
+// Pages are never merged or rebalanced: a leaf may become underfull or empty
+// and freed cell space is only reclaimed when a page is compacted. When an
+// entry lives in an internal B-tree node it is replaced by its in-order
+// predecessor, which may split the node if the predecessor is larger.
+func (t *Tree) Remove(key int64, value []byte) error {
+	delta, err := t.countDelta(key, value, false)
+	if err != nil {
+		return err
+	}
+	root := uint64(t.RootID)
+	if t.CopyOnWrite {
+		if root, err = t.shadow(root); err != nil {
+			return err
+		}
+	}
+	mk, mv, rightID, split, err := t.removeRec(root, key, value, delta)
+	if err != nil {
+		return err
+	}
+	return t.finish(root, mk, mv, rightID, split)
+}
+
+func (t *Tree) removeRec(id uint64, key int64, value []byte, delta int) (int64, []byte, uint64, bool, error) {
+	p, err := t.Pg.Read(id)
+	if err != nil {
+		return 0, nil, 0, false, err
+	}
+	n := btpage.NumCells(p)
+	leaf := isLeaf(p)
+	idx := t.Search(p, key, value, leaf)
+	match := idx < n && t.matches(p, idx, leaf, key, value)
+
+	if leaf {
+		if !match {
+			return 0, nil, 0, false, nil
+		}
+		DeleteCell(p, idx)
+		return 0, nil, 0, false, t.Pg.Write(id, p)
+	}
+
+	if match {
+		return t.removeInternal(id, p, n, idx)
+	}
+
+	childID, err := t.descend(id, p, idx, n, delta)
+	if err != nil {
+		return 0, nil, 0, false, err
+	}
+	mk, mv, rc, split, err := t.removeRec(childID, key, value, delta)
+	if err != nil || !split {
+		return 0, nil, 0, false, err
+	}
+
+	// A predecessor replacement further down split a node; absorb it like an insert.
+	return t.absorbSplit(id, mk, mv, rc)
+}
+
+// removeInternal deletes cell idx of an internal B-tree page and moves the
+// largest entry of its left subtree into its place.
+func (t *Tree) removeInternal(id uint64, p pager.Page, n, idx int) (int64, []byte, uint64, bool, error) {
+	left := uint64(ChildAt(p, idx, n, t.Acc))
+	right := ChildAt(p, idx+1, n, t.Acc)
+	var c childCounts
+	if t.Counted {
+		c = childCounts{t.ChildCount(p, idx, n) - 1, t.ChildCount(p, idx+1, n)}
+	}
+	if t.CopyOnWrite {
+		var err error
+		if left, err = t.shadow(left); err != nil {
+			return 0, nil, 0, false, err
+		}
+	}
+	pk, pv, ok, err := t.removeMax(left)
+	if err != nil {
+		return 0, nil, 0, false, err
+	}
+
 	p, err = t.Pg.Read(id)
 	if err != nil {
 		return 0, nil, 0, false, err
 	}
-	n = btpage.NumCells(p)
-	idx = FindIdx(p, mk, n, t.Acc, false)
+	DeleteCell(p, idx)
+	n--
+	if !ok {
+		// The left subtree holds no entries; drop it and let slot idx point right.
+		return 0, nil, 0, false, t.Pg.Write(id, p)
+	}
+
+	// Slot idx now refers to the right child. Point it back at the left
+	// subtree so doInsert places the predecessor between left and right.
+	setChildAt(p, idx, n, uint32(left))
+	return t.doInsert(id, p, n, idx, pk, pv, uint64(right), c)
+}
+
+// removeMax deletes and returns the largest entry in the subtree rooted at id.
+// ok is false if the subtree holds no entries.
+func (t *Tree) removeMax(id uint64) (key int64, value []byte, ok bool, err error) {
+	p, err := t.Pg.Read(id)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	n := btpage.NumCells(p)
+	if isLeaf(p) {
+		if n == 0 {
+			return 0, nil, false, nil
+		}
+		key, value, _ = t.Acc.ReadCell(p, n-1, true)
+		DeleteCell(p, n-1)
+		return key, value, true, t.Pg.Write(id, p)
+	}
+
+	right := uint64(btpage.Rightmost(p))
+	if t.CopyOnWrite {
+		if right, err = t.shadow(right); err != nil {
+			return 0, nil, false, err
+		}
+		btpage.SetRightmost(p, uint32(right))
+		if err := t.Pg.Write(id, p); err != nil {
+			return 0, nil, false, err
+		}
+	}
+	if key, value, ok, err = t.removeMax(right); err != nil {
+		return 0, nil, false, err
+	}
+	if !ok && n == 0 {
+		return 0, nil, false, nil
+	}
+
+	p, err = t.Pg.Read(id)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	if ok {
+		if t.Counted {
+			btpage.SetRightmostCount(p, btpage.RightmostCount(p)-1)
+			return key, value, true, t.Pg.Write(id, p)
+		}
+		return key, value, true, nil
+	}
+
+	// The rightmost subtree is empty: the last cell is the maximum. Its left
+	// child takes over as the rightmost child.
+	var lcCount uint32
+	if t.Counted {
+		lcCount = t.cellCount(p, n-1)
+	}
+	key, value, lc := t.Acc.ReadCell(p, n-1, false)
+	DeleteCell(p, n-1)
+	btpage.SetRightmost(p, lc)
+	if t.Counted {
+		btpage.SetRightmostCount(p, lcCount)
+	}
+	return key, value, true, t.Pg.Write(id, p)
+}
+
+// FragmentedBytes returns the number of bytes in the cell content area of p
+// that no longer belong to a live cell.
+func (t *Tree) FragmentedBytes(p pager.Page) int {
+	n := btpage.NumCells(p)
+	leaf := isLeaf(p)
+	live := 0
+	for i := 0; i < n; i++ {
+		_, v, _ := t.Acc.ReadCell(p, i, leaf)
+		live += t.sizeOf(leaf, v)
+	}
+	return len(p) - int(btpage.CellContent(p)) - live
+}
 
-	return t.doInsert(id, p, n, idx, mk, mv, rc)
+// compact rewrites p so that space left behind by deleted cells becomes free again.
+func (t *Tree) compact(p pager.Page) {
+	n := btpage.NumCells(p)
+	cells := t.readCells(p, n)
+	pageType := p[btpage.OffType]
+	rightmost := btpage.Rightmost(p)
+	next := btpage.NextLeaf(p) // also the rightmost count of counted internal pages
+	btpage.InitPage(p, pageType)
+	btpage.SetRightmost(p, rightmost)
+	btpage.SetNextLeaf(p, next)
+	for _, c := range cells {
+		t.appendCell(p, c)
+	}
 }
 
 type CellData struct {
 	Key       int64
 	Value     []byte
 	LeftChild uint32
+	Count     uint32 // entries below LeftChild (counted internal cells only)
 }
 
-func (t *Tree) doInsert(id uint64, p pager.Page, n, idx int, key int64, value []byte, rightChild uint64) (int64, []byte, uint64, bool, error) {
+// readCells decodes the first n cells of p, including subtree counts.
+func (t *Tree) readCells(p pager.Page, n int) []CellData {
+	leaf := isLeaf(p)
+	cells := make([]CellData, n)
+	for i := 0; i < n; i++ {
+		k, v, lc := t.Acc.ReadCell(p, i, leaf)
+		cells[i] = CellData{k, v, lc, 0}
+		if t.Counted && !leaf {
+			cells[i].Count = t.cellCount(p, i)
+		}
+	}
+	return cells
+}
+
+func (t *Tree) doInsert(id uint64, p pager.Page, n, idx int, key int64, value []byte, rightChild uint64, c childCounts) (int64, []byte, uint64, bool, error) {
 	leaf := isLeaf(p)
 
-	if btpage.FreeSpace(p, n) >= t.Acc.CellSize(leaf, value) {
+	// The new cell also needs a slot in the cell pointer array. Space left
+	// behind by deleted or overwritten cells is reclaimed before splitting.
+	need := t.sizeOf(leaf, value) + btpage.CellPtrSize
+	if btpage.FreeSpace(p, n) < need && t.FragmentedBytes(p) > 0 {
+		t.compact(p)
+	}
+	if btpage.FreeSpace(p, n) >= need {
 		for i := n; i > idx; i-- {
 			btpage.SetCellPtr(p, i, btpage.CellPtr(p, i-1))
 		}
@@ -207,7 +800,7 @@ func (t *Tree) doInsert(id uint64, p pager.Page, n, idx int, key int64, value []
 		if !leaf {
 			leftChild = ChildAt(p, idx, n, t.Acc)
 		}
-		off := btpage.AllocCell(p, t.Acc.CellSize(leaf, value))
+		off := btpage.AllocCell(p, t.sizeOf(leaf, value))
 		t.Acc.WriteCell(p, off, key, value, leftChild, leaf)
 		btpage.SetCellPtr(p, idx, uint16(off))
 
@@ -218,35 +811,40 @@ func (t *Tree) doInsert(id uint64, p pager.Page, n, idx int, key int64, value []
 			binary.LittleEndian.PutUint32(p[off1:off1+4], uint32(rightChild))
 		}
 		btpage.SetNumCells(p, n+1)
+		if t.Counted && !leaf {
+			t.setChildCount(p, idx, n+1, c.left)
+			t.setChildCount(p, idx+1, n+1, c.right)
+		}
 		return 0, nil, 0, false, t.Pg.Write(id, p)
 	}
 
-	return t.splitNode(id, p, n, idx, key, value, rightChild)
+	return t.splitNode(id, p, n, idx, key, value, rightChild, c)
 }
 
-func (t *Tree) splitNode(id uint64, p pager.Page, n, idx int, key int64, value []byte, rightChild uint64) (int64, []byte, uint64, bool, error) {
+func (t *Tree) splitNode(id uint64, p pager.Page, n, idx int, key int64, value []byte, rightChild uint64, c childCounts) (int64, []byte, uint64, bool, error) {
+	t.splits++
 	leaf := isLeaf(p)
 	pageType := p[btpage.OffType] // cache before InitPage zeroes it
 
 	all := make([]CellData, n+1)
-	for i := 0; i < n; i++ {
-		k, v, lc := t.Acc.ReadCell(p, i, leaf)
-		all[i] = CellData{k, v, lc}
-	}
+	copy(all, t.readCells(p, n))
 	copy(all[idx+1:], all[idx:n])
 	insertLC := uint32(0)
 	if !leaf {
 		insertLC = ChildAt(p, idx, n, t.Acc)
 	}
-	all[idx] = CellData{key, value, insertLC}
+	all[idx] = CellData{key, value, insertLC, c.left}
 
 	oldRightmost := btpage.Rightmost(p)
 	oldNext := btpage.NextLeaf(p)
+	oldRightmostCount := btpage.RightmostCount(p) // same bytes as oldNext; only meaningful when counted
 	if !leaf {
 		if idx == n {
 			oldRightmost = uint32(rightChild)
+			oldRightmostCount = c.right
 		} else {
 			all[idx+1].LeftChild = uint32(rightChild)
+			all[idx+1].Count = c.right
 		}
 	}
 
@@ -264,7 +862,7 @@ func (t *Tree) splitNode(id uint64, p pager.Page, n, idx int, key int64, value [
 
 		// LEFT
 		for i := 0; i < mid; i++ {
-			t.AppendCell(p, all[i].Key, all[i].Value, all[i].LeftChild)
+			t.appendCell(p, all[i])
 		}
 
 		// RIGHT (copy-up semantics)
@@ -273,7 +871,7 @@ func (t *Tree) splitNode(id uint64, p pager.Page, n, idx int, key int64, value [
 			start = mid + 1
 		}
 		for i := start; i <= n; i++ {
-			t.AppendCell(right, all[i].Key, all[i].Value, all[i].LeftChild)
+			t.appendCell(right, all[i])
 		}
 
 		// Link leaves using the previous next pointer (not rightmost).
@@ -283,15 +881,20 @@ func (t *Tree) splitNode(id uint64, p pager.Page, n, idx int, key int64, value [
 
 		// LEFT keeps 0..mid-1
 		for i := 0; i < mid; i++ {
-			t.AppendCell(p, all[i].Key, all[i].Value, all[i].LeftChild)
+			t.appendCell(p, all[i])
 		}
 		btpage.SetRightmost(p, all[mid].LeftChild)
 
 		// RIGHT keeps mid+1..n
 		for i := mid + 1; i <= n; i++ {
-			t.AppendCell(right, all[i].Key, all[i].Value, all[i].LeftChild)
+			t.appendCell(right, all[i])
 		}
 		btpage.SetRightmost(right, oldRightmost)
+
+		if t.Counted {
+			btpage.SetRightmostCount(p, all[mid].Count)
+			btpage.SetRightmostCount(right, oldRightmostCount)
+		}
 	}
 
 	_ = t.Pg.Write(id, p)
@@ -299,7 +902,7 @@ func (t *Tree) splitNode(id uint64, p pager.Page, n, idx int, key int64, value [
 
 	// B+ tree copy-up uses separator keys only (no value).
 	promotedVal := promoted.Value
-	if leaf && t.Acc.CopyUpLeaves() {
+	if leaf && t.Acc.CopyUpLeaves() && !t.Duplicates {
 		promotedVal = nil
 	}
 	return promoted.Key, promotedVal, newID, true, nil
@@ -307,7 +910,12 @@ func (t *Tree) splitNode(id uint64, p pager.Page, n, idx int, key int64, value [
 
 // FindLeaf locates the leaf page that would contain the given key.
 func (t *Tree) FindLeaf(key int64) (uint64, error) {
-	curr := uint64(t.RootID)
+	return t.FindLeafFrom(t.RootID, key)
+}
+
+// FindLeafFrom locates the leaf page that would contain key in the subtree rooted at root.
+func (t *Tree) FindLeafFrom(root uint32, key int64) (uint64, error) {
+	curr := uint64(root)
 	for {
 		p, err := t.Pg.Read(curr)
 		if err != nil {
@@ -317,7 +925,7 @@ func (t *Tree) FindLeaf(key int64) (uint64, error) {
 			return curr, nil
 		}
 		n := btpage.NumCells(p)
-		idx := FindIdx(p, key, n, t.Acc, false)
+		idx := t.Search(p, key, nil, false)
 		curr = uint64(ChildAt(p, idx, n, t.Acc))
 	}
 }
@@ -328,7 +936,15 @@ func (t *Tree) WriteHeader() error {
 	if err != nil {
 		return err
 	}
-	binary.LittleEndian.PutUint32(p[:4], t.RootID)
+	binary.LittleEndian.PutUint32(p[offHeaderRoot:offHeaderRoot+4], t.RootID)
+	flags := byte(0)
+	if t.Duplicates {
+		flags |= FlagDuplicates
+	}
+	if t.Counted {
+		flags |= FlagCounts
+	}
+	p[offHeaderFlags] = flags
 	return t.Pg.Write(1, p)
 }
 
@@ -338,10 +954,112 @@ func (t *Tree) ReadHeader() error {
 	if err != nil {
 		return err
 	}
-	t.RootID = binary.LittleEndian.Uint32(p[:4])
+	t.RootID = binary.LittleEndian.Uint32(p[offHeaderRoot : offHeaderRoot+4])
+	t.Duplicates = p[offHeaderFlags]&FlagDuplicates != 0
+	t.Counted = p[offHeaderFlags]&FlagCounts != 0
 	return nil
 }
 
+// --- Order statistics ---
+
+// Rank returns the number of entries whose key is less than key.
+func (t *Tree) Rank(key int64) (int, error) {
+	if !t.Counted {
+		return 0, ErrNoCounts
+	}
+	rank := 0
+	curr := uint64(t.RootID)
+	for {
+		p, err := t.Pg.Read(curr)
+		if err != nil {
+			return 0, err
+		}
+		n := btpage.NumCells(p)
+		if isLeaf(p) {
+			return rank + t.Search(p, key, nil, true), nil
+		}
+		// Every child left of idx (and, in a B-tree, every cell left of idx) is smaller than key.
+		idx := t.Search(p, key, nil, false)
+		for i := 0; i < idx; i++ {
+			rank += int(t.cellCount(p, i))
+		}
+		if !t.Acc.CopyUpLeaves() {
+			rank += idx
+		}
+		curr = uint64(ChildAt(p, idx, n, t.Acc))
+	}
+}
+
+// Count returns the number of entries with start <= key <= end.
+func (t *Tree) Count(start, end int64) (int, error) {
+	if !t.Counted {
+		return 0, ErrNoCounts
+	}
+	if start > end {
+		return 0, nil
+	}
+	lo, err := t.Rank(start)
+	if err != nil {
+		return 0, err
+	}
+	var hi int
+	if end == math.MaxInt64 {
+		p, err := t.Pg.Read(uint64(t.RootID))
+		if err != nil {
+			return 0, err
+		}
+		hi = int(t.SubtreeCount(p))
+	} else if hi, err = t.Rank(end + 1); err != nil {
+		return 0, err
+	}
+	return hi - lo, nil
+}
+
+// Select returns the entry at zero-based position k in key order.
+func (t *Tree) Select(k int) (int64, []byte, error) {
+	if !t.Counted {
+		return 0, nil, ErrNoCounts
+	}
+	if k < 0 {
+		return 0, nil, ErrOutOfRange
+	}
+	curr := uint64(t.RootID)
+	for {
+		p, err := t.Pg.Read(curr)
+		if err != nil {
+			return 0, nil, err
+		}
+		n := btpage.NumCells(p)
+		if isLeaf(p) {
+			if k >= n {
+				return 0, nil, ErrOutOfRange
+			}
+			key, val, _ := t.Acc.ReadCell(p, k, true)
+			return key, val, nil
+		}
+		child := -1
+		for i := 0; i <= n && child < 0; i++ {
+			c := int(t.ChildCount(p, i, n))
+			if k < c {
+				child = i
+				break
+			}
+			k -= c
+			if i < n && !t.Acc.CopyUpLeaves() {
+				if k == 0 {
+					key, val, _ := t.Acc.ReadCell(p, i, false)
+					return key, val, nil
+				}
+				k--
+			}
+		}
+		if child < 0 {
+			return 0, nil, ErrOutOfRange
+		}
+		curr = uint64(ChildAt(p, child, n, t.Acc))
+	}
+}
+
 // --- Visualization and Info ---
 
 // Height returns the current height of the tree.
@@ -0,0 +1,208 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btree-query-bench/bmark/dbms/index/btpage"
+	"github.com/btree-query-bench/bmark/dbms/pager"
+)
+
+// ErrCorrupt is wrapped by the errors Verify returns for a violated invariant.
+var ErrCorrupt = errors.New("shared: tree is corrupt")
+
+// bound is an optional inclusive limit on the entries of a subtree.
+type bound struct {
+	key   int64
+	value []byte
+	ok    bool
+}
+
+type verifyState struct {
+	seen      map[uint64]bool
+	leafDepth int
+	leaves    []uint64 // leaf page IDs in key order
+	entries   int
+	prev      bound // last entry visited in key order
+}
+
+// Verify walks the whole tree and checks its structural invariants: every
+// page is referenced once and has a valid type and cell area, all leaves are
+// at the same depth, entries are strictly ascending (by key, or by key and
+// value in Duplicates mode) and lie between the separators of their parent,
+// subtree counts match in Counted mode and, unless CopyOnWrite is set, the
+// B+ tree leaf chain links the leaves in key order. It returns the number of
+// entries. Violations are reported as errors wrapping ErrCorrupt.
+func (t *Tree) Verify() (n int, err error) {
+	// A corrupt cell can point outside its page; report that instead of crashing.
+	defer func() {
+		if r := recover(); r != nil {
+			n, err = 0, fmt.Errorf("%w: %v", ErrCorrupt, r)
+		}
+	}()
+
+	st := &verifyState{seen: make(map[uint64]bool), leafDepth: -1}
+	if _, err := t.verifyRec(uint64(t.RootID), 0, bound{}, bound{}, st); err != nil {
+		return 0, err
+	}
+	if t.Acc.CopyUpLeaves() && !t.CopyOnWrite {
+		if err := t.verifyLeafChain(st.leaves); err != nil {
+			return 0, err
+		}
+	}
+	return st.entries, nil
+}
+
+// compare orders two entries the way the tree does.
+func (t *Tree) compare(k1 int64, v1 []byte, k2 int64, v2 []byte) int {
+	if t.Duplicates {
+		return compareCells(k1, v1, k2, v2)
+	}
+	return compareCells(k1, nil, k2, nil)
+}
+
+func corrupt(id uint64, format string, args ...any) error {
+	return fmt.Errorf("%w: page %d: %s", ErrCorrupt, id, fmt.Sprintf(format, args...))
+}
+
+// verifyRec checks the subtree at id, whose entries must lie in [lo, hi],
+// and returns the number of entries in it.
+func (t *Tree) verifyRec(id uint64, depth int, lo, hi bound, st *verifyState) (uint32, error) {
+	if id < 2 || id >= t.Pg.PageCount() {
+		return 0, corrupt(id, "page ID out of range [2, %d)", t.Pg.PageCount())
+	}
+	if st.seen[id] {
+		return 0, corrupt(id, "referenced more than once")
+	}
+	st.seen[id] = true
+
+	p, err := t.Pg.Read(id)
+	if err != nil {
+		return 0, err
+	}
+	if typ := p[btpage.OffType]; typ != btpage.TypeLeaf && typ != btpage.TypeInternal {
+		return 0, corrupt(id, "unknown page type %d", typ)
+	}
+	n := btpage.NumCells(p)
+	if err := t.verifyCellArea(id, p, n); err != nil {
+		return 0, err
+	}
+	leaf := isLeaf(p)
+
+	checkBounds := func(k int64, v []byte) error {
+		if lo.ok && t.compare(k, v, lo.key, lo.value) < 0 {
+			return corrupt(id, "key %d below separator %d of parent", k, lo.key)
+		}
+		if hi.ok && t.compare(k, v, hi.key, hi.value) > 0 {
+			return corrupt(id, "key %d above separator %d of parent", k, hi.key)
+		}
+		return nil
+	}
+	visit := func(k int64, v []byte) error {
+		if err := checkBounds(k, v); err != nil {
+			return err
+		}
+		if st.prev.ok && t.compare(k, v, st.prev.key, st.prev.value) <= 0 {
+			return corrupt(id, "key %d out of order after key %d", k, st.prev.key)
+		}
+		st.prev = bound{k, v, true}
+		st.entries++
+		return nil
+	}
+
+	if leaf {
+		if st.leafDepth < 0 {
+			st.leafDepth = depth
+		} else if depth != st.leafDepth {
+			return 0, corrupt(id, "leaf at depth %d, want %d", depth, st.leafDepth)
+		}
+		st.leaves = append(st.leaves, id)
+		for i := 0; i < n; i++ {
+			k, v, _ := t.Acc.ReadCell(p, i, true)
+			if err := visit(k, v); err != nil {
+				return 0, err
+			}
+		}
+		return uint32(n), nil
+	}
+
+	// Visit child 0, cell 0, child 1, …, cell n-1, child n. B-tree cells are
+	// entries themselves; B+ tree cells are separators only.
+	total := uint32(0)
+	left := lo
+	var prevSep bound
+	for i := 0; i <= n; i++ {
+		right := hi
+		var k int64
+		var v []byte
+		if i < n {
+			k, v, _ = t.Acc.ReadCell(p, i, false)
+			right = bound{k, v, true}
+			if prevSep.ok && t.compare(k, v, prevSep.key, prevSep.value) <= 0 {
+				return 0, corrupt(id, "separator %d not above separator %d", k, prevSep.key)
+			}
+			prevSep = right
+		}
+		child := uint64(ChildAt(p, i, n, t.Acc))
+		c, err := t.verifyRec(child, depth+1, left, right, st)
+		if err != nil {
+			return 0, err
+		}
+		if t.Counted {
+			if stored := t.ChildCount(p, i, n); stored != c {
+				return 0, corrupt(id, "child %d has %d entries, count says %d", i, c, stored)
+			}
+		}
+		total += c
+		if i == n {
+			break
+		}
+		if t.Acc.CopyUpLeaves() {
+			if err := checkBounds(k, v); err != nil {
+				return 0, err
+			}
+		} else {
+			if err := visit(k, v); err != nil {
+				return 0, err
+			}
+			total++
+		}
+		left = right
+	}
+	return total, nil
+}
+
+// verifyCellArea checks that the cell pointers and the cell content area fit on the page.
+func (t *Tree) verifyCellArea(id uint64, p pager.Page, n int) error {
+	if btpage.FreeSpace(p, n) < 0 {
+		return corrupt(id, "%d cell pointers overlap the cell content area", n)
+	}
+	content := int(btpage.CellContent(p))
+	if content > len(p) {
+		return corrupt(id, "cell content offset %d beyond page size %d", content, len(p))
+	}
+	for i := 0; i < n; i++ {
+		if off := int(btpage.CellPtr(p, i)); off < content || off >= len(p) {
+			return corrupt(id, "cell %d at offset %d outside the cell content area [%d, %d)", i, off, content, len(p))
+		}
+	}
+	return nil
+}
+
+// verifyLeafChain checks that the nextLeaf pointers link leaves in key order.
+func (t *Tree) verifyLeafChain(leaves []uint64) error {
+	for i, id := range leaves {
+		p, err := t.Pg.Read(id)
+		if err != nil {
+			return err
+		}
+		want := btpage.InvalidPage
+		if i+1 < len(leaves) {
+			want = uint32(leaves[i+1])
+		}
+		if next := btpage.NextLeaf(p); next != want {
+			return corrupt(id, "next leaf is %d, want %d", next, want)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package sortedarray implements an in-memory index backed by a sorted slice.
+//
+// Lookups use binary search, so Get and the start of a Range are O(log n).
+// Inserts and deletes of keys that are not at the end shift the tail of the
+// slice and are O(n). It serves as a baseline that has no page format, no
+// pager and no tree, only the search algorithm.
+package sortedarray
+
+import (
+	"sort"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+)
+
+var _ index.Index = (*SortedArray)(nil)
+
+// SortedArray keeps keys and values in two parallel slices ordered by key.
+type SortedArray struct {
+	keys []int64
+	vals [][]byte
+}
+
+// NewSortedArray creates a new empty SortedArray.
+func NewSortedArray() *SortedArray {
+	return &SortedArray{}
+}
+
+// search returns the position of the first key >= key.
+func (a *SortedArray) search(key int64) int {
+	return sort.Search(len(a.keys), func(i int) bool { return a.keys[i] >= key })
+}
+
+func (a *SortedArray) Insert(key int64, value []byte) error {
+	// Fast path for ascending bulk loads.
+	if n := len(a.keys); n == 0 || a.keys[n-1] < key {
+		a.keys = append(a.keys, key)
+		a.vals = append(a.vals, value)
+		return nil
+	}
+	i := a.search(key)
+	if a.keys[i] == key {
+		a.vals[i] = value
+		return nil
+	}
+	a.keys = append(a.keys, 0)
+	a.vals = append(a.vals, nil)
+	copy(a.keys[i+1:], a.keys[i:])
+	copy(a.vals[i+1:], a.vals[i:])
+	a.keys[i] = key
+	a.vals[i] = value
+	return nil
+}
+
+func (a *SortedArray) Get(key int64) ([]byte, error) {
+	i := a.search(key)
+	if i < len(a.keys) && a.keys[i] == key {
+		return a.vals[i], nil
+	}
+	return nil, nil
+}
+
+func (a *SortedArray) Delete(key int64) error {
+	i := a.search(key)
+	if i == len(a.keys) || a.keys[i] != key {
+		return nil
+	}
+	a.keys = append(a.keys[:i], a.keys[i+1:]...)
+	a.vals[i] = nil
+	a.vals = append(a.vals[:i], a.vals[i+1:]...)
+	return nil
+}
+
+func (a *SortedArray) Range(start, end int64) (index.Iterator, error) {
+	return &Iterator{arr: a, cur: a.search(start) - 1, end: end}, nil
+}
+
+func (a *SortedArray) Close() error { return nil }
+
+// Len returns the number of stored keys.
+func (a *SortedArray) Len() int { return len(a.keys) }
+
+// Iterator walks the sorted slice from the first key >= start.
+type Iterator struct {
+	arr *SortedArray
+	cur int
+	end int64
+}
+
+// Next advances the iterator to the next key-value pair.
+func (it *Iterator) Next() bool {
+	it.cur++
+	return it.cur < len(it.arr.keys) && it.arr.keys[it.cur] <= it.end
+}
+
+// Key returns the key of the current key-value pair.
+func (it *Iterator) Key() int64 { return it.arr.keys[it.cur] }
+
+// Value returns the value of the current key-value pair.
+func (it *Iterator) Value() []byte { return it.arr.vals[it.cur] }
+
+// Error returns the first error encountered by the iterator, if any.
+func (it *Iterator) Error() error { return nil }
+
+// Close releases resources associated with the iterator.
+func (it *Iterator) Close() error { return nil }
@@ -4,6 +4,7 @@ package pager
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"os"
 )
 
@@ -18,6 +19,19 @@ type Pager struct {
 	PageSize     uint32 // size of each page in bytes
 	writeCount   int    // count of writes since last sync
 	SyncInterval int    // sync to disk every n writes. 0 means no sync, 1 means sync always.
+	stats        Stats
+}
+
+// Stats counts the cache and disk activity of a pager.
+type Stats struct {
+	CacheHits    uint64 // Read calls served from the cache
+	CacheMisses  uint64 // Read calls that went to disk
+	Evictions    uint64 // pages dropped from the cache
+	PageReads    uint64 // pages read from disk
+	PageWrites   uint64 // pages written to disk, including allocation and the page count header
+	Syncs        uint64 // fsync calls
+	BytesRead    uint64
+	BytesWritten uint64
 }
 
 // Open opens (or creates) a pager backed by the file at the given path.
@@ -35,17 +49,21 @@ func Open(path string, cachePages int, pageSize uint32) (*Pager, error) {
 		SyncInterval: 10000,
 	}
 
-	exists, err := p.fileExists()
+	size, err := p.fileSize()
 	if err != nil {
 		f.Close()
 		return nil, err
 	}
 
-	if exists {
+	if size > 0 {
 		if err := p.readPageCount(); err != nil {
 			f.Close()
 			return nil, fmt.Errorf("read page count: %w", err)
 		}
+		// The count in page 0 is only written on Close. After a crash the
+		// file can hold pages allocated since, which may be referenced by a
+		// committed root; never hand them out again.
+		p.pageCount = max(p.pageCount, uint64(size)/uint64(pageSize))
 	} else {
 		p.pageCount = 1
 		if err := p.writePageCount(); err != nil {
@@ -57,12 +75,37 @@ func Open(path string, cachePages int, pageSize uint32) (*Pager, error) {
 	return p, nil
 }
 
-func (p *Pager) fileExists() (bool, error) {
+// PageSizeOf returns the page size of the existing pager file at path. The
+// size is not stored in the file; it is derived from the file length and the
+// page count in page 0, which is only up to date after a clean Close.
+func PageSizeOf(path string) (uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("opening pager file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stat file: %w", err)
+	}
+	var hdr [8]byte
+	if _, err := f.ReadAt(hdr[:], 0); err != nil {
+		return 0, fmt.Errorf("read page count: %w", err)
+	}
+	count := binary.LittleEndian.Uint64(hdr[:])
+	size := uint64(info.Size())
+	if count == 0 || size%count != 0 || size/count > math.MaxUint32 {
+		return 0, fmt.Errorf("pager: %s: file size %d is not a multiple of the page count %d", path, size, count)
+	}
+	return uint32(size / count), nil
+}
+
+func (p *Pager) fileSize() (int64, error) {
 	info, err := p.file.Stat()
 	if err != nil {
-		return false, fmt.Errorf("stat file: %w", err)
+		return 0, fmt.Errorf("stat file: %w", err)
 	}
-	return info.Size() > 0, nil
+	return info.Size(), nil
 }
 
 func (p *Pager) readPageCount() error {
@@ -90,8 +133,10 @@ func (p *Pager) Allocate() (uint64, error) {
 // Read returns the page with the given ID, from cache or disk.
 func (p *Pager) Read(id uint64) (Page, error) {
 	if pg := p.cache.get(id); pg != nil {
+		p.stats.CacheHits++
 		return pg, nil
 	}
+	p.stats.CacheMisses++
 	pg, err := p.readPageFromDisk(id)
 	if err != nil {
 		return nil, err
@@ -100,12 +145,32 @@ func (p *Pager) Read(id uint64) (Page, error) {
 	return pg, nil
 }
 
+// Peek returns the page with the given ID like Read, but leaves the cache
+// order and the statistics as they were and does not cache a page it reads
+// from disk. The page must not be modified.
+func (p *Pager) Peek(id uint64) (Page, error) {
+	if e, ok := p.cache.items[id]; ok {
+		return e.page, nil
+	}
+	pg := make(Page, p.PageSize)
+	if _, err := p.file.ReadAt(pg, p.offset(id)); err != nil {
+		return nil, fmt.Errorf("pager: read page %d: %w", id, err)
+	}
+	return pg, nil
+}
+
 // Write writes a page back to disk and updates the cache.
 func (p *Pager) Write(id uint64, pg Page) error {
 	p.cache.put(id, pg)
 	return p.writePageToDisk(id, pg)
 }
 
+// Sync forces all written pages to stable storage.
+func (p *Pager) Sync() error {
+	p.stats.Syncs++
+	return p.file.Sync()
+}
+
 // Close flushes and closes the underlying file.
 func (p *Pager) Close() error {
 	_ = p.writePageCount()
@@ -117,6 +182,19 @@ func (p *Pager) PageCount() uint64 {
 	return p.pageCount
 }
 
+// Stats returns the activity since the pager was opened or ResetStats was called.
+func (p *Pager) Stats() Stats {
+	s := p.stats
+	s.Evictions = p.cache.evictions
+	return s
+}
+
+// ResetStats sets all counters of Stats to zero.
+func (p *Pager) ResetStats() {
+	p.stats = Stats{}
+	p.cache.evictions = 0
+}
+
 // SetSyncInterval sets the number of writes after which the pager should sync to disk.
 // 0 means no sync (except on Close), 1 means sync every write.
 func (p *Pager) SetSyncInterval(n int) {
@@ -135,6 +213,8 @@ func (p *Pager) readPageFromDisk(id uint64) (Page, error) {
 	if err != nil {
 		return nil, fmt.Errorf("pager: read page %d: %w", id, err)
 	}
+	p.stats.PageReads++
+	p.stats.BytesRead += uint64(len(pg))
 	return pg, nil
 }
 
@@ -143,11 +223,13 @@ func (p *Pager) writePageToDisk(id uint64, pg Page) error {
 	if err != nil {
 		return fmt.Errorf("pager: write page %d: %w", id, err)
 	}
+	p.stats.PageWrites++
+	p.stats.BytesWritten += uint64(len(pg))
 
 	if p.SyncInterval > 0 {
 		p.writeCount++
 		if p.writeCount%p.SyncInterval == 0 {
-			return p.file.Sync()
+			return p.Sync()
 		}
 	}
 	return nil
@@ -175,10 +257,11 @@ type lruEntry struct {
 }
 
 type lruCache struct {
-	cap   int                  // Max number of pages in cache
-	items map[uint64]*lruEntry // Fast O(1) lookup map
-	head  *lruEntry            // Pointer to the MRU node
-	tail  *lruEntry            // Pointer to the LRU node
+	cap       int                  // Max number of pages in cache
+	items     map[uint64]*lruEntry // Fast O(1) lookup map
+	head      *lruEntry            // Pointer to the MRU node
+	tail      *lruEntry            // Pointer to the LRU node
+	evictions uint64               // Number of evicted entries
 }
 
 func newLRUCache(cap int) *lruCache {
@@ -249,6 +332,7 @@ func (c *lruCache) evict() {
 		return
 	}
 	delete(c.items, c.tail.id)
+	c.evictions++
 	if c.tail.prev != nil {
 		c.tail.prev.next = nil
 	}
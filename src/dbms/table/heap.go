@@ -0,0 +1,305 @@
+package table
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btree-query-bench/bmark/dbms/pager"
+)
+
+// Heap page layout:
+//
+//	[0-1]  uint16  number of slots
+//	[2-3]  uint16  start of the record area (records grow downward from the page end)
+//	[4+]   slot array, 4 bytes per slot: uint16 record offset, uint16 record length
+//
+// A slot with offset 0 is free. Slots are never removed, so a RID stays
+// valid until its record is deleted; freed slots are reused by later inserts.
+const (
+	offNumSlots   = 0
+	offRecordArea = 2
+	offSlots      = 4
+	slotSize      = 4
+)
+
+// ErrRecordNotFound is returned when a RID does not refer to a live record.
+var ErrRecordNotFound = errors.New("table: record not found")
+
+// RID identifies a record by its heap page and slot.
+type RID struct {
+	Page uint32
+	Slot uint16
+}
+
+// Bytes encodes the RID in 6 bytes so it can be stored as an index value.
+func (r RID) Bytes() []byte {
+	b := make([]byte, 6)
+	binary.LittleEndian.PutUint32(b[0:4], r.Page)
+	binary.LittleEndian.PutUint16(b[4:6], r.Slot)
+	return b
+}
+
+// ParseRID decodes a RID produced by RID.Bytes.
+func ParseRID(b []byte) (RID, error) {
+	if len(b) != 6 {
+		return RID{}, fmt.Errorf("table: invalid rid length %d", len(b))
+	}
+	return RID{
+		Page: binary.LittleEndian.Uint32(b[0:4]),
+		Slot: binary.LittleEndian.Uint16(b[4:6]),
+	}, nil
+}
+
+func (r RID) String() string { return fmt.Sprintf("(%d,%d)", r.Page, r.Slot) }
+
+// HeapFile stores variable-length records in slotted pages managed by a Pager.
+// New records go to the last page; a new page is allocated when it is full.
+// Space freed by deletes is reclaimed by compacting a page when a record is
+// inserted into or grows within it.
+type HeapFile struct {
+	Pg       *pager.Pager
+	lastPage uint64
+}
+
+// OpenHeap opens (or creates) a heap file at the given path.
+func OpenHeap(path string, cachePages int, pageSize uint32) (*HeapFile, error) {
+	pg, err := pager.Open(path, cachePages, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	h := &HeapFile{Pg: pg}
+	if pg.PageCount() > 1 {
+		h.lastPage = pg.PageCount() - 1
+	}
+	return h, nil
+}
+
+// Close flushes and closes the underlying pager.
+func (h *HeapFile) Close() error { return h.Pg.Close() }
+
+// SetSyncInterval sets the number of writes after which the pager should sync to disk.
+func (h *HeapFile) SetSyncInterval(n int) { h.Pg.SetSyncInterval(n) }
+
+// Insert stores rec and returns its RID.
+func (h *HeapFile) Insert(rec []byte) (RID, error) {
+	if max := int(h.Pg.PageSize) - offSlots - slotSize; len(rec) > max {
+		return RID{}, fmt.Errorf("table: record of %d bytes exceeds page capacity %d", len(rec), max)
+	}
+	if h.lastPage != 0 {
+		p, err := h.Pg.Read(h.lastPage)
+		if err != nil {
+			return RID{}, err
+		}
+		if slot, ok := placeRecord(p, rec); ok {
+			return RID{uint32(h.lastPage), slot}, h.Pg.Write(h.lastPage, p)
+		}
+	}
+	id, err := h.Pg.Allocate()
+	if err != nil {
+		return RID{}, err
+	}
+	p := make(pager.Page, h.Pg.PageSize)
+	initHeapPage(p)
+	slot, _ := placeRecord(p, rec)
+	h.lastPage = id
+	return RID{uint32(id), slot}, h.Pg.Write(id, p)
+}
+
+// Get returns a copy of the record stored at rid.
+func (h *HeapFile) Get(rid RID) ([]byte, error) {
+	p, err := h.readSlotPage(rid)
+	if err != nil {
+		return nil, err
+	}
+	off, length := slotAt(p, int(rid.Slot))
+	if off == 0 {
+		return nil, ErrRecordNotFound
+	}
+	rec := make([]byte, length)
+	copy(rec, p[off:off+length])
+	return rec, nil
+}
+
+// Update replaces the record at rid. If rec no longer fits on the record's
+// page it is moved, and the returned RID differs from rid.
+func (h *HeapFile) Update(rid RID, rec []byte) (RID, error) {
+	p, err := h.readSlotPage(rid)
+	if err != nil {
+		return RID{}, err
+	}
+	off, length := slotAt(p, int(rid.Slot))
+	if off == 0 {
+		return RID{}, ErrRecordNotFound
+	}
+	if len(rec) <= length {
+		copy(p[off:], rec)
+		setSlot(p, int(rid.Slot), off, len(rec))
+		return rid, h.Pg.Write(uint64(rid.Page), p)
+	}
+	setSlot(p, int(rid.Slot), 0, 0)
+	if freeSpace(p) < len(rec) {
+		compactHeapPage(p)
+	}
+	if freeSpace(p) >= len(rec) {
+		top := recordArea(p) - len(rec)
+		copy(p[top:], rec)
+		setRecordArea(p, top)
+		setSlot(p, int(rid.Slot), top, len(rec))
+		return rid, h.Pg.Write(uint64(rid.Page), p)
+	}
+	if err := h.Pg.Write(uint64(rid.Page), p); err != nil {
+		return RID{}, err
+	}
+	return h.Insert(rec)
+}
+
+// Delete frees the record at rid.
+func (h *HeapFile) Delete(rid RID) error {
+	p, err := h.readSlotPage(rid)
+	if err != nil {
+		return err
+	}
+	if off, _ := slotAt(p, int(rid.Slot)); off == 0 {
+		return ErrRecordNotFound
+	}
+	setSlot(p, int(rid.Slot), 0, 0)
+	return h.Pg.Write(uint64(rid.Page), p)
+}
+
+// Scan calls fn for every live record in page and slot order until fn returns false.
+func (h *HeapFile) Scan(fn func(rid RID, rec []byte) bool) error {
+	for id := uint64(1); id < h.Pg.PageCount(); id++ {
+		p, err := h.Pg.Read(id)
+		if err != nil {
+			return err
+		}
+		for s := 0; s < numSlots(p); s++ {
+			off, length := slotAt(p, s)
+			if off == 0 {
+				continue
+			}
+			if !fn(RID{uint32(id), uint16(s)}, p[off:off+length]) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (h *HeapFile) readSlotPage(rid RID) (pager.Page, error) {
+	if rid.Page == 0 || uint64(rid.Page) >= h.Pg.PageCount() {
+		return nil, ErrRecordNotFound
+	}
+	p, err := h.Pg.Read(uint64(rid.Page))
+	if err != nil {
+		return nil, err
+	}
+	if int(rid.Slot) >= numSlots(p) {
+		return nil, ErrRecordNotFound
+	}
+	return p, nil
+}
+
+// ─── page helpers ─────────────────────────────────────────────────────────────
+
+func initHeapPage(p pager.Page) {
+	for i := range p {
+		p[i] = 0
+	}
+	setRecordArea(p, len(p))
+}
+
+func numSlots(p pager.Page) int {
+	return int(binary.LittleEndian.Uint16(p[offNumSlots : offNumSlots+2]))
+}
+
+func setNumSlots(p pager.Page, n int) {
+	binary.LittleEndian.PutUint16(p[offNumSlots:offNumSlots+2], uint16(n))
+}
+
+// recordArea returns the offset of the lowest record byte. A page size of
+// 64 KiB is stored as 0 and read back as len(p).
+func recordArea(p pager.Page) int {
+	v := int(binary.LittleEndian.Uint16(p[offRecordArea : offRecordArea+2]))
+	if v == 0 {
+		return len(p)
+	}
+	return v
+}
+
+func setRecordArea(p pager.Page, v int) {
+	binary.LittleEndian.PutUint16(p[offRecordArea:offRecordArea+2], uint16(v))
+}
+
+func slotAt(p pager.Page, i int) (off, length int) {
+	o := offSlots + i*slotSize
+	return int(binary.LittleEndian.Uint16(p[o : o+2])), int(binary.LittleEndian.Uint16(p[o+2 : o+4]))
+}
+
+func setSlot(p pager.Page, i, off, length int) {
+	o := offSlots + i*slotSize
+	binary.LittleEndian.PutUint16(p[o:o+2], uint16(off))
+	binary.LittleEndian.PutUint16(p[o+2:o+4], uint16(length))
+}
+
+// freeSpace returns the contiguous space between the slot array and the record area.
+func freeSpace(p pager.Page) int {
+	return recordArea(p) - (offSlots + numSlots(p)*slotSize)
+}
+
+// freeSlot returns the first free slot, or numSlots if every slot is in use.
+func freeSlot(p pager.Page) int {
+	n := numSlots(p)
+	for i := 0; i < n; i++ {
+		if off, _ := slotAt(p, i); off == 0 {
+			return i
+		}
+	}
+	return n
+}
+
+// placeRecord copies rec into p, compacting the page first if that makes it fit.
+func placeRecord(p pager.Page, rec []byte) (uint16, bool) {
+	slot := freeSlot(p)
+	need := len(rec)
+	if slot == numSlots(p) {
+		need += slotSize
+	}
+	if freeSpace(p) < need {
+		compactHeapPage(p)
+		if freeSpace(p) < need {
+			return 0, false
+		}
+	}
+	top := recordArea(p) - len(rec)
+	copy(p[top:], rec)
+	setRecordArea(p, top)
+	if slot == numSlots(p) {
+		setNumSlots(p, slot+1)
+	}
+	setSlot(p, slot, top, len(rec))
+	return uint16(slot), true
+}
+
+// compactHeapPage moves all live records to the end of the page, keeping
+// their slot numbers, so the space of deleted records becomes free again.
+func compactHeapPage(p pager.Page) {
+	n := numSlots(p)
+	recs := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		if off, length := slotAt(p, i); off != 0 {
+			recs[i] = append([]byte(nil), p[off:off+length]...)
+		}
+	}
+	top := len(p)
+	for i, rec := range recs {
+		if rec == nil {
+			continue
+		}
+		top -= len(rec)
+		copy(p[top:], rec)
+		setSlot(p, i, top, len(rec))
+	}
+	setRecordArea(p, top)
+}
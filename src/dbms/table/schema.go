@@ -0,0 +1,101 @@
+package table
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ColumnType is the storage type of a column.
+type ColumnType byte
+
+const (
+	// Int64 columns hold an int64 and can be indexed directly.
+	Int64 ColumnType = iota
+	// Bytes columns hold a variable-length byte string of at most 64 KiB.
+	Bytes
+)
+
+// Column describes one column of a table.
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Schema is the ordered list of columns of a table.
+type Schema struct {
+	Columns []Column
+}
+
+// Row holds one value per schema column: int64 for Int64 columns and []byte
+// for Bytes columns.
+type Row []any
+
+// ColumnIndex returns the position of the named column, or -1.
+func (s Schema) ColumnIndex(name string) int {
+	for i, c := range s.Columns {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Encode serializes row. Int64 values take 8 bytes, Bytes values a uint16
+// length prefix followed by the data.
+func (s Schema) Encode(row Row) ([]byte, error) {
+	if len(row) != len(s.Columns) {
+		return nil, fmt.Errorf("table: row has %d values, schema has %d columns", len(row), len(s.Columns))
+	}
+	var buf []byte
+	for i, c := range s.Columns {
+		switch c.Type {
+		case Int64:
+			v, ok := row[i].(int64)
+			if !ok {
+				return nil, fmt.Errorf("table: column %q: want int64, got %T", c.Name, row[i])
+			}
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(v))
+		case Bytes:
+			v, ok := row[i].([]byte)
+			if !ok {
+				return nil, fmt.Errorf("table: column %q: want []byte, got %T", c.Name, row[i])
+			}
+			if len(v) > 0xFFFF {
+				return nil, fmt.Errorf("table: column %q: value of %d bytes too long", c.Name, len(v))
+			}
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(len(v)))
+			buf = append(buf, v...)
+		default:
+			return nil, fmt.Errorf("table: column %q: unknown type %d", c.Name, c.Type)
+		}
+	}
+	return buf, nil
+}
+
+// Decode deserializes a record produced by Encode.
+func (s Schema) Decode(rec []byte) (Row, error) {
+	row := make(Row, len(s.Columns))
+	off := 0
+	for i, c := range s.Columns {
+		switch c.Type {
+		case Int64:
+			if off+8 > len(rec) {
+				return nil, fmt.Errorf("table: column %q: record truncated", c.Name)
+			}
+			row[i] = int64(binary.LittleEndian.Uint64(rec[off : off+8]))
+			off += 8
+		case Bytes:
+			if off+2 > len(rec) {
+				return nil, fmt.Errorf("table: column %q: record truncated", c.Name)
+			}
+			n := int(binary.LittleEndian.Uint16(rec[off : off+2]))
+			off += 2
+			if off+n > len(rec) {
+				return nil, fmt.Errorf("table: column %q: record truncated", c.Name)
+			}
+			row[i] = append([]byte(nil), rec[off:off+n]...)
+			off += n
+		}
+	}
+	return row, nil
+}
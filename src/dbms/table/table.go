@@ -0,0 +1,351 @@
+// Package table implements a minimal table storage layer on top of the pager
+// and the index implementations.
+//
+// Rows are encoded according to a Schema and stored in a slotted HeapFile,
+// where each record is addressed by its RID (page, slot). A table has at most
+// one primary index, mapping a unique Int64 column to the RID, and any number
+// of secondary indexes over Int64 columns. Any index.Index can be attached;
+// secondary indexes that implement index.MultiIndex, in multi-value mode if
+// they have one, may hold duplicate keys, all others are treated as unique.
+// Every insert, update and delete keeps all attached indexes in sync, and
+// checks the unique ones before changing anything, so the cost of index
+// maintenance shows up in the table operations just as it would in a DBMS.
+package table
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+)
+
+var (
+	// ErrNoPrimaryIndex is returned by key-based operations on a table without a primary index.
+	ErrNoPrimaryIndex = errors.New("table: no primary index")
+	// ErrDuplicateKey is returned when an insert or update violates a unique index.
+	ErrDuplicateKey = errors.New("table: duplicate key")
+)
+
+// attachedIndex is an index maintained over one Int64 column.
+type attachedIndex struct {
+	column string
+	col    int
+	idx    index.Index
+}
+
+func (a *attachedIndex) key(row Row) int64 { return row[a.col].(int64) }
+
+// unique reports whether the index rejects duplicate keys: it is not an
+// index.MultiIndex, or one that is not in multi-value mode.
+func (a *attachedIndex) unique() bool {
+	if m, ok := a.idx.(interface{ MultiValue() bool }); ok {
+		return !m.MultiValue()
+	}
+	_, multi := a.idx.(index.MultiIndex)
+	return !multi
+}
+
+// checkFree returns ErrDuplicateKey if the index is unique and already holds key.
+func (a *attachedIndex) checkFree(key int64) error {
+	if !a.unique() {
+		return nil
+	}
+	v, err := a.idx.Get(key)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		return fmt.Errorf("%w: %s=%d", ErrDuplicateKey, a.column, key)
+	}
+	return nil
+}
+
+// add inserts key → rid. Unique indexes reject a key that is already present.
+func (a *attachedIndex) add(key int64, rid RID) error {
+	if err := a.checkFree(key); err != nil {
+		return err
+	}
+	return a.idx.Insert(key, rid.Bytes())
+}
+
+// remove deletes the entry key → rid.
+func (a *attachedIndex) remove(key int64, rid RID) error {
+	if m, ok := a.idx.(index.MultiIndex); ok {
+		return m.DeleteValue(key, rid.Bytes())
+	}
+	return a.idx.Delete(key)
+}
+
+// lookup returns the RIDs stored under key.
+func (a *attachedIndex) lookup(key int64) ([]RID, error) {
+	var vals [][]byte
+	if m, ok := a.idx.(index.MultiIndex); ok {
+		var err error
+		if vals, err = m.GetAll(key); err != nil {
+			return nil, err
+		}
+	} else {
+		v, err := a.idx.Get(key)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		vals = [][]byte{v}
+	}
+	rids := make([]RID, 0, len(vals))
+	for _, v := range vals {
+		rid, err := ParseRID(v)
+		if err != nil {
+			return nil, err
+		}
+		rids = append(rids, rid)
+	}
+	return rids, nil
+}
+
+// Table is a heap file of rows together with the indexes maintained over it.
+type Table struct {
+	Schema    Schema
+	Heap      *HeapFile
+	primary   *attachedIndex
+	secondary []*attachedIndex
+}
+
+// Open opens (or creates) a table whose heap file is stored at path + ".heap".
+// Indexes are not persisted with the table and must be attached after opening.
+func Open(path string, schema Schema, cachePages int, pageSize uint32) (*Table, error) {
+	h, err := OpenHeap(path+".heap", cachePages, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Table{Schema: schema, Heap: h}, nil
+}
+
+func (t *Table) attach(column string, idx index.Index) (*attachedIndex, error) {
+	col := t.Schema.ColumnIndex(column)
+	if col < 0 {
+		return nil, fmt.Errorf("table: unknown column %q", column)
+	}
+	if t.Schema.Columns[col].Type != Int64 {
+		return nil, fmt.Errorf("table: column %q is not an Int64 column", column)
+	}
+	a := &attachedIndex{column: column, col: col, idx: idx}
+
+	// Backfill from rows that are already stored.
+	var err error
+	scanErr := t.Scan(func(rid RID, row Row) bool {
+		err = a.add(a.key(row), rid)
+		return err == nil
+	})
+	if scanErr != nil {
+		return nil, scanErr
+	}
+	return a, err
+}
+
+// SetPrimaryIndex attaches idx as the unique primary index over column.
+func (t *Table) SetPrimaryIndex(column string, idx index.Index) error {
+	if t.primary != nil {
+		return fmt.Errorf("table: primary index already set on %q", t.primary.column)
+	}
+	a, err := t.attach(column, idx)
+	if err != nil {
+		return err
+	}
+	t.primary = a
+	return nil
+}
+
+// AddSecondaryIndex attaches idx as a secondary index over column. Use an
+// index.MultiIndex in multi-value mode for columns with duplicate values.
+func (t *Table) AddSecondaryIndex(column string, idx index.Index) error {
+	a, err := t.attach(column, idx)
+	if err != nil {
+		return err
+	}
+	t.secondary = append(t.secondary, a)
+	return nil
+}
+
+// indexes returns the primary (if any) followed by all secondary indexes.
+func (t *Table) indexes() []*attachedIndex {
+	if t.primary == nil {
+		return t.secondary
+	}
+	return append([]*attachedIndex{t.primary}, t.secondary...)
+}
+
+// Insert stores row and adds it to every attached index.
+func (t *Table) Insert(row Row) (RID, error) {
+	rec, err := t.Schema.Encode(row)
+	if err != nil {
+		return RID{}, err
+	}
+	// Check every unique index first, so that a duplicate leaves nothing behind.
+	for _, a := range t.indexes() {
+		if err := a.checkFree(a.key(row)); err != nil {
+			return RID{}, err
+		}
+	}
+	rid, err := t.Heap.Insert(rec)
+	if err != nil {
+		return RID{}, err
+	}
+	for _, a := range t.indexes() {
+		if err := a.add(a.key(row), rid); err != nil {
+			return RID{}, fmt.Errorf("index %s: %w", a.column, err)
+		}
+	}
+	return rid, nil
+}
+
+// locate returns the RID and the row stored under the primary key pk.
+func (t *Table) locate(pk int64) (RID, Row, error) {
+	if t.primary == nil {
+		return RID{}, nil, ErrNoPrimaryIndex
+	}
+	rids, err := t.primary.lookup(pk)
+	if err != nil || len(rids) == 0 {
+		return RID{}, nil, err
+	}
+	row, err := t.Fetch(rids[0])
+	return rids[0], row, err
+}
+
+// Fetch reads the row stored at rid.
+func (t *Table) Fetch(rid RID) (Row, error) {
+	rec, err := t.Heap.Get(rid)
+	if err != nil {
+		return nil, err
+	}
+	return t.Schema.Decode(rec)
+}
+
+// Get returns the row with primary key pk, or nil if there is none.
+func (t *Table) Get(pk int64) (Row, error) {
+	_, row, err := t.locate(pk)
+	return row, err
+}
+
+// Update replaces the row with primary key pk. The new row may change any
+// column, including the primary key. Index entries are rewritten for every
+// index whose key changed, and for all of them if the record moved.
+func (t *Table) Update(pk int64, row Row) error {
+	rec, err := t.Schema.Encode(row)
+	if err != nil {
+		return err
+	}
+	rid, old, err := t.locate(pk)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return fmt.Errorf("table: update %d: %w", pk, ErrRecordNotFound)
+	}
+	// Check every unique index whose key changes before touching the heap,
+	// so that a duplicate leaves the row and its entries as they were.
+	for _, a := range t.indexes() {
+		if oldKey, newKey := a.key(old), a.key(row); oldKey != newKey {
+			if err := a.checkFree(newKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	newRID, err := t.Heap.Update(rid, rec)
+	if err != nil {
+		return err
+	}
+	for _, a := range t.indexes() {
+		oldKey, newKey := a.key(old), a.key(row)
+		if oldKey == newKey && rid == newRID {
+			continue
+		}
+		if err := a.remove(oldKey, rid); err != nil {
+			return fmt.Errorf("index %s: %w", a.column, err)
+		}
+		if err := a.add(newKey, newRID); err != nil {
+			return fmt.Errorf("index %s: %w", a.column, err)
+		}
+	}
+	return nil
+}
+
+// Delete removes the row with primary key pk and its index entries.
+// Deleting a missing key is not an error.
+func (t *Table) Delete(pk int64) error {
+	rid, row, err := t.locate(pk)
+	if err != nil || row == nil {
+		return err
+	}
+	for _, a := range t.indexes() {
+		if err := a.remove(a.key(row), rid); err != nil {
+			return fmt.Errorf("index %s: %w", a.column, err)
+		}
+	}
+	return t.Heap.Delete(rid)
+}
+
+// Lookup returns all rows whose indexed column equals key, using the primary
+// or a secondary index over column.
+func (t *Table) Lookup(column string, key int64) ([]Row, error) {
+	for _, a := range t.indexes() {
+		if a.column != column {
+			continue
+		}
+		rids, err := a.lookup(key)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]Row, 0, len(rids))
+		for _, rid := range rids {
+			row, err := t.Fetch(rid)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("table: no index on column %q", column)
+}
+
+// Scan calls fn for every row in heap order until fn returns false.
+func (t *Table) Scan(fn func(rid RID, row Row) bool) error {
+	var decodeErr error
+	err := t.Heap.Scan(func(rid RID, rec []byte) bool {
+		row, err := t.Schema.Decode(rec)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		return fn(rid, row)
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// SetSyncInterval sets the sync interval of the heap file and of every attached index that supports it.
+func (t *Table) SetSyncInterval(n int) {
+	t.Heap.SetSyncInterval(n)
+	for _, a := range t.indexes() {
+		if s, ok := a.idx.(interface{ SetSyncInterval(int) }); ok {
+			s.SetSyncInterval(n)
+		}
+	}
+}
+
+// Close closes the heap file and all attached indexes.
+func (t *Table) Close() error {
+	var firstErr error
+	for _, a := range t.indexes() {
+		if err := a.idx.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := t.Heap.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
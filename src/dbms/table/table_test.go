@@ -0,0 +1,204 @@
+package table_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/btree-query-bench/bmark/dbms/index/bptree"
+	"github.com/btree-query-bench/bmark/dbms/index/btree"
+	"github.com/btree-query-bench/bmark/dbms/table"
+)
+
+var schema = table.Schema{Columns: []table.Column{
+	{Name: "id", Type: table.Int64},
+	{Name: "group", Type: table.Int64},
+	{Name: "payload", Type: table.Bytes},
+}}
+
+func openTable(t *testing.T, path string) *table.Table {
+	t.Helper()
+	tbl, err := table.Open(path, schema, 16, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := bptree.Open(path+"_pk", 16, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byGroup, err := btree.OpenMulti(path+"_group", 16, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.SetPrimaryIndex("id", pk); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.AddSecondaryIndex("group", byGroup); err != nil {
+		t.Fatal(err)
+	}
+	tbl.SetSyncInterval(0)
+	return tbl
+}
+
+func TestTable(t *testing.T) {
+	path := "/tmp/table_test"
+	defer os.Remove(path + ".heap")
+	defer os.Remove(path + "_pk.bpt")
+	defer os.Remove(path + "_group.bt")
+
+	tbl := openTable(t, path)
+	defer tbl.Close()
+
+	n := 300
+	for i := 1; i <= n; i++ {
+		row := table.Row{int64(i), int64(i % 7), bytes.Repeat([]byte{'a'}, 20)}
+		if _, err := tbl.Insert(row); err != nil {
+			t.Fatalf("Insert %d failed: %v", i, err)
+		}
+	}
+	if _, err := tbl.Insert(table.Row{int64(1), int64(0), []byte{}}); !errors.Is(err, table.ErrDuplicateKey) {
+		t.Errorf("Insert duplicate primary key: got %v, want ErrDuplicateKey", err)
+	}
+
+	row, err := tbl.Get(42)
+	if err != nil || row == nil {
+		t.Fatalf("Get(42) = %v, %v", row, err)
+	}
+	if row[1].(int64) != 0 {
+		t.Errorf("Get(42) group = %d, want 0", row[1])
+	}
+
+	rows, err := tbl.Lookup("group", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := n / 7; len(rows) != want && len(rows) != want+1 {
+		t.Errorf("Lookup(group=3) returned %d rows", len(rows))
+	}
+
+	// Grow every row in group 3 so records move to other pages, and move them to group 100.
+	for _, r := range rows {
+		id := r[0].(int64)
+		if err := tbl.Update(id, table.Row{id, int64(100), bytes.Repeat([]byte{'b'}, 200)}); err != nil {
+			t.Fatalf("Update %d failed: %v", id, err)
+		}
+	}
+	if got, _ := tbl.Lookup("group", 3); len(got) != 0 {
+		t.Errorf("Lookup(group=3) after update returned %d rows, want 0", len(got))
+	}
+	moved, _ := tbl.Lookup("group", 100)
+	if len(moved) != len(rows) {
+		t.Errorf("Lookup(group=100) returned %d rows, want %d", len(moved), len(rows))
+	}
+	for _, r := range moved {
+		if len(r[2].([]byte)) != 200 {
+			t.Errorf("row %d payload length = %d, want 200", r[0], len(r[2].([]byte)))
+		}
+		got, _ := tbl.Get(r[0].(int64))
+		if got == nil || got[1].(int64) != 100 {
+			t.Errorf("Get(%d) after update = %v", r[0], got)
+		}
+	}
+
+	// Change a primary key.
+	if err := tbl.Update(1, table.Row{int64(1001), int64(1), []byte("x")}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := tbl.Get(1); got != nil {
+		t.Errorf("Get(1) after key change = %v, want nil", got)
+	}
+	if got, _ := tbl.Get(1001); got == nil {
+		t.Errorf("Get(1001) after key change = nil")
+	}
+
+	for i := 2; i <= n; i += 2 {
+		if err := tbl.Delete(int64(i)); err != nil {
+			t.Fatalf("Delete %d failed: %v", i, err)
+		}
+	}
+	count := 0
+	_ = tbl.Scan(func(_ table.RID, r table.Row) bool {
+		if id := r[0].(int64); id%2 == 0 && id <= int64(n) {
+			t.Errorf("Scan returned deleted row %d", id)
+		}
+		count++
+		return true
+	})
+	if want := n - n/2; count != want {
+		t.Errorf("Scan returned %d rows, want %d", count, want)
+	}
+	got, _ := tbl.Lookup("group", 100)
+	for _, g := range got {
+		if g[0].(int64)%2 == 0 {
+			t.Errorf("Lookup(group=100) returned deleted row %d", g[0])
+		}
+	}
+}
+
+func TestUniqueSecondaryIndex(t *testing.T) {
+	path := "/tmp/table_test_unique"
+	defer os.Remove(path + ".heap")
+	defer os.Remove(path + "_pk.bpt")
+	defer os.Remove(path + "_group.bt")
+
+	tbl, err := table.Open(path, schema, 16, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.Close()
+	pk, err := bptree.Open(path+"_pk", 16, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byGroup, err := btree.Open(path+"_group", 16, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.SetPrimaryIndex("id", pk); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.AddSecondaryIndex("group", byGroup); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if _, err := tbl.Insert(table.Row{int64(i), int64(10 * i), []byte("a")}); err != nil {
+			t.Fatalf("Insert %d failed: %v", i, err)
+		}
+	}
+	count := func() int {
+		n := 0
+		_ = tbl.Scan(func(table.RID, table.Row) bool { n++; return true })
+		return n
+	}
+
+	// A duplicate secondary key must leave neither a row nor index entries behind.
+	if _, err := tbl.Insert(table.Row{int64(4), int64(20), []byte("b")}); !errors.Is(err, table.ErrDuplicateKey) {
+		t.Errorf("Insert duplicate secondary key: got %v, want ErrDuplicateKey", err)
+	}
+	if n := count(); n != 3 {
+		t.Errorf("Scan after rejected insert returned %d rows, want 3", n)
+	}
+	if got, _ := tbl.Get(4); got != nil {
+		t.Errorf("Get(4) after rejected insert = %v, want nil", got)
+	}
+
+	// A duplicate secondary key must leave the updated row unchanged.
+	if err := tbl.Update(1, table.Row{int64(1), int64(30), bytes.Repeat([]byte{'c'}, 300)}); !errors.Is(err, table.ErrDuplicateKey) {
+		t.Errorf("Update to duplicate secondary key: got %v, want ErrDuplicateKey", err)
+	}
+	got, _ := tbl.Get(1)
+	if got == nil || got[1].(int64) != 10 || string(got[2].([]byte)) != "a" {
+		t.Errorf("Get(1) after rejected update = %v", got)
+	}
+	if rows, _ := tbl.Lookup("group", 10); len(rows) != 1 || rows[0][0].(int64) != 1 {
+		t.Errorf("Lookup(group=10) after rejected update = %v", rows)
+	}
+	if rows, _ := tbl.Lookup("group", 30); len(rows) != 1 || rows[0][0].(int64) != 3 {
+		t.Errorf("Lookup(group=30) after rejected update = %v", rows)
+	}
+	if n := count(); n != 3 {
+		t.Errorf("Scan after rejected update returned %d rows, want 3", n)
+	}
+}
@@ -5,6 +5,7 @@ go 1.24.2
 require (
 	github.com/cockroachdb/pebble v1.1.0
 	github.com/go-echarts/go-echarts/v2 v2.7.1
+	golang.org/x/sys v0.30.0
 )
 
 require (
@@ -30,7 +31,6 @@ require (
 	github.com/prometheus/procfs v0.7.3 // indirect
 	github.com/rogpeppe/go-internal v1.9.0 // indirect
 	golang.org/x/exp v0.0.0-20240506185415-9bf2ced13842 // indirect
-	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
 )
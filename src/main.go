@@ -1,46 +1,221 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/btree-query-bench/bmark/bench"
 )
 
-// ReadFlagsOrDefault parses command-line flags or returns a Config with default values.
-func ReadFlagsOrDefault() bench.Config {
-	cfg := bench.Config{}
-
-	flag.Int64Var(&cfg.Seed, "seed", 42, "Random seed")
-	flag.IntVar(&cfg.CachePages, "cache-pages", 4096, "Number of cache pages")
-	flag.StringVar(&cfg.DataDir, "data-dir", "./out/data", "Directory for data files")
-	flag.StringVar(&cfg.OutDir, "out-dir", "./out/results", "Directory for result output")
-	flag.IntVar(&cfg.DatasetSize, "dataset-size", 5_000_000, "Number of entries in the dataset")
-	flag.IntVar(&cfg.PointQueryCount, "point-queries", 1_000_000, "Number of point queries to run")
-	flag.IntVar(&cfg.WriteOpsTotal, "write-ops-total", 1_000_000, "Total write operations")
-	flag.IntVar(&cfg.WriteOpsWindow, "write-ops-window", 10_000, "Write ops window size")
-	flag.IntVar(&cfg.MixedOpsTotal, "mixed-ops-total", 1_000_000, "Total mixed operations")
-	flag.IntVar(&cfg.LogInterval, "log-interval", 1_000, "Logging interval")
-	flag.IntVar(&cfg.ValueSize, "value-size", 128, "Size of each value in bytes")
-	flag.IntVar(&cfg.T2StartSize, "t2-start-size", 4096, "T2 range query start size")
-	flag.IntVar(&cfg.T2MaxSize, "t2-max-size", 5_000_000, "T2 range query max size")
-	flag.BoolVar(&cfg.CleanupData, "cleanup-data", true, "Delete data files after each test")
-	flag.Parse()
-
-	return cfg
+// command is a subcommand of the CLI.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"run", "run benchmarks (default selection, --tests or --suite) and plot the results", runCmd},
+	{"plot", "re-plot the CSV files in a results directory", plotCmd},
+	{"compare", "compare two results directories and fail on regressions", compareCmd},
+	{"inspect", "print the header and shape of a .bt/.bpt file", inspectCmd},
+	{"verify", "check the structural invariants of a .bt/.bpt file", verifyCmd},
+	{"dump", "export the entries of a .bt/.bpt file as CSV or JSON lines", dumpCmd},
+	{"load", "import entries from CSV or JSON lines into a .bt/.bpt file", loadCmd},
+	{"dot", "write a Graphviz DOT file of a .bt/.bpt file", dotCmd},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags] [args]\n\ncommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> --help' for the flags of a command.\n", os.Args[0])
 }
 
 func main() {
-	//input
-	cfg := ReadFlagsOrDefault()
+	log.SetFlags(0)
+	args := os.Args[1:]
+	// Without a command (or with flags only) behave like "run", as before subcommands existed.
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help" {
+		args = append([]string{"run"}, args...)
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			if err := c.run(args[1:]); err != nil {
+				log.Fatalf("%s: %v", c.name, err)
+			}
+			return
+		}
+	}
+	if args[0] != "-h" && args[0] != "--help" && args[0] != "help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	}
+	usage()
+	os.Exit(2)
+}
+
+// newFlagSet returns a flag set for the named command with a usage line
+// describing its positional arguments.
+func newFlagSet(name, argsUsage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s [flags] %s\n", os.Args[0], name, argsUsage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// registerConfigFlags adds the benchmark configuration flags to fs, with cfg
+// receiving their values.
+func registerConfigFlags(fs *flag.FlagSet, cfg *bench.Config) {
+	fs.Int64Var(&cfg.Seed, "seed", 42, "Random seed")
+	fs.IntVar(&cfg.CachePages, "cache-pages", 4096, "Number of cache pages")
+	fs.StringVar(&cfg.DataDir, "data-dir", "./out/data", "Directory for data files")
+	fs.StringVar(&cfg.OutDir, "out-dir", "./out/results", "Directory for result output")
+	fs.IntVar(&cfg.DatasetSize, "dataset-size", 5_000_000, "Number of entries in the dataset")
+	fs.IntVar(&cfg.PointQueryCount, "point-queries", 1_000_000, "Number of point queries to run")
+	fs.IntVar(&cfg.WriteOpsTotal, "write-ops-total", 1_000_000, "Total write operations")
+	fs.IntVar(&cfg.WriteOpsWindow, "write-ops-window", 10_000, "Write ops window size")
+	fs.IntVar(&cfg.MixedOpsTotal, "mixed-ops-total", 1_000_000, "Total mixed operations")
+	fs.IntVar(&cfg.LogInterval, "log-interval", 1_000, "Logging interval")
+	fs.IntVar(&cfg.ValueSize, "value-size", 128, "Size of each value in bytes")
+	fs.IntVar(&cfg.T2StartSize, "t2-start-size", 4096, "T2 range query start size")
+	fs.IntVar(&cfg.T2MaxSize, "t2-max-size", 5_000_000, "T2 range query max size")
+	fs.BoolVar(&cfg.CleanupData, "cleanup-data", true, "Delete data files after each test")
+	fs.IntVar(&cfg.Repetitions, "repetitions", 1, "Run each test this many times and report means with standard deviations and 95% confidence intervals")
+	fs.BoolVar(&cfg.Baselines, "baselines", false, "Also benchmark the in-memory sorted array and B-tree baselines")
+	fs.StringVar(&cfg.YCSB, "ycsb", "", "YCSB core workloads to run, e.g. ABCDEF (empty runs none)")
+	dists := strings.Join(bench.KeyDists, ", ")
+	fs.StringVar(&cfg.T1Dist, "t1-dist", bench.DistUniform, "Key distribution of T1 point queries: "+dists)
+	fs.StringVar(&cfg.MixedDist, "mixed-dist", bench.DistUniform, "Key distribution of T4/T5 reads: "+dists)
+	fs.StringVar(&cfg.YCSBDist, "ycsb-dist", "", "Key distribution for all YCSB workloads (empty uses each workload's own)")
+	fs.Float64Var(&cfg.ZipfTheta, "zipf-theta", 0.99, "Skew of the Zipfian key distributions, in (0, 1)")
+	fs.Float64Var(&cfg.HotSetFraction, "hotspot-set", 0.2, "Fraction of keys that are hot in the hotspot distribution")
+	fs.Float64Var(&cfg.HotOpFraction, "hotspot-ops", 0.8, "Fraction of operations on hot keys in the hotspot distribution")
+	fs.StringVar(&cfg.KeyPattern, "key-pattern", bench.KeysDense, "Dataset keys: "+strings.Join(bench.KeyPatterns, ", "))
+	fs.StringVar(&cfg.ValueDist, "value-dist", bench.ValuesFixed, "Dataset value sizes: "+strings.Join(bench.ValueDists, ", "))
+	fs.IntVar(&cfg.ValueMin, "value-min", 8, "Smallest value size for non-fixed value distributions")
+	fs.IntVar(&cfg.ValueMax, "value-max", 1024, "Largest value size for non-fixed value distributions")
+	fs.Float64Var(&cfg.ValueStdDev, "value-stddev", 0, "Standard deviation of normal value sizes (0 means value-size/4)")
+	fs.Float64Var(&cfg.CompressRatio, "compress-ratio", 1, "Fraction of each value that is random; the rest repeats it")
+	fs.Var(&cfg.Warmup, "warmup", "Warm-up before measuring, excluded from the results: a number of point queries or a duration such as 5s")
+	fs.StringVar(&cfg.CacheMode, "cache-mode", bench.CacheWarm, "Cache state of the T1 and T2 measurements: "+strings.Join(bench.CacheModes, ", "))
+	fs.BoolVar(&cfg.DropOSCache, "drop-os-cache", false, "In cold cache mode, also evict the index files from the OS page cache (Linux)")
+	fs.Float64Var(&cfg.TargetRate, "target-rate", 0, "Open-loop target ops/s of the mixed workloads, with latencies measured from the intended start (0 runs closed-loop)")
+	fs.StringVar(&cfg.Arrivals, "arrivals", bench.ArrivalPoisson, "Arrivals of open-loop operations: "+strings.Join(bench.ArrivalProcesses, ", "))
+	fs.IntVar(&cfg.SampleInterval, "sample-interval-ms", 0, "Interval of the throughput, latency and index event time series of every test, in ms (0 disables them; sampling adds overhead to the measured operations)")
+	fs.StringVar(&cfg.RecordTrace, "record-trace", "", "Record the operations of every test on its first index into trace files in this directory")
+	fs.StringVar(&cfg.TraceFormat, "trace-format", bench.TraceBinary, "Format of recorded traces: "+strings.Join(bench.TraceFormats, ", "))
+	fs.StringVar(&cfg.Trace, "trace", "", "Trace file (binary or NDJSON) the replay test executes")
+	fs.Var(&cfg.T4Mix, "t4-mix", "T4 operation mix, e.g. read=80,update=10,delete=5,scan=5 (default "+bench.DefaultT4Mix.String()+")")
+	fs.Var(&cfg.T5Mix, "t5-mix", "T5 operation mix (default "+bench.DefaultT5Mix.String()+")")
+}
+
+// explicitFlags returns the config patch of the configuration flags set on the command line.
+func explicitFlags(fs *flag.FlagSet, cfg bench.Config) (json.RawMessage, error) {
+	config := flag.NewFlagSet("", flag.ContinueOnError)
+	registerConfigFlags(config, &bench.Config{})
+	var names []string
+	fs.Visit(func(f *flag.Flag) {
+		if config.Lookup(f.Name) != nil {
+			names = append(names, strings.ReplaceAll(f.Name, "-", "_"))
+		}
+	})
+	return bench.ConfigPatch(cfg, names)
+}
 
-	// process
-	if err := bench.RunBenchmarks(cfg); err != nil {
-		log.Fatalf("benchmark failed: %v", err)
+func runCmd(args []string) error {
+	var cfg bench.Config
+	var suitePath, testList string
+	var noPlot bool
+	fs := newFlagSet("run", "")
+	registerConfigFlags(fs, &cfg)
+	fs.StringVar(&suitePath, "suite", "", "JSON suite file listing tests, parameters and index variants; other flags override it")
+	fs.StringVar(&testList, "tests", "", "Comma-separated tests to run, e.g. t1,t4,ycsb_a (one of "+strings.Join(bench.TestNames(), ", ")+")")
+	fs.BoolVar(&noPlot, "no-plot", false, "Only write the CSV files; plot them later with the plot command")
+	_ = fs.Parse(args)
+
+	suite, err := bench.DefaultSuite(cfg)
+	if err != nil {
+		return err
+	}
+	var overrides json.RawMessage
+	if suitePath != "" {
+		if overrides, err = explicitFlags(fs, cfg); err != nil {
+			return fmt.Errorf("flags: %w", err)
+		}
+		if suite, err = bench.LoadSuite(suitePath); err != nil {
+			return fmt.Errorf("suite: %w", err)
+		}
+		// Flag defaults, then the suite's config, then the flags set explicitly.
+		if cfg, err = suite.Resolve(cfg, overrides); err != nil {
+			return fmt.Errorf("suite: %w", err)
+		}
+	}
+	if testList != "" {
+		if err := suite.Select(strings.Split(testList, ",")); err != nil {
+			return err
+		}
+	}
+
+	if err := suite.Run(cfg, overrides); err != nil {
+		return fmt.Errorf("benchmark failed: %w", err)
+	}
+	if noPlot {
+		return nil
+	}
+	return bench.PlotAll(cfg.OutDir)
+}
+
+func plotCmd(args []string) error {
+	fs := newFlagSet("plot", "[results-dir]")
+	outDir := fs.String("out-dir", "./out/results", "Directory holding the result CSV files")
+	exportDir := fs.String("export", "", "Also write static charts and Typst tables to this directory, e.g. ../thesis/assets/results")
+	formats := fs.String("formats", strings.Join(bench.ExportFormats, ","), "Comma-separated export formats: svg, png, typ")
+	_ = fs.Parse(args)
+	if fs.NArg() > 0 {
+		*outDir = fs.Arg(0)
+	}
+	if *exportDir == "" {
+		return bench.PlotAll(*outDir)
 	}
+	return bench.PlotAllExport(*outDir, bench.ExportOptions{Dir: *exportDir, Formats: strings.Split(*formats, ",")})
+}
 
-	// output
-	if err := bench.PlotAll(cfg.OutDir); err != nil {
-		log.Fatalf("plot failed: %v", err)
+func compareCmd(args []string) error {
+	fs := newFlagSet("compare", "base-dir new-dir")
+	threshold := fs.Float64("threshold", 5, "Relative change in percent beyond which a worse metric is a regression")
+	markdown := fs.String("o", "-", "Markdown output file (- for stdout)")
+	htmlPath := fs.String("html", "", "HTML chart of the changes (default new-dir/compare.html; - to skip)")
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected two results directories")
+	}
+	c, err := bench.Compare(fs.Arg(0), fs.Arg(1), *threshold/100)
+	if err != nil {
+		return err
+	}
+	if err := c.WriteMarkdownFile(*markdown); err != nil {
+		return err
+	}
+	if *htmlPath == "" {
+		*htmlPath = filepath.Join(fs.Arg(1), "compare.html")
+	}
+	if *htmlPath != "-" {
+		if err := c.WriteHTML(*htmlPath); err != nil {
+			return err
+		}
+	}
+	if n := c.Regressions(); n > 0 {
+		return fmt.Errorf("%d metrics regressed by more than %g%%", n, *threshold)
 	}
+	return nil
 }
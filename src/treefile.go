@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/btree-query-bench/bmark/dbms/index"
+	"github.com/btree-query-bench/bmark/dbms/index/bptree"
+	"github.com/btree-query-bench/bmark/dbms/index/btree"
+	"github.com/btree-query-bench/bmark/dbms/index/shared"
+	"github.com/btree-query-bench/bmark/dbms/pager"
+)
+
+// treeFile is an open .bt (B-tree) or .bpt (B+ tree) file.
+type treeFile struct {
+	path string
+	kind string // "B-tree" or "B+ tree"
+	idx  index.Index
+	tree *shared.Tree
+}
+
+// treeFileFlags are the flags shared by the commands that open a tree file.
+type treeFileFlags struct {
+	pageSize   uint
+	cachePages int
+}
+
+func (f *treeFileFlags) register(fs *flag.FlagSet) {
+	fs.UintVar(&f.pageSize, "page-size", 0, "Page size of the file (0 detects it from the file)")
+	fs.IntVar(&f.cachePages, "cache-pages", 1024, "Number of cache pages")
+}
+
+// openTreeFile opens the existing tree file at path; the extension selects the tree type.
+func openTreeFile(path string, f treeFileFlags) (*treeFile, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	pageSize := uint32(f.pageSize)
+	if pageSize == 0 {
+		var err error
+		if pageSize, err = pager.PageSizeOf(path); err != nil {
+			return nil, fmt.Errorf("%w (pass --page-size)", err)
+		}
+	}
+	return createTreeFile(path, f.cachePages, pageSize, 0)
+}
+
+// createTreeFile opens the tree file at path, creating it with the given
+// shared.Flag* modes if it does not exist.
+func createTreeFile(path string, cachePages int, pageSize uint32, flags byte) (*treeFile, error) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	multi, counted := flags&shared.FlagDuplicates != 0, flags&shared.FlagCounts != 0
+	switch filepath.Ext(path) {
+	case ".bt":
+		var t *btree.BTree
+		var err error
+		switch {
+		case counted:
+			t, err = btree.OpenCounted(base, cachePages, pageSize, multi)
+		case multi:
+			t, err = btree.OpenMulti(base, cachePages, pageSize)
+		default:
+			t, err = btree.Open(base, cachePages, pageSize)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &treeFile{path, "B-tree", t, &t.Tree}, nil
+	case ".bpt":
+		var t *bptree.BPTree
+		var err error
+		switch {
+		case counted:
+			t, err = bptree.OpenCounted(base, cachePages, pageSize, multi)
+		case multi:
+			t, err = bptree.OpenMulti(base, cachePages, pageSize)
+		default:
+			t, err = bptree.Open(base, cachePages, pageSize)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &treeFile{path, "B+ tree", t, &t.Tree}, nil
+	}
+	return nil, fmt.Errorf("%s: unknown file type (want .bt or .bpt)", path)
+}
+
+// entries returns the number of entries, from the subtree counts if the tree keeps them.
+func (f *treeFile) entries() (int, error) {
+	if f.tree.Counted {
+		return f.tree.Count(math.MinInt64, math.MaxInt64)
+	}
+	it, err := f.idx.Range(math.MinInt64, math.MaxInt64)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+	n := 0
+	for it.Next() {
+		n++
+	}
+	return n, it.Error()
+}
+
+// fileArg returns the single positional argument of fs.
+func fileArg(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", errors.New("expected exactly one file")
+	}
+	return fs.Arg(0), nil
+}
+
+func inspectCmd(args []string) error {
+	var tf treeFileFlags
+	fs := newFlagSet("inspect", "file.bt|file.bpt")
+	tf.register(fs)
+	_ = fs.Parse(args)
+	path, err := fileArg(fs)
+	if err != nil {
+		return err
+	}
+	f, err := openTreeFile(path, tf)
+	if err != nil {
+		return err
+	}
+	defer f.idx.Close()
+
+	s, err := f.tree.Stats()
+	if err != nil {
+		return err
+	}
+	pages := f.tree.Pg.PageCount()
+	fmt.Printf("file:        %s\n", f.path)
+	fmt.Printf("type:        %s\n", f.kind)
+	fmt.Printf("page size:   %d\n", f.tree.Pg.PageSize)
+	fmt.Printf("pages:       %d in file (%.1f MiB), %d in tree\n", pages, float64(pages)*float64(f.tree.Pg.PageSize)/(1<<20), s.Pages())
+	fmt.Printf("root page:   %d\n", f.tree.RootID)
+	fmt.Printf("modes:       duplicates=%t counted=%t\n", f.tree.Duplicates, f.tree.Counted)
+	fmt.Printf("height:      %d\n", s.Height())
+	fmt.Printf("entries:     %d\n", s.Entries)
+	fmt.Printf("avg fill:    %.1f%%\n", 100*s.AvgFill)
+	fmt.Printf("avg fanout:  %.1f\n", s.AvgFanout)
+	fmt.Printf("fragmented:  %d B\n", s.FragmentedBytes)
+	fmt.Printf("leaf chain:  %d\n", s.LeafChainLength)
+	for i, l := range s.Levels {
+		fmt.Printf("level %-5d  pages=%d keys=%d avg fill=%.1f%%\n", i, l.Pages, l.Keys, 100*l.AvgFill)
+	}
+	fmt.Printf("fill histogram:")
+	for i, c := range s.FillHistogram {
+		fmt.Printf(" %d%%:%d", i*100/shared.FillBuckets, c)
+	}
+	fmt.Println()
+	return nil
+}
+
+func verifyCmd(args []string) error {
+	var tf treeFileFlags
+	fs := newFlagSet("verify", "file.bt|file.bpt")
+	tf.register(fs)
+	cow := fs.Bool("cow", false, "The file was written in copy-on-write mode (its B+ tree leaf chain is not maintained)")
+	_ = fs.Parse(args)
+	path, err := fileArg(fs)
+	if err != nil {
+		return err
+	}
+	f, err := openTreeFile(path, tf)
+	if err != nil {
+		return err
+	}
+	defer f.idx.Close()
+
+	f.tree.CopyOnWrite = *cow
+	n, err := f.tree.Verify()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: ok (%s, %d entries, height %d)\n", f.path, f.kind, n, f.tree.Height())
+	return nil
+}
+
+// Export formats of dump and load.
+const (
+	formatCSV   = "csv"   // header "key,value", one entry per row
+	formatJSONL = "jsonl" // one {"key": …, "value": "…"} object per line
+)
+
+// valueCodecs encode values as text for dump and load.
+var valueCodecs = map[string]struct {
+	encode func([]byte) string
+	decode func(string) ([]byte, error)
+}{
+	"base64": {base64.StdEncoding.EncodeToString, base64.StdEncoding.DecodeString},
+	"hex":    {hex.EncodeToString, hex.DecodeString},
+	"text":   {func(v []byte) string { return string(v) }, func(s string) ([]byte, error) { return []byte(s), nil }},
+}
+
+type jsonEntry struct {
+	Key   int64  `json:"key"`
+	Value string `json:"value"`
+}
+
+func dumpCmd(args []string) error {
+	var tf treeFileFlags
+	fs := newFlagSet("dump", "file.bt|file.bpt")
+	tf.register(fs)
+	format := fs.String("format", formatCSV, "Output format: csv or jsonl")
+	encoding := fs.String("values", "base64", "Value encoding: base64, hex or text")
+	start := fs.Int64("start", math.MinInt64, "Smallest key to export")
+	end := fs.Int64("end", math.MaxInt64, "Largest key to export")
+	out := fs.String("o", "-", "Output file (- for stdout)")
+	_ = fs.Parse(args)
+	path, err := fileArg(fs)
+	if err != nil {
+		return err
+	}
+	codec, ok := valueCodecs[*encoding]
+	if !ok {
+		return fmt.Errorf("unknown value encoding %q", *encoding)
+	}
+	if *format != formatCSV && *format != formatJSONL {
+		return fmt.Errorf("unknown format %q (want csv or jsonl)", *format)
+	}
+	f, err := openTreeFile(path, tf)
+	if err != nil {
+		return err
+	}
+	defer f.idx.Close()
+
+	dst := io.Writer(os.Stdout)
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		dst = file
+	}
+	bw := bufio.NewWriter(dst)
+	defer bw.Flush()
+	cw := csv.NewWriter(bw)
+	enc := json.NewEncoder(bw)
+	if *format == formatCSV {
+		_ = cw.Write([]string{"key", "value"})
+	}
+
+	it, err := f.idx.Range(*start, *end)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	n := 0
+	for it.Next() {
+		v := codec.encode(it.Value())
+		if *format == formatCSV {
+			err = cw.Write([]string{strconv.FormatInt(it.Key(), 10), v})
+		} else {
+			err = enc.Encode(jsonEntry{it.Key(), v})
+		}
+		if err != nil {
+			return err
+		}
+		n++
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "dumped %d entries from %s\n", n, f.path)
+	return nil
+}
+
+func loadCmd(args []string) error {
+	var tf treeFileFlags
+	fs := newFlagSet("load", "file.bt|file.bpt")
+	tf.register(fs)
+	format := fs.String("format", formatCSV, "Input format: csv (with a key,value header) or jsonl")
+	encoding := fs.String("values", "base64", "Value encoding: base64, hex or text")
+	in := fs.String("i", "-", "Input file (- for stdin)")
+	multi := fs.Bool("multi", false, "Create the file in multi-value mode")
+	counted := fs.Bool("counted", false, "Create the file with subtree counts")
+	_ = fs.Parse(args)
+	path, err := fileArg(fs)
+	if err != nil {
+		return err
+	}
+	codec, ok := valueCodecs[*encoding]
+	if !ok {
+		return fmt.Errorf("unknown value encoding %q", *encoding)
+	}
+
+	src := io.Reader(os.Stdin)
+	if *in != "-" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		src = file
+	}
+
+	// An existing file keeps its page size and modes.
+	var f *treeFile
+	if _, err := os.Stat(path); err == nil {
+		f, err = openTreeFile(path, tf)
+		if err != nil {
+			return err
+		}
+	} else {
+		pageSize := uint32(tf.pageSize)
+		if pageSize == 0 {
+			pageSize = 4096
+		}
+		var flags byte
+		if *multi {
+			flags |= shared.FlagDuplicates
+		}
+		if *counted {
+			flags |= shared.FlagCounts
+		}
+		if f, err = createTreeFile(path, tf.cachePages, pageSize, flags); err != nil {
+			return err
+		}
+	}
+	defer f.idx.Close()
+	f.tree.SetSyncInterval(0)
+
+	insert := func(key int64, text string) error {
+		v, err := codec.decode(text)
+		if err != nil {
+			return fmt.Errorf("key %d: %w", key, err)
+		}
+		return f.idx.Insert(key, v)
+	}
+	n := 0
+	switch *format {
+	case formatCSV:
+		r := csv.NewReader(bufio.NewReader(src))
+		r.FieldsPerRecord = 2
+		if _, err := r.Read(); err != nil { // header
+			return err
+		}
+		for {
+			rec, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			key, err := strconv.ParseInt(rec[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			if err := insert(key, rec[1]); err != nil {
+				return err
+			}
+			n++
+		}
+	case formatJSONL:
+		dec := json.NewDecoder(bufio.NewReader(src))
+		for {
+			var e jsonEntry
+			if err := dec.Decode(&e); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			if err := insert(e.Key, e.Value); err != nil {
+				return err
+			}
+			n++
+		}
+	default:
+		return fmt.Errorf("unknown format %q (want csv or jsonl)", *format)
+	}
+	fmt.Printf("loaded %d entries into %s\n", n, f.path)
+	return nil
+}
+
+func dotCmd(args []string) error {
+	var tf treeFileFlags
+	fs := newFlagSet("dot", "file.bt|file.bpt")
+	tf.register(fs)
+	out := fs.String("o", "", "Output DOT file (default: the tree file with extension .dot)")
+	demo := fs.Int("demo", 0, "First recreate the file with keys 1..n and values \"value-<key>\" in 512-byte pages")
+	_ = fs.Parse(args)
+	path, err := fileArg(fs)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(path, filepath.Ext(path)) + ".dot"
+	}
+
+	var f *treeFile
+	if *demo > 0 {
+		// A small page size makes splits happen early, so the picture shows several levels.
+		_ = os.Remove(path)
+		if f, err = createTreeFile(path, 10, 512, 0); err != nil {
+			return err
+		}
+		for i := 1; i <= *demo; i++ {
+			if err := f.idx.Insert(int64(i), []byte(fmt.Sprintf("value-%d", i))); err != nil {
+				f.idx.Close()
+				return fmt.Errorf("insert key %d: %w", i, err)
+			}
+		}
+	} else if f, err = openTreeFile(path, tf); err != nil {
+		return err
+	}
+	defer f.idx.Close()
+
+	if err := f.tree.ExportDOT(*out); err != nil {
+		return err
+	}
+	fmt.Printf("DOT file generated: %s (render with: dot -Tpng %s -o %s.png)\n", *out, *out, strings.TrimSuffix(*out, ".dot"))
+	return nil
+}